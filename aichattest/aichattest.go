@@ -0,0 +1,279 @@
+// Package aichattest provides a scriptable LLM client for testing code built on the
+// AI Chat SDK without calling a provider.
+//
+// Script the replies in the order the pipeline makes its calls. A non-English chat
+// translates, routes, calls the expert and formats; English messages detected with
+// enough confidence skip translation, and English answers skip formatting:
+//
+//	llm := aichattest.NewLLM(
+//	    aichattest.Route("product", "Asks about features"),
+//	    aichattest.Text("The Widget Pro has three speeds.").ExpectUser("features"),
+//	)
+//	sdk, err := aichat.New(aichat.Config{LLMClient: llm.Client(), ...})
+//	...
+//	llm.AssertDone(t)
+package aichattest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	aichat "github.com/ourstudio-se/ai-chat-sdk"
+)
+
+// Method identifies the LLMClient function that was called.
+type Method string
+
+const (
+	MethodChat       Method = "chat"
+	MethodChatJSON   Method = "chat_json"
+	MethodChatStream Method = "chat_stream"
+)
+
+// Call is an LLM call received by the mock.
+type Call struct {
+	Method       Method
+	SystemPrompt string
+	UserMessage  string
+	Model        aichat.ModelTier // Empty when the caller passed nil options
+}
+
+// Response is a scripted reply to one LLM call.
+type Response struct {
+	// Content is returned by Chat and ChatStream, and unmarshaled into the result by ChatJSON.
+	Content string
+
+	// Err is returned instead of a reply.
+	Err error
+
+	// Tokens are the prompt and completion tokens recorded for the call.
+	PromptTokens     int
+	CompletionTokens int
+
+	method Method
+	expect []func(call Call) error
+}
+
+// Text returns a response with the given content.
+func Text(content string) Response {
+	return Response{Content: content}
+}
+
+// JSON returns a response whose content is v marshaled as JSON. It panics if v
+// cannot be marshaled.
+func JSON(v any) Response {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("aichattest: failed to marshal response: %v", err))
+	}
+	return Response{Content: string(data)}
+}
+
+// Error returns a response that fails with err.
+func Error(err error) Response {
+	return Response{Err: err}
+}
+
+// Translation returns the translator's reply for a message in language.
+func Translation(translatedMessage, language string) Response {
+	return JSON(aichat.TranslationResult{
+		TranslatedMessage: translatedMessage,
+		DetectedLanguage:  language,
+		Confidence:        1,
+	}).ExpectMethod(MethodChatJSON)
+}
+
+// Route returns the router's reply choosing expert.
+func Route(expert aichat.ExpertType, reasoning string) Response {
+	return JSON(map[string]string{
+		"expert":    string(expert),
+		"reasoning": reasoning,
+	}).ExpectMethod(MethodChatJSON)
+}
+
+// WithTokens returns a copy of r that records the given token usage.
+func (r Response) WithTokens(promptTokens, completionTokens int) Response {
+	r.PromptTokens, r.CompletionTokens = promptTokens, completionTokens
+	return r
+}
+
+// ExpectMethod returns a copy of r that fails the call unless it was made with method.
+func (r Response) ExpectMethod(method Method) Response {
+	r.method = method
+	return r
+}
+
+// ExpectSystem returns a copy of r that fails the call unless its system prompt contains substr.
+func (r Response) ExpectSystem(substr string) Response {
+	return r.Expect(func(call Call) error {
+		if !strings.Contains(call.SystemPrompt, substr) {
+			return fmt.Errorf("system prompt does not contain %q", substr)
+		}
+		return nil
+	})
+}
+
+// ExpectUser returns a copy of r that fails the call unless its user message contains substr.
+func (r Response) ExpectUser(substr string) Response {
+	return r.Expect(func(call Call) error {
+		if !strings.Contains(call.UserMessage, substr) {
+			return fmt.Errorf("user message %q does not contain %q", call.UserMessage, substr)
+		}
+		return nil
+	})
+}
+
+// Expect returns a copy of r that fails the call when check returns an error.
+func (r Response) Expect(check func(call Call) error) Response {
+	r.expect = append(r.expect[:len(r.expect):len(r.expect)], check)
+	return r
+}
+
+// LLM is a mock LLM client that replies with scripted responses in order
+// and records the calls it receives. It is safe for concurrent use.
+type LLM struct {
+	mu        sync.Mutex
+	responses []Response
+	calls     []Call
+	failures  []string
+}
+
+// NewLLM creates a mock LLM client that replies with responses in order.
+func NewLLM(responses ...Response) *LLM {
+	return &LLM{responses: responses}
+}
+
+// Add appends responses to the script.
+func (m *LLM) Add(responses ...Response) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.responses = append(m.responses, responses...)
+}
+
+// Calls returns the calls received so far.
+func (m *LLM) Calls() []Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Call(nil), m.calls...)
+}
+
+// AssertDone fails t if a call did not meet its response's expectations, a call
+// was made after the script ran out, or scripted responses were left unused.
+func (m *LLM) AssertDone(t testing.TB) {
+	t.Helper()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, failure := range m.failures {
+		t.Error(failure)
+	}
+	if remaining := len(m.responses) - len(m.calls); remaining > 0 {
+		t.Errorf("aichattest: %d scripted responses were not used", remaining)
+	}
+}
+
+// Client returns the aichat.LLMClient backed by the script.
+func (m *LLM) Client() aichat.LLMClient {
+	return aichat.LLMClient{
+		Chat: func(ctx context.Context, systemPrompt, userMessage string, opts *aichat.ChatOptions) (string, error) {
+			return m.reply(ctx, Call{
+				Method:       MethodChat,
+				SystemPrompt: systemPrompt,
+				UserMessage:  userMessage,
+				Model:        chatModel(opts),
+			})
+		},
+		ChatJSON: func(ctx context.Context, systemPrompt, userMessage string, opts *aichat.ChatJSONOptions, result any) error {
+			var model aichat.ModelTier
+			if opts != nil {
+				model = opts.Model
+			}
+
+			content, err := m.reply(ctx, Call{
+				Method:       MethodChatJSON,
+				SystemPrompt: systemPrompt,
+				UserMessage:  userMessage,
+				Model:        model,
+			})
+			if err != nil {
+				return err
+			}
+
+			if err := json.Unmarshal([]byte(content), result); err != nil {
+				return fmt.Errorf("aichattest: scripted response is not valid JSON for the result: %w", err)
+			}
+			return nil
+		},
+		ChatStream: func(ctx context.Context, systemPrompt, userMessage string, opts *aichat.ChatOptions, onToken func(token string)) (string, error) {
+			content, err := m.reply(ctx, Call{
+				Method:       MethodChatStream,
+				SystemPrompt: systemPrompt,
+				UserMessage:  userMessage,
+				Model:        chatModel(opts),
+			})
+			if err != nil {
+				return "", err
+			}
+
+			// Stream word by word so consumers see more than one token
+			if onToken != nil {
+				for _, token := range strings.SplitAfter(content, " ") {
+					if token != "" {
+						onToken(token)
+					}
+				}
+			}
+			return content, nil
+		},
+	}
+}
+
+// reply records call and returns the next scripted response.
+func (m *LLM) reply(ctx context.Context, call Call) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	index := len(m.calls)
+	m.calls = append(m.calls, call)
+
+	if index >= len(m.responses) {
+		err := fmt.Errorf("aichattest: unexpected %s call #%d: %q", call.Method, index+1, call.UserMessage)
+		m.failures = append(m.failures, err.Error())
+		return "", err
+	}
+
+	response := m.responses[index]
+	var problems []string
+	if response.method != "" && response.method != call.Method {
+		problems = append(problems, fmt.Sprintf("expected a %s call", response.method))
+	}
+	for _, check := range response.expect {
+		if err := check(call); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	if len(problems) > 0 {
+		err := fmt.Errorf("aichattest: %s call #%d: %s", call.Method, index+1, strings.Join(problems, "; "))
+		m.failures = append(m.failures, err.Error())
+		return "", err
+	}
+
+	if response.Err != nil {
+		return "", response.Err
+	}
+
+	aichat.RecordTokenUsage(ctx, response.PromptTokens, response.CompletionTokens)
+	return response.Content, nil
+}
+
+func chatModel(opts *aichat.ChatOptions) aichat.ModelTier {
+	if opts == nil {
+		return ""
+	}
+	return opts.Model
+}
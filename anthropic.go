@@ -0,0 +1,537 @@
+package aichat
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+const (
+	// AnthropicBaseURL is the base URL for the Anthropic API.
+	AnthropicBaseURL = "https://api.anthropic.com/v1"
+
+	// anthropicVersion is the Anthropic API version sent with every request.
+	anthropicVersion = "2023-06-01"
+
+	// defaultAnthropicMaxTokens is used when a call does not set MaxTokens,
+	// since the Messages API requires an explicit limit.
+	defaultAnthropicMaxTokens = 4096
+)
+
+// AnthropicConfig holds configuration for creating an Anthropic client.
+type AnthropicConfig struct {
+	// APIKey is your Anthropic API key (required).
+	APIKey string
+
+	// BaseURL overrides the API base URL (optional, defaults to AnthropicBaseURL).
+	BaseURL string
+
+	// ModelMap maps model tiers to Claude model names (optional, defaults to DefaultAnthropicModelMap).
+	ModelMap map[ModelTier]string
+
+	// ModelFallbacks lists model names to try in order when a request fails with a
+	// rate limit, overload, server or prompt-too-long error (optional).
+	ModelFallbacks []string
+
+	// HTTPClient is the HTTP client to use (optional, defaults to http.DefaultClient).
+	HTTPClient *http.Client
+
+	// Logger is the structured logger to use. If nil, a default logger is used.
+	Logger *slog.Logger
+}
+
+// DefaultAnthropicModelMap returns a model map using Claude Haiku for the
+// smaller tiers and Claude Sonnet for the larger ones.
+func DefaultAnthropicModelMap() map[ModelTier]string {
+	return map[ModelTier]string{
+		ModelNano:      "claude-haiku-4-5",
+		ModelMini:      "claude-haiku-4-5",
+		ModelStandard:  "claude-sonnet-4-5",
+		ModelReasoning: "claude-sonnet-4-5",
+	}
+}
+
+// anthropicMessage is a message in the Messages API format.
+// Content is a string, or a slice of anthropicContentBlock when images are attached.
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content any    `json:"content"`
+}
+
+// anthropicContentBlock is a text or image content block.
+type anthropicContentBlock struct {
+	Type   string                `json:"type"`
+	Text   string                `json:"text,omitempty"`
+	Source *anthropicImageSource `json:"source,omitempty"`
+}
+
+// anthropicImageSource is the source of an image content block.
+type anthropicImageSource struct {
+	Type      string `json:"type"`
+	URL       string `json:"url,omitempty"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+}
+
+// anthropicRequest is a Messages API request body.
+type anthropicRequest struct {
+	Model       string               `json:"model"`
+	System      []anthropicTextBlock `json:"system,omitempty"`
+	Messages    []anthropicMessage   `json:"messages"`
+	MaxTokens   int                  `json:"max_tokens"`
+	Temperature float32              `json:"temperature"`
+	Stream      bool                 `json:"stream,omitempty"`
+}
+
+// anthropicTextBlock is a text content block of the system prompt.
+type anthropicTextBlock struct {
+	Type         string                 `json:"type"`
+	Text         string                 `json:"text"`
+	CacheControl *anthropicCacheControl `json:"cache_control,omitempty"`
+}
+
+// anthropicCacheControl marks the prompt up to and including its block as cacheable.
+type anthropicCacheControl struct {
+	Type string `json:"type"`
+}
+
+// anthropicResponse is a Messages API response body.
+type anthropicResponse struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	StopReason string         `json:"stop_reason"`
+	Usage      anthropicUsage `json:"usage"`
+}
+
+// anthropicStreamEvent is a server-sent event from a streaming Messages API request.
+type anthropicStreamEvent struct {
+	Type  string `json:"type"`
+	Delta struct {
+		Type       string `json:"type"`
+		Text       string `json:"text"`
+		StopReason string `json:"stop_reason"`
+	} `json:"delta"`
+	Message struct {
+		Usage anthropicUsage `json:"usage"`
+	} `json:"message"`
+	Usage *anthropicUsage       `json:"usage"`
+	Error *anthropicErrorDetail `json:"error"`
+}
+
+// anthropicUsage is the token usage of a Messages API request.
+// Input tokens read from or written to the prompt cache are not counted in InputTokens.
+type anthropicUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+}
+
+// promptTokens returns all input tokens, cached or not.
+func (u anthropicUsage) promptTokens() int {
+	return u.InputTokens + u.CacheReadInputTokens + u.CacheCreationInputTokens
+}
+
+// recordAnthropicUsage records the token usage of a message served by model.
+func recordAnthropicUsage(ctx context.Context, model string, usage anthropicUsage) {
+	promptTokens := usage.promptTokens()
+	recordModelUsage(ctx, model, TokenUsage{
+		PromptTokens:     promptTokens,
+		CompletionTokens: usage.OutputTokens,
+		TotalTokens:      promptTokens + usage.OutputTokens,
+		CachedTokens:     usage.CacheReadInputTokens,
+	})
+}
+
+type anthropicErrorDetail struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// AnthropicError is an error returned by the Anthropic API.
+type AnthropicError struct {
+	StatusCode int
+	Type       string
+	Message    string
+}
+
+func (e *AnthropicError) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("Anthropic API error: %s: %s", e.Type, e.Message)
+	}
+	return fmt.Sprintf("Anthropic API error (status %d): %s: %s", e.StatusCode, e.Type, e.Message)
+}
+
+// NewAnthropicClient creates an LLM client backed by the Anthropic Messages API.
+// Use it with Config.LLMClient to run the SDK on Claude models.
+func NewAnthropicClient(cfg AnthropicConfig) LLMClient {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = AnthropicBaseURL
+	}
+	if cfg.ModelMap == nil {
+		cfg.ModelMap = DefaultAnthropicModelMap()
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+
+	c := &anthropicClient{cfg: cfg, logger: cfg.Logger}
+
+	return LLMClient{
+		Chat:       c.chat,
+		ChatJSON:   c.chatJSON,
+		ChatStream: c.chatStream,
+	}
+}
+
+// NewAnthropicHealthCheck creates a health check that lists models on the
+// Anthropic API, verifying both reachability and the API key.
+func NewAnthropicHealthCheck(cfg AnthropicConfig) HealthCheckFn {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = AnthropicBaseURL
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, cfg.BaseURL+"/models?limit=1", nil)
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		setAnthropicHeaders(req, cfg.APIKey)
+
+		resp, err := cfg.HTTPClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("LLM provider unreachable: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("LLM provider unreachable: %w", readAnthropicError(resp))
+		}
+		return nil
+	}
+}
+
+type anthropicClient struct {
+	cfg    AnthropicConfig
+	logger *slog.Logger
+}
+
+func (c *anthropicClient) chat(ctx context.Context, systemPrompt, userMessage string, opts *ChatOptions) (string, error) {
+	if opts == nil {
+		defaultOpts := defaultChatOptions()
+		opts = &defaultOpts
+	}
+
+	body := c.newRequest(systemPrompt, opts.CacheableSystemPrefix, userMessage, opts.Images, opts.Model, opts.Temperature, opts.MaxTokens)
+	applyAnthropicModelOverride(ctx, &body)
+	return c.complete(ctx, body)
+}
+
+func (c *anthropicClient) chatJSON(ctx context.Context, systemPrompt, userMessage string, opts *ChatJSONOptions, result any) error {
+	if opts == nil {
+		defaultOpts := defaultChatJSONOptions()
+		opts = &defaultOpts
+	}
+
+	// The Messages API has no JSON mode, so ask for JSON explicitly and
+	// extract the object from the reply
+	systemPrompt += "\n\nRespond with a single JSON object and nothing else."
+
+	content, err := c.complete(ctx, c.newRequest(systemPrompt, opts.CacheableSystemPrefix, userMessage, opts.Images, opts.Model, opts.Temperature, opts.MaxTokens))
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal([]byte(extractJSONObject(content)), result); err != nil {
+		return &responseParseError{provider: "Anthropic", content: content, err: err}
+	}
+
+	return nil
+}
+
+func (c *anthropicClient) chatStream(ctx context.Context, systemPrompt, userMessage string, opts *ChatOptions, onToken func(token string)) (string, error) {
+	if opts == nil {
+		defaultOpts := defaultChatOptions()
+		opts = &defaultOpts
+	}
+
+	body := c.newRequest(systemPrompt, opts.CacheableSystemPrefix, userMessage, opts.Images, opts.Model, opts.Temperature, opts.MaxTokens)
+	applyAnthropicModelOverride(ctx, &body)
+	body.Stream = true
+
+	c.logger.Debug("creating streaming Anthropic message",
+		slog.String("model", body.Model),
+		slog.Int("user_message_len", len(userMessage)),
+	)
+
+	resp, err := c.postWithFallback(ctx, &body)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var content strings.Builder
+	stopReason := ""
+	startOutputTokens := 0
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+
+		var event anthropicStreamEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			continue
+		}
+
+		switch event.Type {
+		case "message_start":
+			startOutputTokens = event.Message.Usage.OutputTokens
+			recordAnthropicUsage(ctx, body.Model, event.Message.Usage)
+		case "content_block_delta":
+			if event.Delta.Type == "text_delta" && event.Delta.Text != "" {
+				content.WriteString(event.Delta.Text)
+				if onToken != nil {
+					onToken(event.Delta.Text)
+				}
+			}
+		case "message_delta":
+			stopReason = event.Delta.StopReason
+			// The final output token count is cumulative, including those counted at message_start
+			if event.Usage != nil {
+				outputTokens := event.Usage.OutputTokens - startOutputTokens
+				recordModelUsage(ctx, body.Model, TokenUsage{CompletionTokens: outputTokens, TotalTokens: outputTokens})
+			}
+		case "error":
+			if event.Error != nil {
+				return content.String(), &AnthropicError{Type: event.Error.Type, Message: event.Error.Message}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		if ctx.Err() != nil {
+			return content.String(), ctx.Err()
+		}
+		return content.String(), fmt.Errorf("Anthropic streaming error: %w", err)
+	}
+
+	if err := c.checkStopReason(body.Model, stopReason); err != nil {
+		return content.String(), err
+	}
+
+	c.logger.Debug("streaming Anthropic message successful",
+		slog.String("model", body.Model),
+		slog.Int("response_len", content.Len()),
+	)
+
+	return content.String(), nil
+}
+
+func (c *anthropicClient) newRequest(systemPrompt string, cacheablePrefix int, userMessage string, images []Image, tier ModelTier, temperature float32, maxTokens int) anthropicRequest {
+	if maxTokens <= 0 {
+		maxTokens = defaultAnthropicMaxTokens
+	}
+
+	return anthropicRequest{
+		Model:       getModelName(tier, c.cfg.ModelMap),
+		System:      newAnthropicSystem(systemPrompt, cacheablePrefix),
+		Messages:    []anthropicMessage{{Role: "user", Content: newAnthropicUserContent(userMessage, images)}},
+		MaxTokens:   maxTokens,
+		Temperature: temperature,
+	}
+}
+
+// newAnthropicSystem splits the system prompt into text blocks, marking the
+// first cacheablePrefix bytes as cacheable.
+func newAnthropicSystem(systemPrompt string, cacheablePrefix int) []anthropicTextBlock {
+	if systemPrompt == "" {
+		return nil
+	}
+	if cacheablePrefix <= 0 {
+		return []anthropicTextBlock{{Type: "text", Text: systemPrompt}}
+	}
+
+	cacheablePrefix = min(cacheablePrefix, len(systemPrompt))
+	blocks := []anthropicTextBlock{{
+		Type:         "text",
+		Text:         systemPrompt[:cacheablePrefix],
+		CacheControl: &anthropicCacheControl{Type: "ephemeral"},
+	}}
+	if rest := systemPrompt[cacheablePrefix:]; rest != "" {
+		blocks = append(blocks, anthropicTextBlock{Type: "text", Text: rest})
+	}
+	return blocks
+}
+
+// applyAnthropicModelOverride applies the request's model and temperature override to body.
+func applyAnthropicModelOverride(ctx context.Context, body *anthropicRequest) {
+	body.Model, body.Temperature = applyModelOverride(ctx, body.Model, body.Temperature)
+
+	// The Messages API accepts temperatures up to 1
+	body.Temperature = min(body.Temperature, 1)
+}
+
+// newAnthropicUserContent returns the user message as a string, or as content
+// blocks with the images first (as Anthropic recommends) when images are attached.
+func newAnthropicUserContent(userMessage string, images []Image) any {
+	if len(images) == 0 {
+		return userMessage
+	}
+
+	blocks := make([]anthropicContentBlock, 0, len(images)+1)
+	for _, image := range images {
+		source := &anthropicImageSource{Type: "url", URL: image.URL}
+		if mediaType, data, ok := image.base64Data(); ok {
+			source = &anthropicImageSource{Type: "base64", MediaType: mediaType, Data: data}
+		}
+		blocks = append(blocks, anthropicContentBlock{Type: "image", Source: source})
+	}
+	blocks = append(blocks, anthropicContentBlock{Type: "text", Text: userMessage})
+
+	return blocks
+}
+
+// complete sends a non-streaming request and returns the concatenated text content.
+func (c *anthropicClient) complete(ctx context.Context, body anthropicRequest) (string, error) {
+	c.logger.Debug("creating Anthropic message",
+		slog.String("model", body.Model),
+		slog.Float64("temperature", float64(body.Temperature)),
+		slog.Int("max_tokens", body.MaxTokens),
+	)
+
+	resp, err := c.postWithFallback(ctx, &body)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var result anthropicResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode Anthropic response: %w", err)
+	}
+	recordAnthropicUsage(ctx, body.Model, result.Usage)
+
+	var content strings.Builder
+	for _, block := range result.Content {
+		if block.Type == "text" {
+			content.WriteString(block.Text)
+		}
+	}
+
+	if err := c.checkStopReason(body.Model, result.StopReason); err != nil {
+		return "", err
+	}
+
+	if content.Len() == 0 {
+		return "", errors.New("empty response from Anthropic")
+	}
+
+	c.logger.Debug("Anthropic message successful",
+		slog.String("model", body.Model),
+		slog.Int("response_len", content.Len()),
+		slog.Int("prompt_tokens", result.Usage.promptTokens()),
+		slog.Int("cached_tokens", result.Usage.CacheReadInputTokens),
+		slog.Int("completion_tokens", result.Usage.OutputTokens),
+	)
+
+	return content.String(), nil
+}
+
+// checkStopReason turns stop reasons that mean the answer is unusable into errors.
+func (c *anthropicClient) checkStopReason(model, stopReason string) error {
+	switch stopReason {
+	case "refusal":
+		return errors.New("Anthropic model declined to respond")
+	case "max_tokens":
+		c.logger.Warn("Anthropic response truncated at max tokens", slog.String("model", model))
+	}
+	return nil
+}
+
+// postWithFallback posts body, retrying with the configured fallback models.
+// On return body.Model is the model that served the request.
+func (c *anthropicClient) postWithFallback(ctx context.Context, body *anthropicRequest) (*http.Response, error) {
+	resp, model, err := withModelFallback(ctx, body.Model, c.cfg.ModelFallbacks, isAnthropicFallbackError, c.logger,
+		func(model string) (*http.Response, error) {
+			body.Model = model
+			return c.post(ctx, *body)
+		},
+	)
+	return resp, asContextLengthError(err, model)
+}
+
+func (c *anthropicClient) post(ctx context.Context, body anthropicRequest) (*http.Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal Anthropic request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.BaseURL+"/messages", bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	setAnthropicHeaders(req, c.cfg.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Anthropic API error: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, readAnthropicError(resp)
+	}
+
+	return resp, nil
+}
+
+func setAnthropicHeaders(req *http.Request, apiKey string) {
+	req.Header.Set("x-api-key", apiKey)
+	req.Header.Set("anthropic-version", anthropicVersion)
+}
+
+// readAnthropicError builds an AnthropicError from a non-200 response.
+func readAnthropicError(resp *http.Response) error {
+	apiErr := &AnthropicError{StatusCode: resp.StatusCode, Type: "api_error", Message: resp.Status}
+
+	var body struct {
+		Error anthropicErrorDetail `json:"error"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if json.Unmarshal(data, &body) == nil && body.Error.Type != "" {
+		apiErr.Type = body.Error.Type
+		apiErr.Message = body.Error.Message
+	}
+
+	return apiErr
+}
+
+// extractJSONObject returns the outermost JSON object in text, tolerating
+// surrounding prose or Markdown code fences.
+func extractJSONObject(text string) string {
+	start := strings.Index(text, "{")
+	end := strings.LastIndex(text, "}")
+	if start == -1 || end < start {
+		return text
+	}
+	return text[start : end+1]
+}
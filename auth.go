@@ -0,0 +1,529 @@
+package aichat
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Principal identifies the authenticated caller of an HTTP request.
+type Principal struct {
+	// ID identifies the user or API client.
+	ID string
+
+	// Tenant is the tenant the caller belongs to, if any.
+	Tenant string
+
+	// Claims holds the verified JWT claims (nil for API keys).
+	Claims map[string]any
+}
+
+// AuthenticateFn authenticates an HTTP request, typically from its Authorization header.
+// It returns an error wrapping ErrUnauthorized when credentials are missing or invalid.
+type AuthenticateFn func(r *http.Request) (*Principal, error)
+
+// NewAPIKeyAuthenticator creates an authenticator for static API keys sent as
+// "Authorization: Bearer <key>". keys maps each key to the principal it authenticates.
+func NewAPIKeyAuthenticator(keys map[string]Principal) AuthenticateFn {
+	return func(r *http.Request) (*Principal, error) {
+		token, ok := bearerToken(r)
+		if !ok {
+			return nil, fmt.Errorf("%w: missing bearer token", ErrUnauthorized)
+		}
+
+		// Compare against every key so timing doesn't reveal which prefix matched
+		var match *Principal
+		for key, principal := range keys {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(key)) == 1 {
+				match = &principal
+			}
+		}
+
+		if match == nil {
+			return nil, fmt.Errorf("%w: unknown API key", ErrUnauthorized)
+		}
+		return match, nil
+	}
+}
+
+// JWTAuthConfig configures JWT bearer token authentication.
+type JWTAuthConfig struct {
+	// JWKSURL is the URL of the JSON Web Key Set used to verify token signatures (required).
+	JWKSURL string
+
+	// Issuer is the required "iss" claim (optional).
+	Issuer string
+
+	// Audience is a required "aud" claim value (optional).
+	Audience string
+
+	// TenantClaim names the claim holding the caller's tenant (defaults to "tenant").
+	TenantClaim string
+
+	// RefreshInterval is how often the key set is refetched (defaults to 1h).
+	// Tokens signed with an unknown key ID also trigger a refetch, at most once a minute
+	// per key ID and once every 5s overall.
+	RefreshInterval time.Duration
+
+	// Leeway is the clock skew tolerated when checking "exp" and "nbf" (defaults to 1m).
+	Leeway time.Duration
+
+	// HTTPClient is the client used to fetch the key set (optional, defaults to a client with a 10s timeout).
+	HTTPClient *http.Client
+}
+
+// applyDefaults fills in default values for the JWT config.
+func (c *JWTAuthConfig) applyDefaults() {
+	if c.TenantClaim == "" {
+		c.TenantClaim = "tenant"
+	}
+
+	if c.RefreshInterval == 0 {
+		c.RefreshInterval = time.Hour
+	}
+
+	if c.Leeway == 0 {
+		c.Leeway = time.Minute
+	}
+
+	if c.HTTPClient == nil {
+		c.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+}
+
+// NewJWTAuthenticator creates an authenticator for JWTs sent as "Authorization: Bearer <token>".
+// Tokens must be signed with RS256, RS384, RS512, ES256, ES384 or ES512 by a key in the
+// configured key set and carry an "exp" claim. The principal ID is the "sub" claim.
+func NewJWTAuthenticator(cfg JWTAuthConfig) (AuthenticateFn, error) {
+	if cfg.JWKSURL == "" {
+		return nil, errors.New("JWTAuthConfig.JWKSURL is required")
+	}
+	cfg.applyDefaults()
+
+	keys := newJWKSCache(cfg.JWKSURL, cfg.HTTPClient, cfg.RefreshInterval)
+
+	return func(r *http.Request) (*Principal, error) {
+		token, ok := bearerToken(r)
+		if !ok {
+			return nil, fmt.Errorf("%w: missing bearer token", ErrUnauthorized)
+		}
+
+		claims, err := verifyJWT(r.Context(), token, keys.get)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrUnauthorized, err)
+		}
+
+		if err := validateJWTClaims(claims, cfg, time.Now()); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrUnauthorized, err)
+		}
+
+		principal := &Principal{Claims: claims}
+		principal.ID, _ = claims["sub"].(string)
+		principal.Tenant, _ = claims[cfg.TenantClaim].(string)
+		return principal, nil
+	}, nil
+}
+
+// authMiddleware returns a middleware that rejects unauthenticated requests with 401
+// and stores the authenticated principal in the request context.
+func authMiddleware(authenticate AuthenticateFn, logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, err := authenticate(r)
+			if err != nil {
+				logger.Info("request unauthorized",
+					slog.String("path", r.URL.Path),
+					slog.String("error", err.Error()),
+					slog.String("request_id", RequestIDFromContext(r.Context())),
+				)
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				respondError(w, http.StatusUnauthorized, "Unauthorized")
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(withPrincipal(r.Context(), principal)))
+		})
+	}
+}
+
+// bearerToken returns the token of a "Bearer" Authorization header.
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// verifyJWT checks the signature of a compact JWT and returns its claims.
+// Expiry and other claims are checked separately by validateJWTClaims.
+func verifyJWT(ctx context.Context, token string, getKey func(ctx context.Context, kid string) (crypto.PublicKey, error)) (map[string]any, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJWTSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("malformed token header: %w", err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed token signature: %w", err)
+	}
+
+	key, err := getKey(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verifyJWTSignature(header.Alg, key, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]any
+	if err := decodeJWTSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("malformed token claims: %w", err)
+	}
+	return claims, nil
+}
+
+func decodeJWTSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// jwtCurves maps each ECDSA JWS algorithm to the only curve it may be used with.
+var jwtCurves = map[string]elliptic.Curve{
+	"ES256": elliptic.P256(),
+	"ES384": elliptic.P384(),
+	"ES512": elliptic.P521(),
+}
+
+// verifyJWTSignature verifies signature over signed with key for the given JWS algorithm.
+func verifyJWTSignature(alg string, key crypto.PublicKey, signed, signature []byte) error {
+	var h hash.Hash
+	var hashID crypto.Hash
+	switch alg {
+	case "RS256", "ES256":
+		h, hashID = sha256.New(), crypto.SHA256
+	case "RS384", "ES384":
+		h, hashID = sha512.New384(), crypto.SHA384
+	case "RS512", "ES512":
+		h, hashID = sha512.New(), crypto.SHA512
+	default:
+		return fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+	h.Write(signed)
+	digest := h.Sum(nil)
+
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(alg, "RS") {
+			return fmt.Errorf("algorithm %q does not match RSA key", alg)
+		}
+		if err := rsa.VerifyPKCS1v15(key, hashID, digest, signature); err != nil {
+			return errors.New("invalid token signature")
+		}
+		return nil
+
+	case *ecdsa.PublicKey:
+		if jwtCurves[alg] != key.Curve {
+			return fmt.Errorf("algorithm %q does not match EC key", alg)
+		}
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return errors.New("invalid token signature")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(key, digest, r, s) {
+			return errors.New("invalid token signature")
+		}
+		return nil
+
+	default:
+		return errors.New("unsupported key type")
+	}
+}
+
+// validateJWTClaims checks the time-based, issuer and audience claims.
+func validateJWTClaims(claims map[string]any, cfg JWTAuthConfig, now time.Time) error {
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return errors.New("token has no expiry")
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(cfg.Leeway)) {
+		return errors.New("token expired")
+	}
+
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(cfg.Leeway).Before(time.Unix(int64(nbf), 0)) {
+		return errors.New("token not yet valid")
+	}
+
+	if cfg.Issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != cfg.Issuer {
+			return errors.New("unexpected token issuer")
+		}
+	}
+
+	if cfg.Audience != "" && !hasAudience(claims["aud"], cfg.Audience) {
+		return errors.New("unexpected token audience")
+	}
+
+	return nil
+}
+
+// hasAudience reports whether an "aud" claim, a string or an array of strings, contains audience.
+func hasAudience(aud any, audience string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == audience
+	case []any:
+		for _, a := range aud {
+			if a == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// jwksCache fetches and caches the public keys of a JSON Web Key Set.
+type jwksCache struct {
+	url             string
+	client          *http.Client
+	refreshInterval time.Duration
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time            // Time of the last successful fetch
+	misses    map[string]time.Time // Unknown key IDs and when they were last looked up
+	inflight  *jwksFetch
+}
+
+// jwksFetch is a key set fetch shared by the requests waiting for it.
+type jwksFetch struct {
+	done chan struct{}
+	err  error
+}
+
+const (
+	// minJWKSRefetchInterval limits refetches triggered by the same unknown key ID.
+	minJWKSRefetchInterval = time.Minute
+
+	// minJWKSFetchSpacing limits refetches triggered by unknown key IDs overall.
+	minJWKSFetchSpacing = 5 * time.Second
+
+	// jwksFetchTimeout bounds a key set fetch, which is not tied to any one request.
+	jwksFetchTimeout = 10 * time.Second
+)
+
+func newJWKSCache(url string, client *http.Client, refreshInterval time.Duration) *jwksCache {
+	return &jwksCache{
+		url:             url,
+		client:          client,
+		refreshInterval: refreshInterval,
+		misses:          make(map[string]time.Time),
+	}
+}
+
+// get returns the key with the given ID, refetching the key set when it is stale
+// or doesn't contain the key. An empty kid matches a key set holding a single key.
+// Stale keys are served while the key set is refetched in the background.
+func (c *jwksCache) get(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	c.mu.Lock()
+	key, ok := c.lookup(kid)
+	if ok {
+		if time.Since(c.fetchedAt) > c.refreshInterval {
+			c.startFetch()
+		}
+		c.mu.Unlock()
+		return key, nil
+	}
+	if !c.mayFetchFor(kid) {
+		c.mu.Unlock()
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	fetch := c.startFetch()
+	c.mu.Unlock()
+
+	// Cancelling the request stops waiting, not the fetch other requests may share
+	select {
+	case <-fetch.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if key, ok := c.lookup(kid); ok {
+		return key, nil
+	}
+	if fetch.err != nil {
+		return nil, fetch.err
+	}
+	c.misses[kid] = time.Now()
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// mayFetchFor reports whether an unknown key ID may trigger a fetch. Fetch failures
+// start no cooldown, so an unavailable endpoint is retried on the next request.
+// The caller must hold c.mu.
+func (c *jwksCache) mayFetchFor(kid string) bool {
+	if c.inflight != nil || c.fetchedAt.IsZero() {
+		return true
+	}
+	if time.Since(c.fetchedAt) < minJWKSFetchSpacing {
+		return false
+	}
+	lastMiss, missed := c.misses[kid]
+	return !missed || time.Since(lastMiss) > minJWKSRefetchInterval
+}
+
+// startFetch starts fetching the key set unless a fetch is in flight, and returns
+// the fetch. The caller must hold c.mu.
+func (c *jwksCache) startFetch() *jwksFetch {
+	if c.inflight != nil {
+		return c.inflight
+	}
+
+	fetch := &jwksFetch{done: make(chan struct{})}
+	c.inflight = fetch
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), jwksFetchTimeout)
+		defer cancel()
+		keys, err := fetchJWKS(ctx, c.client, c.url)
+
+		c.mu.Lock()
+		if err == nil {
+			c.keys = keys
+			c.fetchedAt = time.Now()
+			for kid, lastMiss := range c.misses {
+				if time.Since(lastMiss) > minJWKSRefetchInterval {
+					delete(c.misses, kid)
+				}
+			}
+		}
+		fetch.err = err
+		c.inflight = nil
+		c.mu.Unlock()
+
+		close(fetch.done)
+	}()
+
+	return fetch
+}
+
+func (c *jwksCache) lookup(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(c.keys) == 1 {
+		for _, key := range c.keys {
+			return key, true
+		}
+	}
+	key, ok := c.keys[kid]
+	return key, ok
+}
+
+// fetchJWKS downloads a JSON Web Key Set, skipping keys of unsupported types.
+func fetchJWKS(ctx context.Context, client *http.Client, url string) (map[string]crypto.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JWKS request: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch JWKS: status %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		switch k.Kty {
+		case "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(k.N)
+			e, errE := base64.RawURLEncoding.DecodeString(k.E)
+			if errN != nil || errE != nil || len(e) > 4 {
+				continue
+			}
+			keys[k.Kid] = &rsa.PublicKey{
+				N: new(big.Int).SetBytes(n),
+				E: int(new(big.Int).SetBytes(e).Int64()),
+			}
+
+		case "EC":
+			curve := map[string]elliptic.Curve{
+				"P-256": elliptic.P256(),
+				"P-384": elliptic.P384(),
+				"P-521": elliptic.P521(),
+			}[k.Crv]
+			x, errX := base64.RawURLEncoding.DecodeString(k.X)
+			y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+			if curve == nil || errX != nil || errY != nil {
+				continue
+			}
+			size := (curve.Params().BitSize + 7) / 8
+			if len(x) != size || len(y) != size {
+				continue
+			}
+			point := append(append([]byte{4}, x...), y...)
+			key, err := ecdsa.ParseUncompressedPublicKey(curve, point)
+			if err != nil {
+				continue
+			}
+			keys[k.Kid] = key
+		}
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,41 @@
+package aichat
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+)
+
+func TestVerifyJWTSignatureBindsAlgorithmToCurve(t *testing.T) {
+	hashes := map[string]crypto.Hash{"ES256": crypto.SHA256, "ES384": crypto.SHA384, "ES512": crypto.SHA512}
+	curves := map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384(), "P-521": elliptic.P521()}
+	valid := map[string]string{"ES256": "P-256", "ES384": "P-384", "ES512": "P-521"}
+
+	signed := []byte("header.claims")
+	for curveName, curve := range curves {
+		key, err := ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			t.Fatalf("GenerateKey(%s) error = %v", curveName, err)
+		}
+
+		for alg, hashID := range hashes {
+			h := hashID.New()
+			h.Write(signed)
+			r, s, err := ecdsa.Sign(rand.Reader, key, h.Sum(nil))
+			if err != nil {
+				t.Fatalf("Sign() error = %v", err)
+			}
+			size := (curve.Params().BitSize + 7) / 8
+			signature := make([]byte, 2*size)
+			r.FillBytes(signature[:size])
+			s.FillBytes(signature[size:])
+
+			err = verifyJWTSignature(alg, &key.PublicKey, signed, signature)
+			if want := valid[alg] == curveName; (err == nil) != want {
+				t.Errorf("verifyJWTSignature(%s, %s key) error = %v, want valid = %v", alg, curveName, err, want)
+			}
+		}
+	}
+}
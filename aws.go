@@ -0,0 +1,290 @@
+package aichat
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AWSCredentials are the credentials used to sign requests to AWS.
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string // For temporary credentials (optional)
+}
+
+// AWSCredentialsFn returns the credentials for a request. It is called for every
+// request, so implementations backed by temporary credentials should cache them.
+type AWSCredentialsFn func(ctx context.Context) (AWSCredentials, error)
+
+// DefaultAWSCredentials loads credentials from the AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN environment variables, falling back
+// to the AWS_PROFILE (or "default") profile of the shared credentials file
+// (AWS_SHARED_CREDENTIALS_FILE, or ~/.aws/credentials).
+func DefaultAWSCredentials(ctx context.Context) (AWSCredentials, error) {
+	if id, secret := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"); id != "" && secret != "" {
+		return AWSCredentials{AccessKeyID: id, SecretAccessKey: secret, SessionToken: os.Getenv("AWS_SESSION_TOKEN")}, nil
+	}
+
+	path := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return AWSCredentials{}, fmt.Errorf("no AWS credentials in the environment and no home directory: %w", err)
+		}
+		path = filepath.Join(home, ".aws", "credentials")
+	}
+	profile := os.Getenv("AWS_PROFILE")
+	if profile == "" {
+		profile = "default"
+	}
+
+	creds, err := readAWSCredentialsFile(path, profile)
+	if err != nil {
+		return AWSCredentials{}, fmt.Errorf("no AWS credentials in the environment: %w", err)
+	}
+	return creds, nil
+}
+
+// readAWSCredentialsFile reads the credentials of profile from a shared credentials file.
+func readAWSCredentialsFile(path, profile string) (AWSCredentials, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return AWSCredentials{}, fmt.Errorf("failed to open AWS credentials file: %w", err)
+	}
+	defer file.Close()
+
+	var creds AWSCredentials
+	found := false
+	section := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if name, ok := strings.CutPrefix(line, "["); ok {
+			section = strings.TrimSpace(strings.TrimSuffix(name, "]"))
+			found = found || section == profile
+			continue
+		}
+		if section != profile {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "aws_access_key_id":
+			creds.AccessKeyID = strings.TrimSpace(value)
+		case "aws_secret_access_key":
+			creds.SecretAccessKey = strings.TrimSpace(value)
+		case "aws_session_token":
+			creds.SessionToken = strings.TrimSpace(value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return AWSCredentials{}, fmt.Errorf("failed to read AWS credentials file: %w", err)
+	}
+
+	if !found || creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return AWSCredentials{}, fmt.Errorf("profile %q has no credentials in %s", profile, path)
+	}
+	return creds, nil
+}
+
+// signAWSRequest signs req with AWS Signature Version 4 for service in region.
+// payload is the request body.
+func signAWSRequest(req *http.Request, payload []byte, creds AWSCredentials, region, service string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	// Sign the host and all X-Amz-* and Content-Type headers
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if lower == "content-type" || strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	payloadHash := sha256.Sum256(payload)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		awsCanonicalURI(req.URL.EscapedPath()),
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// awsCanonicalURI encodes each segment of an escaped path again, as Signature
+// Version 4 requires for every service except S3.
+func awsCanonicalURI(escapedPath string) string {
+	if escapedPath == "" {
+		return "/"
+	}
+	segments := strings.Split(escapedPath, "/")
+	for i, segment := range segments {
+		segments[i] = awsURIEncode(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// awsURIEncode percent-encodes every byte of s except the unreserved characters.
+func awsURIEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// awsEvent is a message of the AWS event stream encoding used by streaming APIs.
+type awsEvent struct {
+	Headers map[string]string // String-valued headers, such as ":event-type"
+	Payload []byte
+}
+
+// errAWSEventChecksum is returned for an event stream message whose checksum does not match.
+var errAWSEventChecksum = errors.New("AWS event stream message checksum mismatch")
+
+// readAWSEvent reads the next message of an AWS event stream. It returns io.EOF
+// at the end of the stream.
+func readAWSEvent(r io.Reader) (awsEvent, error) {
+	// The prelude holds the total and header lengths and their checksum
+	var prelude [12]byte
+	if _, err := io.ReadFull(r, prelude[:]); err != nil {
+		return awsEvent{}, err
+	}
+	totalLength := binary.BigEndian.Uint32(prelude[0:4])
+	headersLength := binary.BigEndian.Uint32(prelude[4:8])
+	if crc32.ChecksumIEEE(prelude[:8]) != binary.BigEndian.Uint32(prelude[8:12]) {
+		return awsEvent{}, errAWSEventChecksum
+	}
+	if totalLength < 16+headersLength || totalLength > 16<<20 {
+		return awsEvent{}, fmt.Errorf("invalid AWS event stream message length %d", totalLength)
+	}
+
+	message := make([]byte, totalLength)
+	copy(message, prelude[:])
+	if _, err := io.ReadFull(r, message[12:]); err != nil {
+		return awsEvent{}, fmt.Errorf("truncated AWS event stream message: %w", err)
+	}
+	if crc32.ChecksumIEEE(message[:totalLength-4]) != binary.BigEndian.Uint32(message[totalLength-4:]) {
+		return awsEvent{}, errAWSEventChecksum
+	}
+
+	headers, err := parseAWSEventHeaders(message[12 : 12+headersLength])
+	if err != nil {
+		return awsEvent{}, err
+	}
+	return awsEvent{Headers: headers, Payload: message[12+headersLength : totalLength-4]}, nil
+}
+
+// parseAWSEventHeaders returns the string-valued headers of an event stream
+// message, skipping headers of other types.
+func parseAWSEventHeaders(data []byte) (map[string]string, error) {
+	errInvalid := errors.New("invalid AWS event stream headers")
+	headers := make(map[string]string)
+	for len(data) > 0 {
+		nameLength := int(data[0])
+		if len(data) < 2+nameLength {
+			return nil, errInvalid
+		}
+		name := string(data[1 : 1+nameLength])
+		valueType := data[1+nameLength]
+		data = data[2+nameLength:]
+
+		// Value sizes by type: bool true/false, byte, short, int, long,
+		// length-prefixed bytes and string, timestamp and UUID
+		var size int
+		switch valueType {
+		case 0, 1:
+			size = 0
+		case 2:
+			size = 1
+		case 3:
+			size = 2
+		case 4:
+			size = 4
+		case 5, 8:
+			size = 8
+		case 6, 7:
+			if len(data) < 2 {
+				return nil, errInvalid
+			}
+			size = int(binary.BigEndian.Uint16(data[:2]))
+			data = data[2:]
+		case 9:
+			size = 16
+		default:
+			return nil, errInvalid
+		}
+		if len(data) < size {
+			return nil, errInvalid
+		}
+		if valueType == 7 {
+			headers[name] = string(data[:size])
+		}
+		data = data[size:]
+	}
+	return headers, nil
+}
@@ -0,0 +1,514 @@
+package aichat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// bedrockService is the service name requests to Bedrock Runtime are signed for.
+	bedrockService = "bedrock"
+
+	// defaultBedrockMaxTokens is used when a call does not set MaxTokens.
+	defaultBedrockMaxTokens = 4096
+)
+
+// BedrockConfig holds configuration for creating an AWS Bedrock client.
+type BedrockConfig struct {
+	// Region is the AWS region to call (optional, defaults to AWS_REGION or AWS_DEFAULT_REGION).
+	Region string
+
+	// Credentials returns the credentials that sign each request (optional,
+	// defaults to DefaultAWSCredentials).
+	Credentials AWSCredentialsFn
+
+	// BaseURL overrides the Bedrock Runtime endpoint (optional, defaults to
+	// https://bedrock-runtime.<region>.amazonaws.com).
+	BaseURL string
+
+	// ModelMap maps model tiers to Bedrock model or inference profile IDs
+	// (optional, defaults to DefaultBedrockModelMap).
+	ModelMap map[ModelTier]string
+
+	// ModelFallbacks lists model IDs to try in order when a request fails with a
+	// throttling, server or input-too-long error (optional).
+	ModelFallbacks []string
+
+	// HTTPClient is the HTTP client to use (optional, defaults to http.DefaultClient).
+	HTTPClient *http.Client
+
+	// Logger is the structured logger to use. If nil, a default logger is used.
+	Logger *slog.Logger
+}
+
+// DefaultBedrockModelMap returns a model map using the global cross-region
+// inference profiles of Claude Haiku for the smaller tiers and Claude Sonnet for
+// the larger ones.
+func DefaultBedrockModelMap() map[ModelTier]string {
+	return map[ModelTier]string{
+		ModelNano:      "global.anthropic.claude-haiku-4-5-20251001-v1:0",
+		ModelMini:      "global.anthropic.claude-haiku-4-5-20251001-v1:0",
+		ModelStandard:  "global.anthropic.claude-sonnet-4-5-20250929-v1:0",
+		ModelReasoning: "global.anthropic.claude-sonnet-4-5-20250929-v1:0",
+	}
+}
+
+// bedrockRequest is a Converse API request body. The model ID is part of the URL.
+type bedrockRequest struct {
+	model string
+
+	Messages        []bedrockMessage       `json:"messages"`
+	System          []bedrockSystemBlock   `json:"system,omitempty"`
+	InferenceConfig bedrockInferenceConfig `json:"inferenceConfig"`
+}
+
+// bedrockMessage is a message in the Converse API format.
+type bedrockMessage struct {
+	Role    string                `json:"role"`
+	Content []bedrockContentBlock `json:"content"`
+}
+
+// bedrockContentBlock is a text or image content block.
+type bedrockContentBlock struct {
+	Text  string        `json:"text,omitempty"`
+	Image *bedrockImage `json:"image,omitempty"`
+}
+
+// bedrockImage is an image content block. Bytes is base64-encoded.
+type bedrockImage struct {
+	Format string `json:"format"`
+	Source struct {
+		Bytes string `json:"bytes"`
+	} `json:"source"`
+}
+
+// bedrockSystemBlock is a text block of the system prompt, or a cache point
+// marking the prompt before it as cacheable.
+type bedrockSystemBlock struct {
+	Text       string             `json:"text,omitempty"`
+	CachePoint *bedrockCachePoint `json:"cachePoint,omitempty"`
+}
+
+type bedrockCachePoint struct {
+	Type string `json:"type"`
+}
+
+type bedrockInferenceConfig struct {
+	MaxTokens   int     `json:"maxTokens"`
+	Temperature float32 `json:"temperature"`
+}
+
+// bedrockResponse is a Converse API response body.
+type bedrockResponse struct {
+	Output struct {
+		Message struct {
+			Content []struct {
+				Text string `json:"text"`
+			} `json:"content"`
+		} `json:"message"`
+	} `json:"output"`
+	StopReason string       `json:"stopReason"`
+	Usage      bedrockUsage `json:"usage"`
+}
+
+// bedrockStreamEvent is the payload of a ConverseStream event. Which fields are
+// set depends on the event type.
+type bedrockStreamEvent struct {
+	Delta struct {
+		Text string `json:"text"`
+	} `json:"delta"`
+	StopReason string        `json:"stopReason"`
+	Usage      *bedrockUsage `json:"usage"`
+	Message    string        `json:"message"`
+}
+
+// bedrockUsage is the token usage of a Converse API request.
+// Input tokens read from or written to the prompt cache are not counted in InputTokens.
+type bedrockUsage struct {
+	InputTokens           int `json:"inputTokens"`
+	OutputTokens          int `json:"outputTokens"`
+	CacheReadInputTokens  int `json:"cacheReadInputTokens"`
+	CacheWriteInputTokens int `json:"cacheWriteInputTokens"`
+}
+
+// promptTokens returns all input tokens, cached or not.
+func (u bedrockUsage) promptTokens() int {
+	return u.InputTokens + u.CacheReadInputTokens + u.CacheWriteInputTokens
+}
+
+// recordBedrockUsage records the token usage of a message served by model.
+func recordBedrockUsage(ctx context.Context, model string, usage bedrockUsage) {
+	promptTokens := usage.promptTokens()
+	recordModelUsage(ctx, model, TokenUsage{
+		PromptTokens:     promptTokens,
+		CompletionTokens: usage.OutputTokens,
+		TotalTokens:      promptTokens + usage.OutputTokens,
+		CachedTokens:     usage.CacheReadInputTokens,
+	})
+}
+
+// BedrockError is an error returned by the Bedrock Runtime API.
+type BedrockError struct {
+	StatusCode int
+	Type       string // e.g. "ThrottlingException" or "ValidationException"
+	Message    string
+}
+
+func (e *BedrockError) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("Bedrock API error: %s: %s", e.Type, e.Message)
+	}
+	return fmt.Sprintf("Bedrock API error (status %d): %s: %s", e.StatusCode, e.Type, e.Message)
+}
+
+// NewBedrockClient creates an LLM client backed by the AWS Bedrock Converse API.
+// Use it with Config.LLMClient to run the SDK on models hosted in your AWS account.
+func NewBedrockClient(cfg BedrockConfig) LLMClient {
+	if cfg.Region == "" {
+		cfg.Region = os.Getenv("AWS_REGION")
+	}
+	if cfg.Region == "" {
+		cfg.Region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if cfg.Credentials == nil {
+		cfg.Credentials = DefaultAWSCredentials
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = "https://bedrock-runtime." + cfg.Region + ".amazonaws.com"
+	}
+	if cfg.ModelMap == nil {
+		cfg.ModelMap = DefaultBedrockModelMap()
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+
+	c := &bedrockClient{cfg: cfg, logger: cfg.Logger}
+
+	return LLMClient{
+		Chat:       c.chat,
+		ChatJSON:   c.chatJSON,
+		ChatStream: c.chatStream,
+	}
+}
+
+type bedrockClient struct {
+	cfg    BedrockConfig
+	logger *slog.Logger
+}
+
+func (c *bedrockClient) chat(ctx context.Context, systemPrompt, userMessage string, opts *ChatOptions) (string, error) {
+	if opts == nil {
+		defaultOpts := defaultChatOptions()
+		opts = &defaultOpts
+	}
+
+	body, err := c.newRequest(systemPrompt, opts.CacheableSystemPrefix, userMessage, opts.Images, opts.Model, opts.Temperature, opts.MaxTokens)
+	if err != nil {
+		return "", err
+	}
+	applyBedrockModelOverride(ctx, &body)
+	return c.complete(ctx, body)
+}
+
+func (c *bedrockClient) chatJSON(ctx context.Context, systemPrompt, userMessage string, opts *ChatJSONOptions, result any) error {
+	if opts == nil {
+		defaultOpts := defaultChatJSONOptions()
+		opts = &defaultOpts
+	}
+
+	// The Converse API has no JSON mode, so ask for JSON explicitly and
+	// extract the object from the reply
+	systemPrompt += "\n\nRespond with a single JSON object and nothing else."
+
+	body, err := c.newRequest(systemPrompt, opts.CacheableSystemPrefix, userMessage, opts.Images, opts.Model, opts.Temperature, opts.MaxTokens)
+	if err != nil {
+		return err
+	}
+	content, err := c.complete(ctx, body)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal([]byte(extractJSONObject(content)), result); err != nil {
+		return &responseParseError{provider: "Bedrock", content: content, err: err}
+	}
+
+	return nil
+}
+
+func (c *bedrockClient) chatStream(ctx context.Context, systemPrompt, userMessage string, opts *ChatOptions, onToken func(token string)) (string, error) {
+	if opts == nil {
+		defaultOpts := defaultChatOptions()
+		opts = &defaultOpts
+	}
+
+	body, err := c.newRequest(systemPrompt, opts.CacheableSystemPrefix, userMessage, opts.Images, opts.Model, opts.Temperature, opts.MaxTokens)
+	if err != nil {
+		return "", err
+	}
+	applyBedrockModelOverride(ctx, &body)
+
+	c.logger.Debug("creating streaming Bedrock message",
+		slog.String("model", body.model),
+		slog.Int("user_message_len", len(userMessage)),
+	)
+
+	resp, err := c.postWithFallback(ctx, &body, "converse-stream")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var content strings.Builder
+	stopReason := ""
+
+	for {
+		event, err := readAWSEvent(resp.Body)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return content.String(), ctx.Err()
+			}
+			return content.String(), fmt.Errorf("Bedrock streaming error: %w", err)
+		}
+
+		var payload bedrockStreamEvent
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			continue
+		}
+
+		// Errors after the response started arrive as exception events
+		if event.Headers[":message-type"] == "exception" {
+			return content.String(), &BedrockError{Type: event.Headers[":exception-type"], Message: payload.Message}
+		}
+
+		switch event.Headers[":event-type"] {
+		case "contentBlockDelta":
+			if payload.Delta.Text != "" {
+				content.WriteString(payload.Delta.Text)
+				if onToken != nil {
+					onToken(payload.Delta.Text)
+				}
+			}
+		case "messageStop":
+			stopReason = payload.StopReason
+		case "metadata":
+			if payload.Usage != nil {
+				recordBedrockUsage(ctx, body.model, *payload.Usage)
+			}
+		}
+	}
+
+	if err := c.checkStopReason(body.model, stopReason); err != nil {
+		return content.String(), err
+	}
+
+	c.logger.Debug("streaming Bedrock message successful",
+		slog.String("model", body.model),
+		slog.Int("response_len", content.Len()),
+	)
+
+	return content.String(), nil
+}
+
+func (c *bedrockClient) newRequest(systemPrompt string, cacheablePrefix int, userMessage string, images []Image, tier ModelTier, temperature float32, maxTokens int) (bedrockRequest, error) {
+	if maxTokens <= 0 {
+		maxTokens = defaultBedrockMaxTokens
+	}
+
+	content, err := newBedrockUserContent(userMessage, images)
+	if err != nil {
+		return bedrockRequest{}, err
+	}
+
+	return bedrockRequest{
+		model:           getModelName(tier, c.cfg.ModelMap),
+		Messages:        []bedrockMessage{{Role: "user", Content: content}},
+		System:          newBedrockSystem(systemPrompt, cacheablePrefix),
+		InferenceConfig: bedrockInferenceConfig{MaxTokens: maxTokens, Temperature: temperature},
+	}, nil
+}
+
+// newBedrockSystem splits the system prompt into text blocks, with a cache point
+// after the first cacheablePrefix bytes.
+func newBedrockSystem(systemPrompt string, cacheablePrefix int) []bedrockSystemBlock {
+	if systemPrompt == "" {
+		return nil
+	}
+	if cacheablePrefix <= 0 {
+		return []bedrockSystemBlock{{Text: systemPrompt}}
+	}
+
+	cacheablePrefix = min(cacheablePrefix, len(systemPrompt))
+	blocks := []bedrockSystemBlock{
+		{Text: systemPrompt[:cacheablePrefix]},
+		{CachePoint: &bedrockCachePoint{Type: "default"}},
+	}
+	if rest := systemPrompt[cacheablePrefix:]; rest != "" {
+		blocks = append(blocks, bedrockSystemBlock{Text: rest})
+	}
+	return blocks
+}
+
+// applyBedrockModelOverride applies the request's model and temperature override to body.
+func applyBedrockModelOverride(ctx context.Context, body *bedrockRequest) {
+	body.model, body.InferenceConfig.Temperature = applyModelOverride(ctx, body.model, body.InferenceConfig.Temperature)
+
+	// Models on Bedrock accept temperatures up to 1
+	body.InferenceConfig.Temperature = min(body.InferenceConfig.Temperature, 1)
+}
+
+// newBedrockUserContent returns the user message as content blocks with the images
+// first. The Converse API only accepts image bytes, so images given as remote URLs
+// fail with ErrImagesNotSupported.
+func newBedrockUserContent(userMessage string, images []Image) ([]bedrockContentBlock, error) {
+	blocks := make([]bedrockContentBlock, 0, len(images)+1)
+	for _, img := range images {
+		mediaType, data, ok := img.base64Data()
+		if !ok {
+			return nil, fmt.Errorf("%w: Bedrock requires image data instead of a URL", ErrImagesNotSupported)
+		}
+		image := &bedrockImage{Format: strings.TrimPrefix(mediaType, "image/")}
+		image.Source.Bytes = data
+		blocks = append(blocks, bedrockContentBlock{Image: image})
+	}
+	blocks = append(blocks, bedrockContentBlock{Text: userMessage})
+
+	return blocks, nil
+}
+
+// complete sends a non-streaming request and returns the concatenated text content.
+func (c *bedrockClient) complete(ctx context.Context, body bedrockRequest) (string, error) {
+	c.logger.Debug("creating Bedrock message",
+		slog.String("model", body.model),
+		slog.Float64("temperature", float64(body.InferenceConfig.Temperature)),
+		slog.Int("max_tokens", body.InferenceConfig.MaxTokens),
+	)
+
+	resp, err := c.postWithFallback(ctx, &body, "converse")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var result bedrockResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode Bedrock response: %w", err)
+	}
+	recordBedrockUsage(ctx, body.model, result.Usage)
+
+	var content strings.Builder
+	for _, block := range result.Output.Message.Content {
+		content.WriteString(block.Text)
+	}
+
+	if err := c.checkStopReason(body.model, result.StopReason); err != nil {
+		return "", err
+	}
+
+	if content.Len() == 0 {
+		return "", errors.New("empty response from Bedrock")
+	}
+
+	c.logger.Debug("Bedrock message successful",
+		slog.String("model", body.model),
+		slog.Int("response_len", content.Len()),
+		slog.Int("prompt_tokens", result.Usage.promptTokens()),
+		slog.Int("cached_tokens", result.Usage.CacheReadInputTokens),
+		slog.Int("completion_tokens", result.Usage.OutputTokens),
+	)
+
+	return content.String(), nil
+}
+
+// checkStopReason turns stop reasons that mean the answer is unusable into errors.
+func (c *bedrockClient) checkStopReason(model, stopReason string) error {
+	switch stopReason {
+	case "guardrail_intervened", "content_filtered":
+		return fmt.Errorf("Bedrock response blocked: %s", stopReason)
+	case "max_tokens":
+		c.logger.Warn("Bedrock response truncated at max tokens", slog.String("model", model))
+	}
+	return nil
+}
+
+// postWithFallback posts body to the given Converse operation, retrying with the
+// configured fallback models. On return body.model is the model that served the request.
+func (c *bedrockClient) postWithFallback(ctx context.Context, body *bedrockRequest, operation string) (*http.Response, error) {
+	resp, model, err := withModelFallback(ctx, body.model, c.cfg.ModelFallbacks, isBedrockFallbackError, c.logger,
+		func(model string) (*http.Response, error) {
+			body.model = model
+			return c.post(ctx, *body, operation)
+		},
+	)
+	return resp, asContextLengthError(err, model)
+}
+
+func (c *bedrockClient) post(ctx context.Context, body bedrockRequest, operation string) (*http.Response, error) {
+	if c.cfg.Region == "" {
+		return nil, errors.New("Bedrock region is not set: set BedrockConfig.Region or AWS_REGION")
+	}
+
+	creds, err := c.cfg.Credentials(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS credentials: %w", err)
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal Bedrock request: %w", err)
+	}
+
+	url := c.cfg.BaseURL + "/model/" + awsURIEncode(body.model) + "/" + operation
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	signAWSRequest(req, data, creds, c.cfg.Region, bedrockService, time.Now())
+
+	resp, err := c.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Bedrock API error: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, readBedrockError(resp)
+	}
+
+	return resp, nil
+}
+
+// readBedrockError builds a BedrockError from a non-200 response.
+func readBedrockError(resp *http.Response) error {
+	// The error type is sent as "ThrottlingException:http://internal.amazon.com/..."
+	errorType, _, _ := strings.Cut(resp.Header.Get("X-Amzn-Errortype"), ":")
+	apiErr := &BedrockError{StatusCode: resp.StatusCode, Type: errorType, Message: resp.Status}
+
+	// Some errors spell the field "Message", which Unmarshal matches as well
+	var body struct {
+		Message string `json:"message"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if json.Unmarshal(data, &body) == nil && body.Message != "" {
+		apiErr.Message = body.Message
+	}
+
+	return apiErr
+}
@@ -0,0 +1,221 @@
+package aichat
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignAWSRequest(t *testing.T) {
+	// The "get-vanilla" case of the AWS Signature Version 4 test suite
+	req := httptest.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	creds := AWSCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	signAWSRequest(req, nil, creds, "us-east-1", "service", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+		"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("Authorization = %q, want %q", got, want)
+	}
+}
+
+func TestBedrockChat(t *testing.T) {
+	var got struct {
+		path, authorization, token string
+		body                       bedrockRequest
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.path = r.URL.EscapedPath()
+		got.authorization = r.Header.Get("Authorization")
+		got.token = r.Header.Get("X-Amz-Security-Token")
+		if err := json.NewDecoder(r.Body).Decode(&got.body); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		w.Write([]byte(`{
+			"output": {"message": {"role": "assistant", "content": [{"text": "The Widget Pro "}, {"text": "has three speeds."}]}},
+			"stopReason": "end_turn",
+			"usage": {"inputTokens": 20, "outputTokens": 8, "cacheReadInputTokens": 100, "totalTokens": 128}
+		}`))
+	}))
+	defer server.Close()
+
+	client := newTestBedrockClient(server.URL)
+	ctx, lastCall := withLLMCallRecord(context.Background())
+	content, err := client.Chat(ctx, "You are a product expert.", "How many speeds?", &ChatOptions{
+		Model:                 ModelStandard,
+		Temperature:           1.5,
+		CacheableSystemPrefix: len("You are"),
+		Images:                []Image{{MediaType: "image/png", Data: "aGVsbG8="}},
+	})
+	if err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	if content != "The Widget Pro has three speeds." {
+		t.Errorf("Chat() = %q", content)
+	}
+
+	// The model ID is encoded in the path, and encoded again when signed
+	if want := "/model/global.anthropic.claude-sonnet-4-5-20250929-v1%3A0/converse"; got.path != want {
+		t.Errorf("path = %s, want %s", got.path, want)
+	}
+	if !strings.HasPrefix(got.authorization, "AWS4-HMAC-SHA256 Credential=AKIDTEST/") ||
+		!strings.Contains(got.authorization, "/eu-west-1/bedrock/aws4_request, SignedHeaders=content-type;host;x-amz-date;x-amz-security-token, Signature=") {
+		t.Errorf("Authorization = %q", got.authorization)
+	}
+	if got.token != "session-token" {
+		t.Errorf("X-Amz-Security-Token = %q", got.token)
+	}
+
+	body := got.body
+	if len(body.System) != 3 || body.System[0].Text != "You are" || body.System[1].CachePoint == nil || body.System[2].Text != " a product expert." {
+		t.Errorf("system = %+v, want the cacheable prefix followed by a cache point", body.System)
+	}
+	if len(body.Messages) != 1 || len(body.Messages[0].Content) != 2 ||
+		body.Messages[0].Content[0].Image == nil || body.Messages[0].Content[0].Image.Format != "png" ||
+		body.Messages[0].Content[1].Text != "How many speeds?" {
+		t.Errorf("messages = %+v, want the image followed by the text", body.Messages)
+	}
+	if body.InferenceConfig.Temperature != 1 || body.InferenceConfig.MaxTokens != defaultBedrockMaxTokens {
+		t.Errorf("inferenceConfig = %+v, want the temperature capped at 1 and the default max tokens", body.InferenceConfig)
+	}
+
+	want := TokenUsage{PromptTokens: 120, CompletionTokens: 8, TotalTokens: 128, CachedTokens: 100}
+	if record := lastCall(); record.Usage != want || record.Model != DefaultBedrockModelMap()[ModelStandard] {
+		t.Errorf("recorded usage %+v of %s, want %+v", record.Usage, record.Model, want)
+	}
+
+	// Bedrock only accepts image bytes
+	_, err = client.Chat(context.Background(), "", "What is this?", &ChatOptions{Images: []Image{{URL: "https://example.com/widget.png"}}})
+	if !errors.Is(err, ErrImagesNotSupported) {
+		t.Errorf("Chat(image URL) error = %v, want ErrImagesNotSupported", err)
+	}
+}
+
+func TestBedrockChatStream(t *testing.T) {
+	events := [][]byte{
+		awsEventFrame("messageStart", `{"role": "assistant"}`),
+		awsEventFrame("contentBlockDelta", `{"contentBlockIndex": 0, "delta": {"text": "Three "}}`),
+		awsEventFrame("contentBlockDelta", `{"contentBlockIndex": 0, "delta": {"text": "speeds."}}`),
+		awsEventFrame("contentBlockStop", `{"contentBlockIndex": 0}`),
+		awsEventFrame("messageStop", `{"stopReason": "end_turn"}`),
+		awsEventFrame("metadata", `{"usage": {"inputTokens": 12, "outputTokens": 3, "totalTokens": 15}, "metrics": {"latencyMs": 200}}`),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/converse-stream") {
+			t.Errorf("path = %s, want the converse-stream operation", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/vnd.amazon.eventstream")
+		w.Write(bytes.Join(events, nil))
+	}))
+	defer server.Close()
+
+	client := newTestBedrockClient(server.URL)
+	ctx, lastCall := withLLMCallRecord(context.Background())
+	var tokens []string
+	content, err := client.ChatStream(ctx, "", "How many speeds?", nil, func(token string) {
+		tokens = append(tokens, token)
+	})
+	if err != nil {
+		t.Fatalf("ChatStream() error = %v", err)
+	}
+	if content != "Three speeds." || len(tokens) != 2 {
+		t.Errorf("ChatStream() = %q with tokens %q", content, tokens)
+	}
+	if want := (TokenUsage{PromptTokens: 12, CompletionTokens: 3, TotalTokens: 15}); lastCall().Usage != want {
+		t.Errorf("recorded usage %+v, want %+v", lastCall().Usage, want)
+	}
+
+	// An exception after the response started ends the stream with an error
+	events = [][]byte{
+		awsEventFrame("contentBlockDelta", `{"contentBlockIndex": 0, "delta": {"text": "Three "}}`),
+		awsExceptionFrame("throttlingException", `{"message": "Too many requests"}`),
+	}
+	content, err = client.ChatStream(context.Background(), "", "How many speeds?", nil, nil)
+	var apiErr *BedrockError
+	if !errors.As(err, &apiErr) || apiErr.Type != "throttlingException" || content != "Three " {
+		t.Errorf("ChatStream() = %q, %v; want the partial content and a throttling error", content, err)
+	}
+
+	// A corrupted message is rejected
+	frame := awsEventFrame("contentBlockDelta", `{"contentBlockIndex": 0, "delta": {"text": "Three "}}`)
+	frame[len(frame)-5] ^= 0xff
+	events = [][]byte{frame}
+	if _, err := client.ChatStream(context.Background(), "", "How many speeds?", nil, nil); !errors.Is(err, errAWSEventChecksum) {
+		t.Errorf("ChatStream(corrupted) error = %v, want a checksum error", err)
+	}
+}
+
+func TestBedrockErrors(t *testing.T) {
+	var calls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.URL.EscapedPath())
+		w.Header().Set("X-Amzn-Errortype", "ValidationException:http://internal.amazon.com/coral/com.amazon.bedrock/")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"message": "Input is too long for requested model."}`))
+	}))
+	defer server.Close()
+
+	client := newTestBedrockClient(server.URL)
+	_, err := client.Chat(context.Background(), "", "A very long question", &ChatOptions{Model: ModelMini})
+
+	var lengthErr *ContextLengthError
+	if !errors.Is(err, ErrContextLengthExceeded) || !errors.As(err, &lengthErr) || lengthErr.Limit != 200_000 {
+		t.Fatalf("Chat() error = %v, want a ContextLengthError with the model's context window", err)
+	}
+	var apiErr *BedrockError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest || apiErr.Type != "ValidationException" {
+		t.Errorf("Chat() error = %v, want the Bedrock validation error", err)
+	}
+	if len(calls) != 1 {
+		t.Errorf("made %d calls, want 1 without fallback models", len(calls))
+	}
+}
+
+func newTestBedrockClient(baseURL string) LLMClient {
+	return NewBedrockClient(BedrockConfig{
+		Region:  "eu-west-1",
+		BaseURL: baseURL,
+		Credentials: func(ctx context.Context) (AWSCredentials, error) {
+			return AWSCredentials{AccessKeyID: "AKIDTEST", SecretAccessKey: "secret", SessionToken: "session-token"}, nil
+		},
+		Logger: slog.New(slog.DiscardHandler),
+	})
+}
+
+func awsEventFrame(eventType, payload string) []byte {
+	return encodeAWSEvent(map[string]string{":message-type": "event", ":event-type": eventType}, payload)
+}
+
+func awsExceptionFrame(exceptionType, payload string) []byte {
+	return encodeAWSEvent(map[string]string{":message-type": "exception", ":exception-type": exceptionType}, payload)
+}
+
+// encodeAWSEvent encodes an event stream message with string headers.
+func encodeAWSEvent(headers map[string]string, payload string) []byte {
+	var encodedHeaders bytes.Buffer
+	for name, value := range headers {
+		encodedHeaders.WriteByte(byte(len(name)))
+		encodedHeaders.WriteString(name)
+		encodedHeaders.WriteByte(7)
+		binary.Write(&encodedHeaders, binary.BigEndian, uint16(len(value)))
+		encodedHeaders.WriteString(value)
+	}
+
+	var message bytes.Buffer
+	binary.Write(&message, binary.BigEndian, uint32(16+encodedHeaders.Len()+len(payload)))
+	binary.Write(&message, binary.BigEndian, uint32(encodedHeaders.Len()))
+	binary.Write(&message, binary.BigEndian, crc32.ChecksumIEEE(message.Bytes()))
+	message.Write(encodedHeaders.Bytes())
+	message.WriteString(payload)
+	binary.Write(&message, binary.BigEndian, crc32.ChecksumIEEE(message.Bytes()))
+	return message.Bytes()
+}
@@ -1,47 +1,127 @@
 package aichat
 
 import (
+	"context"
 	"errors"
 	"log/slog"
 	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
 )
 
 // SDK is the main AI Chat SDK instance.
 type SDK struct {
-	config      *Config
-	logger      *slog.Logger
-	processChat ProcessChatFn
-	httpHandler http.Handler
+	config                *Config
+	logger                *slog.Logger
+	llmClient             LLMClient
+	processChat           ProcessChatFn
+	processChatStream     ProcessChatStreamFn
+	exportConversation    ExportConversationFn
+	summarizeConversation SummarizeConversationFn
+	updateConversation    UpdateConversationFn
+	forkConversation      ForkConversationFn
+	truncateConversation  TruncateConversationFn
+	conversationUsage     ConversationUsageFn
+	submitFeedback        SubmitFeedbackFn
+	shutdownWebhooks      func(ctx context.Context) error
+	store                 ConversationStore
+	httpHandler           http.Handler
+	routes                chi.Router
 }
 
 // New creates a new AI Chat SDK instance.
 func New(config Config) (*SDK, error) {
 	config.applyDefaults()
 
-	if config.OpenAIClient == nil {
-		return nil, errors.New("OpenAIClient is required")
+	customClient := config.LLMClient.Chat != nil || config.LLMClient.ChatJSON != nil || config.LLMClient.ChatStream != nil
+	if customClient {
+		if config.LLMClient.Chat == nil || config.LLMClient.ChatJSON == nil || config.LLMClient.ChatStream == nil {
+			return nil, errors.New("LLMClient must implement Chat, ChatJSON and ChatStream")
+		}
+	} else if config.OpenAIClient == nil {
+		return nil, errors.New("OpenAIClient or LLMClient is required")
 	}
 
 	if len(config.Experts) == 0 {
 		return nil, errors.New("at least one expert must be configured")
 	}
 
-	if len(config.AllowedOrigins) == 0 {
-		return nil, errors.New("AllowedOrigins must be configured (or enable DevMode)")
+	if err := config.validateExperts(); err != nil {
+		return nil, err
+	}
+
+	if err := config.validateBatch(); err != nil {
+		return nil, err
+	}
+
+	if err := config.validateCORS(); err != nil {
+		return nil, err
+	}
+
+	if err := config.LogPrompts.validate(); err != nil {
+		return nil, err
+	}
+
+	if config.InjectionGuard.enabled() {
+		if err := config.InjectionGuard.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.Webhooks.enabled() {
+		if err := config.Webhooks.validate(); err != nil {
+			return nil, err
+		}
 	}
 
 	logger := config.Logger
 
-	// Wrap OpenAI client with internal API
-	openaiClient := newInternalOpenAIClient(config.OpenAIClient, logger, config.ModelMap)
+	// Resolve entities and enforce expert quotas, then serve cacheable experts from the
+	// semantic response cache if configured so that cache hits skip both. Data is
+	// validated first of all
+	experts := config.Experts
+	if config.EntityResolver != nil {
+		experts = withEntityResolver(experts, config.EntityResolver)
+	}
+	experts = withQuotas(experts, config.QuotaLimiter, logger)
+	if config.ResponseCache.Embed != nil {
+		experts = withResponseCache(experts, config.ResponseCache, logger)
+	}
+	experts = withDataValidation(experts)
+
+	// Use the custom LLM client, or wrap the OpenAI client with the internal API
+	llmClient := config.LLMClient
+	if !customClient {
+		llmClient = newInternalOpenAIClient(config.OpenAIClient, logger, config.ModelMap, config.ModelFallbacks, config.Seed, config.ReasoningModels)
+	}
+	if config.EnablePromptCache {
+		llmClient = newPromptCacheMiddleware(defaultPromptCacheSize, logger)(llmClient)
+	}
+	if config.Persona != "" || len(config.Personas) > 0 {
+		llmClient = newPersonaMiddleware()(llmClient)
+	}
+	if config.ReturnTrace {
+		llmClient = newTraceMiddleware()(llmClient)
+	}
+	if logPrompts := newPromptLoggingMiddleware(config.LogPrompts, logger); logPrompts != nil {
+		llmClient = logPrompts(llmClient)
+	}
+	llmClient = applyLLMMiddleware(llmClient, config.LLMMiddleware)
 
 	// Create translator
-	translateFn := newTranslator(openaiClient.ChatJSON, logger, config.TranslatorSystemPrompt)
+	translateFn := newTranslator(
+		llmClient.ChatJSON,
+		config.LanguageDetector,
+		config.LanguageDetectionThreshold,
+		logger,
+		config.TranslatorSystemPrompt,
+	)
 
 	// Create router
 	routeQuestionFn := newRouter(
-		openaiClient.ChatJSON,
-		config.Experts,
+		llmClient.ChatJSON,
+		experts,
 		config.RouterSystemPromptTemplate,
 		config.DefaultExpert,
 		config.DefaultReasoning,
@@ -49,21 +129,30 @@ func New(config Config) (*SDK, error) {
 	)
 
 	// Create formatter
-	formatResponseFn := newFormatter(openaiClient.Chat, logger, config.FormatterSystemPrompt)
+	formatResponseFn := newFormatter(llmClient.Chat, logger, config.FormatterSystemPrompt)
+	if config.OutputModerator != nil {
+		formatResponseFn = withOutputModeration(formatResponseFn, config.OutputModerator, config.ModerationFallback, logger)
+	}
+
+	// Answer unmatched questions with the canned response if configured
+	dispatchExperts, dispatchDefaultExpert := experts, config.DefaultExpert
+	if config.NoMatchResponse != "" && config.DefaultExpert == "" {
+		dispatchExperts, dispatchDefaultExpert = withNoMatchExpert(experts, config.NoMatchResponse), noMatchExpert
+	}
 
 	// Create dispatcher (non-streaming for regular chat)
 	dispatchQuestionFn := NewDispatcher(
 		routeQuestionFn,
-		config.Experts,
-		config.DefaultExpert,
+		dispatchExperts,
+		dispatchDefaultExpert,
 		logger,
 	)
 
 	// Create streaming dispatcher
 	dispatchQuestionStreamFn := NewDispatcherStreaming(
 		routeQuestionFn,
-		config.Experts,
-		config.DefaultExpert,
+		dispatchExperts,
+		dispatchDefaultExpert,
 		logger,
 	)
 
@@ -72,6 +161,13 @@ func New(config Config) (*SDK, error) {
 	if store.Create == nil {
 		store = NewMemoryStore(logger)
 	}
+	store = withConversationOwnership(store)
+	store = withEphemeralConversations(store)
+
+	// Suggest follow-up questions alongside formatting if configured
+	if config.GenerateSuggestions {
+		formatResponseFn = withFollowupSuggestions(formatResponseFn, llmClient.ChatJSON, store, logger)
+	}
 
 	// Create chat service (non-streaming)
 	processChatFn := NewChatService(
@@ -91,36 +187,284 @@ func New(config Config) (*SDK, error) {
 		logger,
 	)
 
+	// Validate and apply per-request model and temperature overrides
+	processChatFn = withModelOverrides(processChatFn, config.AllowedModels)
+	processChatStreamFn = withModelOverridesStreaming(processChatStreamFn, config.AllowedModels)
+
+	// Reject images up front when the LLM can't process them
+	if config.DisableImages {
+		processChatFn = withoutImages(processChatFn)
+		processChatStreamFn = withoutImagesStreaming(processChatStreamFn)
+	}
+
+	// Resolve the request's persona
+	processChatFn = withPersonas(processChatFn, config.Personas, config.Persona)
+	processChatStreamFn = withPersonasStreaming(processChatStreamFn, config.Personas, config.Persona)
+
+	// Pick each request's default expert if configured
+	if config.DefaultExpertResolver != nil {
+		processChatFn = withDefaultExpertResolvers(processChatFn, config.DefaultExpertResolver, experts, logger)
+		processChatStreamFn = withDefaultExpertResolversStreaming(processChatStreamFn, config.DefaultExpertResolver, experts, logger)
+	}
+
+	// Withhold streamed content until the answer has been moderated
+	if config.OutputModerator != nil {
+		processChatStreamFn = withBufferedContent(processChatStreamFn)
+	}
+
+	// Block or flag suspected prompt injections if configured
+	if config.InjectionGuard.enabled() {
+		scoreInjection := newInjectionScorer(config.InjectionGuard, llmClient.ChatJSON, logger)
+		processChatFn = withInjectionGuard(processChatFn, config.InjectionGuard, scoreInjection, store, logger)
+		processChatStreamFn = withInjectionGuardStreaming(processChatStreamFn, config.InjectionGuard, scoreInjection, store, logger)
+	}
+
+	// Create conversations under client-supplied IDs on first use if allowed
+	if config.AllowClientConversationIDs {
+		processChatFn = withClientConversationIDs(processChatFn, store)
+		processChatStreamFn = withClientConversationIDsStreaming(processChatStreamFn, store)
+	}
+
+	// Enforce the per-conversation token budget if configured
+	if config.MaxConversationTokens > 0 {
+		processChatFn = withConversationTokenBudget(processChatFn, store, config.MaxConversationTokens)
+		processChatStreamFn = withConversationTokenBudgetStreaming(processChatStreamFn, store, config.MaxConversationTokens)
+	}
+
+	// Regenerate last responses under the conversation lock
+	processChatFn = withRegeneration(processChatFn, store, logger)
+
+	// Serialize turns on the same conversation
+	processChatFn = withConversationLock(processChatFn, config.ConversationLocker, !config.RejectConcurrentTurns)
+	processChatStreamFn = withConversationLockStreaming(processChatStreamFn, config.ConversationLocker, !config.RejectConcurrentTurns)
+
+	// Generate conversation and message IDs if configured
+	if config.IDGenerator.NewConversationID != nil || config.IDGenerator.NewMessageID != nil {
+		processChatFn = withIDGenerators(processChatFn, config.IDGenerator)
+		processChatStreamFn = withIDGeneratorsStreaming(processChatStreamFn, config.IDGenerator)
+	}
+
+	// Keep ephemeral requests out of the store
+	processChatFn = withEphemeralRequests(processChatFn, config.DisablePersistence)
+	processChatStreamFn = withEphemeralRequestsStreaming(processChatStreamFn, config.DisablePersistence)
+
+	// Deliver chat.completed webhooks if configured
+	var notifyWebhook NotifyWebhookFn
+	var shutdownWebhooks func(ctx context.Context) error
+	if config.Webhooks.enabled() {
+		notifyWebhook, shutdownWebhooks = newWebhookNotifier(config.Webhooks, logger)
+		processChatFn = withChatCompletedWebhook(processChatFn, notifyWebhook)
+		processChatStreamFn = withChatCompletedWebhookStreaming(processChatStreamFn, notifyWebhook)
+	}
+
+	// Create readiness check (default to listing models on the provider)
+	healthCheck := config.HealthCheck
+	if healthCheck == nil {
+		if config.OpenAIClient != nil {
+			healthCheck = NewOpenAIHealthCheck(config.OpenAIClient)
+		} else {
+			logger.Warn("no HealthCheck configured for custom LLMClient, /health/ready will always report ok")
+			healthCheck = func(ctx context.Context) error { return nil }
+		}
+	}
+
+	// Create conversation exporter and updater
+	exportConversationFn := NewConversationExporter(store)
+	summarizeConversationFn := newConversationSummarizer(
+		store,
+		llmClient.Chat,
+		config.ConversationLocker,
+		config.SummarySystemPrompt,
+		config.SummaryModel,
+		logger,
+	)
+	updateConversationFn := NewConversationUpdater(store, config.ConversationLocker)
+	forkConversationFn := newConversationForker(store, config.IDGenerator)
+	truncateConversationFn := newConversationTruncator(store, config.ConversationLocker)
+	conversationUsageFn := newConversationUsageReporter(store, config.ModelPricing)
+
+	// Create feedback recorder
+	submitFeedbackFn := NewFeedbackRecorder(store)
+	if notifyWebhook != nil {
+		submitFeedbackFn = withFeedbackReceivedWebhook(submitFeedbackFn, notifyWebhook)
+	}
+
 	// Create HTTP handlers
 	healthHandler := newHealthHandler()
+	readinessHandler := newReadinessHandler(cachedHealthCheck(healthCheck, 30*time.Second, 5*time.Second), logger)
 	chatHandler := newChatHandler(processChatFn, config.MaxMessageLength, logger)
 	chatStreamHandler := newChatStreamHandler(processChatStreamFn, config.MaxMessageLength, logger)
+	chatBatchHandler := newChatBatchHandler(
+		processChatFn,
+		config.MaxMessageLength,
+		config.MaxBatchSize,
+		config.BatchConcurrency,
+		logger,
+	)
+	exportHandler := newExportHandler(exportConversationFn, logger)
+	summaryHandler := newSummaryHandler(summarizeConversationFn, logger)
+	updateConversationHandler := newUpdateConversationHandler(updateConversationFn, logger)
+	regenerateHandler := newRegenerateHandler(processChatFn, logger)
+	forkConversationHandler := newForkConversationHandler(forkConversationFn, logger)
+	truncateConversationHandler := newTruncateConversationHandler(truncateConversationFn, logger)
+	usageHandler := newUsageHandler(conversationUsageFn, logger)
+	submitFeedbackHandler := newSubmitFeedbackHandler(submitFeedbackFn, logger)
+	getFeedbackHandler := newGetFeedbackHandler(store, logger)
 
 	// Create HTTP router
-	httpHandler := newHTTPRouter(
-		config.AllowedOrigins,
+	httpHandler, routes := newHTTPRouter(
+		config.corsOptions(),
 		config.RequestTimeout,
+		config.BatchTimeout,
 		config.MaxRequestBodySize,
 		logger,
+		config.HTTPMiddleware,
+		config.Authenticator,
 		healthHandler,
+		readinessHandler,
 		chatHandler,
 		chatStreamHandler,
+		chatBatchHandler,
+		exportHandler,
+		summaryHandler,
+		usageHandler,
+		updateConversationHandler,
+		regenerateHandler,
+		forkConversationHandler,
+		truncateConversationHandler,
+		submitFeedbackHandler,
+		getFeedbackHandler,
 	)
 
 	return &SDK{
-		config:      &config,
-		logger:      logger,
-		processChat: processChatFn,
-		httpHandler: httpHandler,
+		config:                &config,
+		logger:                logger,
+		llmClient:             llmClient,
+		processChat:           processChatFn,
+		processChatStream:     processChatStreamFn,
+		exportConversation:    exportConversationFn,
+		summarizeConversation: summarizeConversationFn,
+		updateConversation:    updateConversationFn,
+		forkConversation:      forkConversationFn,
+		truncateConversation:  truncateConversationFn,
+		conversationUsage:     conversationUsageFn,
+		submitFeedback:        submitFeedbackFn,
+		shutdownWebhooks:      shutdownWebhooks,
+		store:                 store,
+		httpHandler:           httpHandler,
+		routes:                routes,
 	}, nil
 }
 
+// LLMClient returns the SDK's LLM client, including configured middleware and the prompt cache.
+// Expert handlers can use it to share model mapping, logging and caching with the pipeline.
+func (s *SDK) LLMClient() LLMClient {
+	return s.llmClient
+}
+
 // ProcessChat returns the chat processing function for direct use (without HTTP).
 func (s *SDK) ProcessChat() ProcessChatFn {
 	return s.processChat
 }
 
+// ProcessChatStream returns the streaming chat processing function for custom transports.
+func (s *SDK) ProcessChatStream() ProcessChatStreamFn {
+	return s.processChatStream
+}
+
+// ExportConversation exports a stored conversation as OpenAI chat messages,
+// either as a JSON array (ExportFormatOpenAI) or one message per line (ExportFormatJSONL).
+func (s *SDK) ExportConversation(ctx context.Context, id string, format ExportFormat) ([]byte, error) {
+	return s.exportConversation(ctx, id, format)
+}
+
+// SummarizeConversation returns a one-paragraph summary of a conversation, e.g. for
+// a support agent taking over the chat. The summary is cached on the conversation
+// and regenerated once new messages arrive.
+func (s *SDK) SummarizeConversation(ctx context.Context, id string) (string, error) {
+	return s.summarizeConversation(ctx, id)
+}
+
+// ConversationUsage reports the tokens a conversation has used, with an estimated
+// cost, broken down by model and expert. Costs are estimated from Config.ModelPricing.
+func (s *SDK) ConversationUsage(ctx context.Context, id string) (*UsageReport, error) {
+	return s.conversationUsage(ctx, id)
+}
+
+// UpdateConversation merges metadata into a conversation and, if update.Tags is non-nil, replaces its tags.
+func (s *SDK) UpdateConversation(ctx context.Context, id string, update ConversationUpdate) (*Conversation, error) {
+	return s.updateConversation(ctx, id, update)
+}
+
+// RegenerateResponse answers the last user message of a conversation again and
+// replaces the previous answer, optionally with different sampling. It returns
+// ErrNothingToRegenerate if the conversation does not end with an assistant message.
+// If answering fails, the conversation is left unchanged.
+func (s *SDK) RegenerateResponse(ctx context.Context, id string, opts RegenerateOptions) (*ChatResult, error) {
+	return s.processChat(withRegenerationRequest(ctx, &regeneration{}), newRegenerateRequest(id, opts))
+}
+
+// ForkConversation copies a conversation up to and including fromMessageID into a new
+// conversation, e.g. to explore an alternative answer, and returns the new conversation's ID.
+func (s *SDK) ForkConversation(ctx context.Context, id, fromMessageID string) (string, error) {
+	return s.forkConversation(ctx, id, fromMessageID)
+}
+
+// TruncateConversation removes the messages after afterMessageID, e.g. so a user can
+// edit a message and continue from there. It returns the truncated conversation.
+func (s *SDK) TruncateConversation(ctx context.Context, id, afterMessageID string) (*Conversation, error) {
+	return s.truncateConversation(ctx, id, afterMessageID)
+}
+
+// ListConversations returns stored conversations matching the filter.
+// It returns ErrNotSupported if the configured store does not implement List.
+func (s *SDK) ListConversations(ctx context.Context, filter ConversationFilter) ([]*Conversation, error) {
+	if s.store.List == nil {
+		return nil, ErrNotSupported
+	}
+	return s.store.List(ctx, filter)
+}
+
+// SubmitFeedback stores a user's rating of an assistant message.
+func (s *SDK) SubmitFeedback(ctx context.Context, feedback Feedback) (*Feedback, error) {
+	return s.submitFeedback(ctx, feedback)
+}
+
+// GetFeedback returns the feedback on a message, or ErrFeedbackNotFound.
+func (s *SDK) GetFeedback(ctx context.Context, messageID string) (*Feedback, error) {
+	if s.store.GetFeedback == nil {
+		return nil, ErrNotSupported
+	}
+	return s.store.GetFeedback(ctx, messageID)
+}
+
+// ListFeedback returns all feedback in a conversation, oldest first.
+func (s *SDK) ListFeedback(ctx context.Context, conversationID string) ([]Feedback, error) {
+	if s.store.ListFeedback == nil {
+		return nil, ErrNotSupported
+	}
+	return s.store.ListFeedback(ctx, conversationID)
+}
+
+// Shutdown stops webhook delivery, delivering queued events until ctx is done.
+// Events notified afterwards are dropped. ListenAndServe calls it when stopping;
+// call it yourself when serving HTTPHandler with your own server.
+func (s *SDK) Shutdown(ctx context.Context) error {
+	if s.shutdownWebhooks == nil {
+		return nil
+	}
+	return s.shutdownWebhooks(ctx)
+}
+
 // HTTPHandler returns the HTTP handler for the SDK.
 func (s *SDK) HTTPHandler() http.Handler {
 	return s.httpHandler
 }
+
+// RegisterRoute adds a custom route to the SDK's HTTP handler, e.g. GET /version.
+// The route runs behind the same middleware as the SDK's routes, including
+// Config.Authenticator. Register routes before serving; it is not safe to call
+// concurrently with requests. It panics on an unsupported method or a conflicting pattern.
+func (s *SDK) RegisterRoute(method, pattern string, handler http.Handler) {
+	s.routes.Method(method, pattern, handler)
+}
@@ -0,0 +1,232 @@
+// Package chatws provides a WebSocket transport for AI Chat SDK streaming chat.
+//
+// It lives in its own package so that SSE-only users of the SDK don't pull in
+// the WebSocket dependency. Mount the handler next to the SDK's HTTP handler:
+//
+//	mux := http.NewServeMux()
+//	mux.Handle("/chat/ws", chatws.NewHandler(sdk.ProcessChatStream(), chatws.Options{
+//	    AllowedOrigins: []string{"https://myapp.com"},
+//	}))
+//	mux.Handle("/", sdk.HTTPHandler())
+package chatws
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/websocket"
+
+	aichat "github.com/ourstudio-se/ai-chat-sdk"
+)
+
+// FrameType identifies the type of a client-sent frame.
+type FrameType string
+
+const (
+	// FrameChat starts a chat request.
+	FrameChat FrameType = "chat"
+
+	// FrameCancel cancels the in-flight chat request.
+	FrameCancel FrameType = "cancel"
+)
+
+// ClientFrame is a JSON frame sent by the client.
+type ClientFrame struct {
+	Type           FrameType      `json:"type"`
+	Message        string         `json:"message,omitempty"`
+	ConversationID string         `json:"conversationId,omitempty"`
+	EntityID       string         `json:"entityId,omitempty"`
+	Data           any            `json:"data,omitempty"`
+	Images         []aichat.Image `json:"images,omitempty"`
+}
+
+// Options configures the WebSocket handler.
+type Options struct {
+	// AllowedOrigins lists origins allowed to connect. "*" allows any origin.
+	// If empty, only same-origin connections are accepted.
+	AllowedOrigins []string
+
+	// MaxMessageLength is the maximum length of a chat message in characters (defaults to 1000).
+	MaxMessageLength int
+
+	// Logger is the structured logger to use. If nil, a default logger is used.
+	Logger *slog.Logger
+}
+
+// NewHandler returns a handler that upgrades GET requests to WebSocket connections.
+//
+// Each connection accepts "chat" frames and streams back aichat.StreamEvent frames
+// (thinking, translating, routing, processing, content, done, error). A "cancel"
+// frame cancels the in-flight request's context. One request runs at a time per connection.
+func NewHandler(processChatStream aichat.ProcessChatStreamFn, opts Options) http.Handler {
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	if opts.MaxMessageLength == 0 {
+		opts.MaxMessageLength = 1000
+	}
+
+	upgrader := websocket.Upgrader{
+		CheckOrigin: newOriginChecker(opts.AllowedOrigins),
+	}
+	logger := opts.Logger
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			logger.Warn("websocket upgrade failed", "error", err)
+			return
+		}
+		defer conn.Close()
+
+		ctx, cancelConn := context.WithCancel(r.Context())
+		defer cancelConn()
+
+		var writeMu sync.Mutex
+		send := func(event aichat.StreamEvent) {
+			writeMu.Lock()
+			defer writeMu.Unlock()
+			if err := conn.WriteJSON(event); err != nil {
+				logger.Debug("failed to write websocket frame", "error", err)
+			}
+		}
+
+		var mu sync.Mutex
+		var cancelRequest context.CancelFunc
+		var wg sync.WaitGroup
+		defer wg.Wait()
+
+		for {
+			var frame ClientFrame
+			if err := conn.ReadJSON(&frame); err != nil {
+				if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+					logger.Debug("websocket read ended", "error", err)
+				}
+				return
+			}
+
+			switch frame.Type {
+			case FrameCancel:
+				mu.Lock()
+				if cancelRequest != nil {
+					cancelRequest()
+				}
+				mu.Unlock()
+
+			case FrameChat:
+				if msg := validateMessage(frame.Message, opts.MaxMessageLength); msg != "" {
+					send(errorEvent(aichat.CodeInvalidRequest, msg))
+					continue
+				}
+
+				mu.Lock()
+				if cancelRequest != nil {
+					mu.Unlock()
+					send(errorEvent(aichat.CodeInvalidRequest, "A request is already in progress"))
+					continue
+				}
+				reqCtx, cancel := context.WithCancel(ctx)
+				cancelRequest = cancel
+				mu.Unlock()
+
+				wg.Add(1)
+				go func(frame ClientFrame) {
+					defer wg.Done()
+					defer func() {
+						mu.Lock()
+						cancelRequest = nil
+						mu.Unlock()
+						cancel()
+					}()
+
+					runChat(reqCtx, processChatStream, frame, send, logger)
+				}(frame)
+
+			default:
+				send(errorEvent(aichat.CodeInvalidRequest, fmt.Sprintf("Unknown frame type %q", frame.Type)))
+			}
+		}
+	})
+}
+
+func runChat(
+	ctx context.Context,
+	processChatStream aichat.ProcessChatStreamFn,
+	frame ClientFrame,
+	send aichat.StreamCallback,
+	logger *slog.Logger,
+) {
+	send(aichat.StreamEvent{Type: aichat.EventThinking})
+
+	result, err := processChatStream(ctx, aichat.ChatRequest{
+		Message:        frame.Message,
+		ConversationID: frame.ConversationID,
+		EntityID:       frame.EntityID,
+		Data:           frame.Data,
+		Images:         frame.Images,
+	}, send)
+	if err != nil {
+		if ctx.Err() == context.Canceled {
+			send(errorEvent(aichat.CodeInvalidRequest, "Request cancelled"))
+			return
+		}
+		logger.Error("failed to process chat message", "error", err)
+		send(errorEvent(aichat.CodeInternal, "An error occurred while processing your message"))
+		return
+	}
+
+	expertType := result.ExpertResult.ExpertType
+	event := aichat.StreamEvent{
+		Type:           aichat.EventDone,
+		ConversationID: &result.ConversationID,
+		Expert:         &expertType,
+		ExpertName:     &result.ExpertResult.ExpertName,
+		Content:        &result.ExpertResult.Answer,
+		Data:           result.ExpertResult.Details,
+
+		TokensRemaining:    result.TokensRemaining,
+		SuggestedFollowups: result.SuggestedFollowups,
+	}
+	if result.MessageID != "" {
+		event.MessageID = &result.MessageID
+	}
+	send(event)
+}
+
+func validateMessage(message string, maxMessageLength int) string {
+	if message == "" {
+		return "Message cannot be empty"
+	}
+	if len(message) > maxMessageLength {
+		return fmt.Sprintf("Message exceeds maximum length of %d characters", maxMessageLength)
+	}
+	return ""
+}
+
+func errorEvent(code aichat.ErrorCode, message string) aichat.StreamEvent {
+	return aichat.StreamEvent{
+		Type:    aichat.EventError,
+		Content: &message,
+		Code:    code,
+	}
+}
+
+// newOriginChecker returns an origin check for the upgrader, or nil to use
+// gorilla/websocket's default same-origin check.
+func newOriginChecker(allowedOrigins []string) func(r *http.Request) bool {
+	if len(allowedOrigins) == 0 {
+		return nil
+	}
+
+	allowed := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		allowed[origin] = true
+	}
+
+	return func(r *http.Request) bool {
+		return allowed["*"] || allowed[r.Header.Get("Origin")]
+	}
+}
@@ -0,0 +1,119 @@
+package aichat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// HTTPClient calls the HTTP API served by SDK.HTTPHandler, e.g. from a worker
+// that processes a queue of messages in another service.
+type HTTPClient struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// HTTPClientOption configures an HTTPClient.
+type HTTPClientOption func(*HTTPClient)
+
+// WithBearerToken sends token as "Authorization: Bearer <token>" with every request,
+// for APIs protected by Config.Authenticator.
+func WithBearerToken(token string) HTTPClientOption {
+	return func(c *HTTPClient) {
+		c.token = token
+	}
+}
+
+// WithHTTPClient sets the http.Client used to send requests (defaults to
+// http.DefaultClient). Set its Timeout above the server's BatchTimeout for batches.
+func WithHTTPClient(httpClient *http.Client) HTTPClientOption {
+	return func(c *HTTPClient) {
+		c.httpClient = httpClient
+	}
+}
+
+// NewHTTPClient creates a client for the API served at baseURL, e.g. "https://chat.example.com/api".
+func NewHTTPClient(baseURL string, opts ...HTTPClientOption) *HTTPClient {
+	c := &HTTPClient{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// HTTPError is returned by HTTPClient when the API responds with an error status.
+type HTTPError struct {
+	Status  int
+	Code    ErrorCode
+	Message string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("chat API returned %d (%s): %s", e.Status, e.Code, e.Message)
+}
+
+// Chat sends a message to POST /chat.
+func (c *HTTPClient) Chat(ctx context.Context, req HTTPChatRequest) (*HTTPChatResponse, error) {
+	var response HTTPChatResponse
+	if err := c.post(ctx, "/chat", req, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// ChatBatch sends several messages to POST /chat/batch and returns their results
+// in request order. A failing item is reported in its result, not as an error;
+// the error is only set when the batch as a whole fails.
+func (c *HTTPClient) ChatBatch(ctx context.Context, reqs []HTTPChatRequest) ([]HTTPChatBatchResult, error) {
+	var results []HTTPChatBatchResult
+	if err := c.post(ctx, "/chat/batch", reqs, &results); err != nil {
+		return nil, err
+	}
+	if len(results) != len(reqs) {
+		return nil, fmt.Errorf("chat API returned %d batch results for %d requests", len(results), len(reqs))
+	}
+	return results, nil
+}
+
+// post sends body as JSON to path and decodes the JSON response into result.
+func (c *HTTPClient) post(ctx context.Context, path string, body, result any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil || errResp.Error == "" {
+			return &HTTPError{Status: resp.StatusCode, Code: errorCodeForStatus(resp.StatusCode), Message: http.StatusText(resp.StatusCode)}
+		}
+		return &HTTPError{Status: resp.StatusCode, Code: errResp.Code, Message: errResp.Error}
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
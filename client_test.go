@@ -0,0 +1,60 @@
+package aichat_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	aichat "github.com/ourstudio-se/ai-chat-sdk"
+	"github.com/ourstudio-se/ai-chat-sdk/aichattest"
+)
+
+func TestHTTPClientChatBatch(t *testing.T) {
+	ctx := context.Background()
+	llm := aichattest.NewLLM(script(turn("The Widget Pro has three speeds."))...)
+	sdk := newTestSDK(t, llm, aichat.Config{
+		Authenticator: aichat.NewAPIKeyAuthenticator(map[string]aichat.Principal{"worker-key": {ID: "worker"}}),
+		// One worker keeps the scripted calls in order
+		BatchConcurrency: 1,
+	})
+	server := httptest.NewServer(sdk.HTTPHandler())
+	defer server.Close()
+
+	missing := "missing"
+	client := aichat.NewHTTPClient(server.URL+"/", aichat.WithBearerToken("worker-key"))
+
+	results, err := client.ChatBatch(ctx, []aichat.HTTPChatRequest{
+		{Message: ""},
+		{Message: "How many speeds?"},
+		{Message: "Continue", ConversationID: &missing},
+	})
+	if err != nil {
+		t.Fatalf("ChatBatch() error = %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("ChatBatch() returned %d results, want 3", len(results))
+	}
+
+	if results[0].Status != http.StatusBadRequest || results[0].Code != aichat.CodeInvalidRequest || results[0].Response != nil {
+		t.Errorf("results[0] = %+v, want a 400 invalid_request entry", results[0])
+	}
+	if results[1].Status != http.StatusOK || results[1].Response == nil || results[1].Response.Response != "The Widget Pro has three speeds." {
+		t.Errorf("results[1] = %+v, want the answer", results[1])
+	}
+	if results[2].Status != http.StatusNotFound || results[2].Code != aichat.CodeNotFound {
+		t.Errorf("results[2] = %+v, want a 404 not_found entry", results[2])
+	}
+	llm.AssertDone(t)
+
+	// A batch that fails as a whole returns an error
+	var httpErr *aichat.HTTPError
+	if _, err := client.ChatBatch(ctx, []aichat.HTTPChatRequest{}); !errors.As(err, &httpErr) || httpErr.Status != http.StatusBadRequest {
+		t.Errorf("ChatBatch(empty) error = %v, want a 400 HTTPError", err)
+	}
+	unauthenticated := aichat.NewHTTPClient(server.URL)
+	if _, err := unauthenticated.ChatBatch(ctx, []aichat.HTTPChatRequest{{Message: "Hi"}}); !errors.As(err, &httpErr) || httpErr.Code != aichat.CodeUnauthorized {
+		t.Errorf("ChatBatch(no token) error = %v, want an unauthorized HTTPError", err)
+	}
+}
@@ -0,0 +1,72 @@
+package aichat
+
+import (
+	"context"
+	"maps"
+)
+
+type contextKey string
+
+// Context keys set by the SDK. Expert handlers read them with the accessor functions below.
+const (
+	// requestIDKey holds the ID of the HTTP request, also returned in the X-Request-ID header.
+	requestIDKey contextKey = "request_id"
+
+	// conversationIDKey holds the ID of the conversation being processed.
+	conversationIDKey contextKey = "conversation_id"
+
+	// conversationMetadataKey holds the conversation's metadata.
+	conversationMetadataKey contextKey = "conversation_metadata"
+
+	// principalKey holds the principal authenticated by Config.Authenticator.
+	principalKey contextKey = "principal"
+)
+
+// RequestIDFromContext returns the ID of the HTTP request being served, or ""
+// when the chat was not started through the SDK's HTTP handler.
+func RequestIDFromContext(ctx context.Context) string {
+	if requestID, ok := ctx.Value(requestIDKey).(string); ok {
+		return requestID
+	}
+	return ""
+}
+
+// PrincipalFromContext returns the caller authenticated by Config.Authenticator,
+// or nil when authentication is disabled or the chat was not started over HTTP.
+func PrincipalFromContext(ctx context.Context) *Principal {
+	if principal, ok := ctx.Value(principalKey).(*Principal); ok {
+		return principal
+	}
+	return nil
+}
+
+// withPrincipal returns a context carrying the authenticated principal.
+func withPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey, principal)
+}
+
+// ConversationIDFromContext returns the ID of the conversation being processed, or "".
+func ConversationIDFromContext(ctx context.Context) string {
+	if conversationID, ok := ctx.Value(conversationIDKey).(string); ok {
+		return conversationID
+	}
+	return ""
+}
+
+// ConversationMetadataFromContext returns a copy of the metadata of the conversation
+// being processed, e.g. for tenant scoping in expert handlers, or nil.
+func ConversationMetadataFromContext(ctx context.Context) map[string]string {
+	if metadata, ok := ctx.Value(conversationMetadataKey).(map[string]string); ok {
+		return maps.Clone(metadata)
+	}
+	return nil
+}
+
+// withConversation returns a context carrying the conversation's ID and metadata.
+func withConversation(ctx context.Context, conversation *Conversation) context.Context {
+	ctx = context.WithValue(ctx, conversationIDKey, conversation.ID)
+	if conversation.Metadata != nil {
+		ctx = context.WithValue(ctx, conversationMetadataKey, maps.Clone(conversation.Metadata))
+	}
+	return ctx
+}
@@ -0,0 +1,173 @@
+package aichat
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// modelContextWindows maps model names, or prefixes of dated model names, to their
+// context window in tokens.
+var modelContextWindows = map[string]int{
+	"gpt-3.5-turbo":         16_385,
+	"gpt-4":                 8_192,
+	"gpt-4-turbo":           128_000,
+	"gpt-4o":                128_000,
+	"gpt-4o-mini":           128_000,
+	"gpt-4.1":               1_047_576,
+	"gpt-4.1-mini":          1_047_576,
+	"gpt-4.1-nano":          1_047_576,
+	"gpt-5":                 400_000,
+	"gpt-5-mini":            400_000,
+	"gpt-5-nano":            400_000,
+	"o1":                    200_000,
+	"o3":                    200_000,
+	"o3-mini":               200_000,
+	"o4-mini":               200_000,
+	"claude-3-5-haiku":      200_000,
+	"claude-3-5-sonnet":     200_000,
+	"claude-3-7-sonnet":     200_000,
+	"claude-haiku-4":        200_000,
+	"claude-sonnet-4":       200_000,
+	"claude-opus-4":         200_000,
+	"gemini-2.0-flash":      1_048_576,
+	"gemini-2.0-flash-lite": 1_048_576,
+	"gemini-2.5-flash":      1_048_576,
+	"gemini-2.5-flash-lite": 1_048_576,
+	"gemini-2.5-pro":        1_048_576,
+}
+
+// ModelContextWindow returns the context window in tokens of a known model. Dated
+// and provider-prefixed names such as "gpt-4o-2024-08-06", "openai/gpt-4o" or
+// "anthropic.claude-sonnet-4-5-20250929-v1:0" match their base model.
+func ModelContextWindow(model string) (int, bool) {
+	return lookupModel(modelContextWindows, model)
+}
+
+// lookupModel returns the entry of table for model, matching dated and
+// provider-prefixed names to their base model like ModelContextWindow.
+func lookupModel[V any](table map[string]V, model string) (V, bool) {
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		model = model[i+1:]
+	}
+
+	// Bedrock IDs such as "us.anthropic.claude-sonnet-4-5-20250929-v1:0" prefix
+	// the model name with a region and provider
+	name, _, _ := strings.Cut(model, "-")
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		model = model[i+1:]
+	}
+
+	// The longest matching name wins, so "gpt-4o-mini-..." is not taken for "gpt-4"
+	var value V
+	var matched int
+	for name, v := range table {
+		if len(name) > matched && (model == name || strings.HasPrefix(model, name+"-")) {
+			value, matched = v, len(name)
+		}
+	}
+	return value, matched > 0
+}
+
+// ContextLengthError is returned when a prompt does not fit the model's context window.
+// It matches ErrContextLengthExceeded with errors.Is.
+type ContextLengthError struct {
+	// Model is the model that rejected the prompt.
+	Model string
+
+	// PromptTokens is the size of the prompt as reported by the provider, or 0 if unknown.
+	PromptTokens int
+
+	// Limit is the model's context window, or 0 if unknown.
+	Limit int
+
+	// Err is the provider's error.
+	Err error
+}
+
+func (e *ContextLengthError) Error() string {
+	if e.PromptTokens > 0 && e.Limit > 0 {
+		return fmt.Sprintf("prompt of %d tokens exceeds the %d token context window of %s: %v", e.PromptTokens, e.Limit, e.Model, e.Err)
+	}
+	return fmt.Sprintf("prompt exceeds the context window of %s: %v", e.Model, e.Err)
+}
+
+func (e *ContextLengthError) Unwrap() []error {
+	return []error{ErrContextLengthExceeded, e.Err}
+}
+
+var (
+	// "This model's maximum context length is 128000 tokens. However, your messages resulted in 130512 tokens."
+	openAIContextLengthPattern = regexp.MustCompile(`maximum context length is (\d+) tokens.*?resulted in (\d+) tokens`)
+
+	// "prompt is too long: 210000 tokens > 200000 maximum"
+	anthropicContextLengthPattern = regexp.MustCompile(`prompt is too long: (\d+) tokens > (\d+) maximum`)
+
+	// "The input token count (1200000) exceeds the maximum number of tokens allowed (1048576)."
+	geminiContextLengthPattern = regexp.MustCompile(`input token count \((\d+)\) exceeds the maximum number of tokens allowed \((\d+)\)`)
+)
+
+// isBedrockContextLengthMessage reports whether a Bedrock validation error rejects the
+// prompt as too long: "Input is too long for requested model." or, for Claude
+// models, Anthropic's "prompt is too long" message.
+func isBedrockContextLengthMessage(message string) bool {
+	return strings.Contains(message, "Input is too long") || strings.Contains(message, "prompt is too long")
+}
+
+// asContextLengthError returns err as a *ContextLengthError if the provider rejected
+// the prompt as too long for model, and err unchanged otherwise.
+func asContextLengthError(err error, model string) error {
+	if err == nil {
+		return nil
+	}
+
+	var limit, promptTokens int
+	var openAIErr *openai.APIError
+	var anthropicErr *AnthropicError
+	var bedrockErr *BedrockError
+	var geminiErr *GeminiError
+	switch {
+	case errors.As(err, &openAIErr):
+		if code, ok := openAIErr.Code.(string); !ok || code != "context_length_exceeded" {
+			return err
+		}
+		if m := openAIContextLengthPattern.FindStringSubmatch(openAIErr.Message); m != nil {
+			limit, _ = strconv.Atoi(m[1])
+			promptTokens, _ = strconv.Atoi(m[2])
+		}
+	case errors.As(err, &anthropicErr):
+		if anthropicErr.Type != "invalid_request_error" || !strings.Contains(anthropicErr.Message, "prompt is too long") {
+			return err
+		}
+		if m := anthropicContextLengthPattern.FindStringSubmatch(anthropicErr.Message); m != nil {
+			promptTokens, _ = strconv.Atoi(m[1])
+			limit, _ = strconv.Atoi(m[2])
+		}
+	case errors.As(err, &bedrockErr):
+		if !strings.EqualFold(bedrockErr.Type, "ValidationException") || !isBedrockContextLengthMessage(bedrockErr.Message) {
+			return err
+		}
+		if m := anthropicContextLengthPattern.FindStringSubmatch(bedrockErr.Message); m != nil {
+			promptTokens, _ = strconv.Atoi(m[1])
+			limit, _ = strconv.Atoi(m[2])
+		}
+	case errors.As(err, &geminiErr):
+		m := geminiContextLengthPattern.FindStringSubmatch(geminiErr.Message)
+		if geminiErr.Status != "INVALID_ARGUMENT" || m == nil {
+			return err
+		}
+		promptTokens, _ = strconv.Atoi(m[1])
+		limit, _ = strconv.Atoi(m[2])
+	default:
+		return err
+	}
+
+	if limit == 0 {
+		limit, _ = ModelContextWindow(model)
+	}
+	return &ContextLengthError{Model: model, PromptTokens: promptTokens, Limit: limit, Err: err}
+}
@@ -0,0 +1,108 @@
+package aichat_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	aichat "github.com/ourstudio-se/ai-chat-sdk"
+)
+
+func TestExpertsReadRequestScopedValuesFromContext(t *testing.T) {
+	type scope struct {
+		requestID      string
+		conversationID string
+		metadata       map[string]string
+	}
+	var scopes []scope
+
+	sdk, err := aichat.New(aichat.Config{
+		// Every question is routed to the product expert
+		LLMClient: aichat.LLMClient{
+			ChatJSON: func(ctx context.Context, systemPrompt, userMessage string, opts *aichat.ChatJSONOptions, result any) error {
+				return json.Unmarshal([]byte(`{"expert": "product", "reasoning": "Asks about a product"}`), result)
+			},
+			Chat: func(ctx context.Context, systemPrompt, userMessage string, opts *aichat.ChatOptions) (string, error) {
+				return "", errors.New("unexpected Chat call")
+			},
+			ChatStream: func(ctx context.Context, systemPrompt, userMessage string, opts *aichat.ChatOptions, onToken func(string)) (string, error) {
+				return "", errors.New("unexpected ChatStream call")
+			},
+		},
+		LanguageDetector: func(text string) aichat.LanguageDetection {
+			return aichat.LanguageDetection{Language: "en", Confidence: 1}
+		},
+		DevMode: true,
+		Logger:  slog.New(slog.DiscardHandler),
+		Experts: map[aichat.ExpertType]aichat.Expert{
+			"product": {
+				Name:        "Product Expert",
+				Description: "Questions about products",
+				Handler: func(ctx context.Context, req aichat.ExpertRequest) (*aichat.ExpertResult, error) {
+					scopes = append(scopes, scope{
+						requestID:      aichat.RequestIDFromContext(ctx),
+						conversationID: aichat.ConversationIDFromContext(ctx),
+						metadata:       aichat.ConversationMetadataFromContext(ctx),
+					})
+					// Each call returns a copy, so handlers can't change the conversation's metadata
+					if metadata := aichat.ConversationMetadataFromContext(ctx); metadata != nil {
+						metadata["market"] = "changed"
+					}
+					return &aichat.ExpertResult{Answer: "It has three speeds."}, nil
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/chat", strings.NewReader(`{"message": "How many speeds?", "metadata": {"market": "se"}}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	sdk.HTTPHandler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("POST /chat status = %d, body %s", rec.Code, rec.Body.String())
+	}
+	var resp aichat.HTTPChatResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	// A follow-up outside the HTTP handler has no request ID
+	if _, err := sdk.ProcessChat()(context.Background(), aichat.ChatRequest{Message: "And the weight?", ConversationID: resp.ConversationID}); err != nil {
+		t.Fatalf("ProcessChat() error = %v", err)
+	}
+
+	if len(scopes) != 2 {
+		t.Fatalf("expert ran %d times, want 2", len(scopes))
+	}
+	overHTTP, inProcess := scopes[0], scopes[1]
+
+	if want := rec.Header().Get("X-Request-ID"); overHTTP.requestID == "" || overHTTP.requestID != want {
+		t.Errorf("RequestIDFromContext() = %q, want the X-Request-ID header %q", overHTTP.requestID, want)
+	}
+	if inProcess.requestID != "" {
+		t.Errorf("RequestIDFromContext() = %q outside HTTP, want \"\"", inProcess.requestID)
+	}
+	for i, s := range scopes {
+		if s.conversationID != resp.ConversationID {
+			t.Errorf("turn %d: ConversationIDFromContext() = %q, want %q", i+1, s.conversationID, resp.ConversationID)
+		}
+		if s.metadata["market"] != "se" {
+			t.Errorf("turn %d: ConversationMetadataFromContext() = %v, want market se", i+1, s.metadata)
+		}
+	}
+
+	if got := aichat.ConversationIDFromContext(context.Background()); got != "" {
+		t.Errorf("ConversationIDFromContext(empty) = %q, want \"\"", got)
+	}
+	if got := aichat.ConversationMetadataFromContext(context.Background()); got != nil {
+		t.Errorf("ConversationMetadataFromContext(empty) = %v, want nil", got)
+	}
+}
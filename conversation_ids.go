@@ -0,0 +1,154 @@
+package aichat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// maxClientConversationIDLength is the longest conversation ID a client may supply.
+const maxClientConversationIDLength = 128
+
+// ensureConversation creates the conversation requested by a client-supplied ID if it
+// does not exist yet, so that the rest of the pipeline finds it.
+func ensureConversation(ctx context.Context, store ConversationStore, req ChatRequest) error {
+	if req.ConversationID == "" {
+		return nil
+	}
+
+	_, err := store.Get(ctx, req.ConversationID)
+	if err == nil || !errors.Is(err, ErrConversationNotFound) {
+		// Other lookup failures are left for the chat function to report
+		return nil
+	}
+
+	if err := validateStoreID(req.ConversationID); err != nil {
+		return fmt.Errorf("invalid conversation ID: %w", err)
+	}
+	if len(req.ConversationID) > maxClientConversationIDLength {
+		return fmt.Errorf("%w: conversation ID exceeds %d characters", ErrInvalidInput, maxClientConversationIDLength)
+	}
+
+	if err := store.Save(ctx, newConversationRecord(req.ConversationID, req)); err != nil {
+		return fmt.Errorf("failed to create conversation: %w", err)
+	}
+	return nil
+}
+
+// newConversationRecord returns a new, empty conversation for req under id.
+func newConversationRecord(id string, req ChatRequest) *Conversation {
+	now := time.Now()
+	return &Conversation{
+		ID:        id,
+		CreatedAt: now,
+		UpdatedAt: now,
+		EntityID:  req.EntityID,
+		Messages:  []Message{},
+		Metadata:  req.Metadata,
+		Tags:      req.Tags,
+	}
+}
+
+// withClientConversationIDs wraps a chat function to create conversations under
+// client-supplied IDs on first use instead of failing with ErrConversationNotFound.
+// It must run under the conversation lock, so concurrent first turns create it once.
+func withClientConversationIDs(processChat ProcessChatFn, store ConversationStore) ProcessChatFn {
+	return func(ctx context.Context, req ChatRequest) (*ChatResult, error) {
+		if err := ensureConversation(ctx, store, req); err != nil {
+			return nil, err
+		}
+		return processChat(ctx, req)
+	}
+}
+
+// withClientConversationIDsStreaming wraps a streaming chat function to create
+// conversations under client-supplied IDs on first use.
+func withClientConversationIDsStreaming(processChatStream ProcessChatStreamFn, store ConversationStore) ProcessChatStreamFn {
+	return func(ctx context.Context, req ChatRequest, stream StreamCallback) (*ChatResult, error) {
+		if err := ensureConversation(ctx, store, req); err != nil {
+			return nil, err
+		}
+		return processChatStream(ctx, req, stream)
+	}
+}
+
+// IDGenerator generates the IDs of new conversations and messages, e.g. sortable
+// ULIDs or prefixed IDs (see the idgen subpackage). Either function may be nil to
+// keep the default: conversation IDs chosen by the store's Create and random UUIDs
+// for messages. IDs may only contain letters, digits, '-' and '_'.
+type IDGenerator struct {
+	// NewConversationID returns the ID of a new conversation. When set, new
+	// conversations are created with ConversationStore.Save instead of Create, and
+	// an ID that is already in use fails with ErrConversationExists.
+	NewConversationID func() string
+
+	// NewMessageID returns the ID of a new message.
+	NewMessageID func() string
+}
+
+// idGeneratorKey is the context key for the configured ID generator.
+type idGeneratorKey struct{}
+
+// withIDGenerator returns a context in which new conversations and messages get IDs from gen.
+func withIDGenerator(ctx context.Context, gen IDGenerator) context.Context {
+	return context.WithValue(ctx, idGeneratorKey{}, gen)
+}
+
+// newMessageID returns an ID for a new message.
+func newMessageID(ctx context.Context) (string, error) {
+	gen, _ := ctx.Value(idGeneratorKey{}).(IDGenerator)
+	if gen.NewMessageID == nil {
+		return uuid.New().String(), nil
+	}
+
+	id := gen.NewMessageID()
+	if err := validateStoreID(id); err != nil {
+		return "", fmt.Errorf("generated message ID %q: %w", id, err)
+	}
+	return id, nil
+}
+
+// createConversation creates a new conversation for req, under a generated ID if
+// an IDGenerator is configured.
+func createConversation(ctx context.Context, store ConversationStore, req ChatRequest) (*Conversation, error) {
+	gen, _ := ctx.Value(idGeneratorKey{}).(IDGenerator)
+	if gen.NewConversationID == nil {
+		return store.Create(ctx, req.EntityID)
+	}
+
+	id := gen.NewConversationID()
+	if err := validateStoreID(id); err != nil {
+		return nil, fmt.Errorf("generated conversation ID %q: %w", id, err)
+	}
+
+	// Save overwrites, so a colliding generator would replace another conversation
+	if _, err := store.Get(ctx, id); err == nil {
+		return nil, fmt.Errorf("generated conversation ID %q: %w", id, ErrConversationExists)
+	} else if !errors.Is(err, ErrConversationNotFound) {
+		return nil, fmt.Errorf("failed to check generated conversation ID: %w", err)
+	}
+
+	conversation := newConversationRecord(id, ChatRequest{EntityID: req.EntityID})
+	if err := store.Save(ctx, conversation); err != nil {
+		return nil, err
+	}
+	return conversation, nil
+}
+
+// withIDGenerators wraps a chat function so that new conversations and messages get IDs from gen.
+func withIDGenerators(processChat ProcessChatFn, gen IDGenerator) ProcessChatFn {
+	return func(ctx context.Context, req ChatRequest) (*ChatResult, error) {
+		return processChat(withIDGenerator(ctx, gen), req)
+	}
+}
+
+// withIDGeneratorsStreaming wraps a streaming chat function so that new
+// conversations and messages get IDs from gen.
+func withIDGeneratorsStreaming(processChatStream ProcessChatStreamFn, gen IDGenerator) ProcessChatStreamFn {
+	return func(ctx context.Context, req ChatRequest, stream StreamCallback) (*ChatResult, error) {
+		return processChatStream(withIDGenerator(ctx, gen), req, stream)
+	}
+}
@@ -0,0 +1,44 @@
+package aichat_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	aichat "github.com/ourstudio-se/ai-chat-sdk"
+	"github.com/ourstudio-se/ai-chat-sdk/aichattest"
+)
+
+func TestGeneratedConversationIDCollisionKeepsExistingConversation(t *testing.T) {
+	ctx := context.Background()
+	llm := aichattest.NewLLM(turn("The Widget Pro has three speeds.")...)
+	sdk := newTestSDK(t, llm, aichat.Config{
+		IDGenerator: aichat.IDGenerator{
+			NewConversationID: func() string { return "conv_fixed" },
+		},
+	})
+
+	first := mustChat(t, sdk, aichat.ChatRequest{Message: "How many speeds?"})
+	if first.ConversationID != "conv_fixed" {
+		t.Fatalf("ConversationID = %q, want %q", first.ConversationID, "conv_fixed")
+	}
+
+	// The generator returns the same ID again
+	_, err := sdk.ProcessChat()(ctx, aichat.ChatRequest{Message: "Is it waterproof?"})
+	if !errors.Is(err, aichat.ErrConversationExists) {
+		t.Fatalf("ProcessChat() error = %v, want ErrConversationExists", err)
+	}
+
+	conversations, err := sdk.ListConversations(ctx, aichat.ConversationFilter{})
+	if err != nil {
+		t.Fatalf("ListConversations() error = %v", err)
+	}
+	if len(conversations) != 1 {
+		t.Fatalf("ListConversations() = %d conversations, want 1", len(conversations))
+	}
+	messages := conversations[0].Messages
+	if len(messages) != 2 || messages[0].Content != "How many speeds?" {
+		t.Errorf("stored messages = %+v, want the first turn", messages)
+	}
+	llm.AssertDone(t)
+}
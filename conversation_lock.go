@@ -0,0 +1,104 @@
+package aichat
+
+import (
+	"context"
+	"sync"
+)
+
+// LockConversationFn acquires an exclusive lock on a conversation so that its turns
+// run one at a time. When wait is false and the conversation is locked, it returns
+// ErrConversationBusy instead of waiting. The returned function releases the lock.
+type LockConversationFn func(ctx context.Context, conversationID string, wait bool) (unlock func(), err error)
+
+// NewMemoryConversationLocker creates an in-process conversation lock. It only
+// serializes turns handled by this process; replicas sharing a store need a
+// distributed lock, e.g. a Redis lock or a Postgres advisory lock.
+func NewMemoryConversationLocker() LockConversationFn {
+	type conversationLock struct {
+		held    chan struct{}
+		waiters int
+	}
+
+	var mu sync.Mutex
+	locks := make(map[string]*conversationLock)
+
+	// release drops a reference to the conversation's lock, removing it when unused
+	release := func(conversationID string, lock *conversationLock) {
+		mu.Lock()
+		defer mu.Unlock()
+		lock.waiters--
+		if lock.waiters == 0 {
+			delete(locks, conversationID)
+		}
+	}
+
+	return func(ctx context.Context, conversationID string, wait bool) (func(), error) {
+		mu.Lock()
+		lock, ok := locks[conversationID]
+		if !ok {
+			lock = &conversationLock{held: make(chan struct{}, 1)}
+			locks[conversationID] = lock
+		}
+		lock.waiters++
+		mu.Unlock()
+
+		select {
+		case lock.held <- struct{}{}:
+		default:
+			if !wait {
+				release(conversationID, lock)
+				return nil, ErrConversationBusy
+			}
+			select {
+			case lock.held <- struct{}{}:
+			case <-ctx.Done():
+				release(conversationID, lock)
+				return nil, ctx.Err()
+			}
+		}
+
+		var once sync.Once
+		return func() {
+			once.Do(func() {
+				<-lock.held
+				release(conversationID, lock)
+			})
+		}, nil
+	}
+}
+
+// withConversationLock wraps a chat function so that turns on an existing
+// conversation run one at a time. New conversations need no lock.
+func withConversationLock(processChat ProcessChatFn, lock LockConversationFn, wait bool) ProcessChatFn {
+	return func(ctx context.Context, req ChatRequest) (*ChatResult, error) {
+		if req.ConversationID == "" {
+			return processChat(ctx, req)
+		}
+
+		unlock, err := lock(ctx, req.ConversationID, wait)
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+
+		return processChat(ctx, req)
+	}
+}
+
+// withConversationLockStreaming wraps a streaming chat function so that turns on an
+// existing conversation run one at a time. New conversations need no lock.
+func withConversationLockStreaming(processChatStream ProcessChatStreamFn, lock LockConversationFn, wait bool) ProcessChatStreamFn {
+	return func(ctx context.Context, req ChatRequest, stream StreamCallback) (*ChatResult, error) {
+		if req.ConversationID == "" {
+			return processChatStream(ctx, req, stream)
+		}
+
+		unlock, err := lock(ctx, req.ConversationID, wait)
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+
+		return processChatStream(ctx, req, stream)
+	}
+}
@@ -0,0 +1,219 @@
+package aichat_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	aichat "github.com/ourstudio-se/ai-chat-sdk"
+)
+
+func TestMemoryConversationLocker(t *testing.T) {
+	ctx := context.Background()
+	lock := aichat.NewMemoryConversationLocker()
+
+	unlock, err := lock(ctx, "conv-1", true)
+	if err != nil {
+		t.Fatalf("lock() error = %v", err)
+	}
+
+	if _, err := lock(ctx, "conv-1", false); !errors.Is(err, aichat.ErrConversationBusy) {
+		t.Errorf("lock(held, wait=false) error = %v, want ErrConversationBusy", err)
+	}
+
+	// Other conversations are not blocked
+	unlockOther, err := lock(ctx, "conv-2", false)
+	if err != nil {
+		t.Fatalf("lock(other) error = %v", err)
+	}
+	unlockOther()
+
+	// A waiter gives up when its context ends
+	waitCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := lock(waitCtx, "conv-1", true); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("lock(held, cancelled) error = %v, want context.DeadlineExceeded", err)
+	}
+
+	// A waiter gets the lock once it is released, and a second unlock is a no-op
+	acquired := make(chan func())
+	go func() {
+		next, err := lock(ctx, "conv-1", true)
+		if err != nil {
+			t.Errorf("lock(waiting) error = %v", err)
+		}
+		acquired <- next
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("waiter acquired a held lock")
+	case <-time.After(20 * time.Millisecond):
+	}
+	unlock()
+	unlock()
+	next := <-acquired
+
+	if _, err := lock(ctx, "conv-1", false); !errors.Is(err, aichat.ErrConversationBusy) {
+		t.Errorf("lock() after a double unlock error = %v, want ErrConversationBusy", err)
+	}
+	next()
+	if _, err := lock(ctx, "conv-1", false); err != nil {
+		t.Errorf("lock(released) error = %v", err)
+	}
+}
+
+// newBlockingSDK creates an SDK with one expert that answers "Answer to <message>".
+// For messages starting with "Blocking:" the expert first sends a channel on the
+// returned channel and waits for it to be closed.
+func newBlockingSDK(t *testing.T, rejectConcurrentTurns bool) (*aichat.SDK, <-chan chan struct{}) {
+	t.Helper()
+
+	entered := make(chan chan struct{}, 2)
+	sdk, err := aichat.New(aichat.Config{
+		LLMClient: aichat.LLMClient{
+			ChatJSON: func(ctx context.Context, systemPrompt, userMessage string, opts *aichat.ChatJSONOptions, result any) error {
+				return json.Unmarshal([]byte(`{"expert": "product", "reasoning": "Asks about a product"}`), result)
+			},
+			Chat: func(ctx context.Context, systemPrompt, userMessage string, opts *aichat.ChatOptions) (string, error) {
+				return "", errors.New("unexpected Chat call")
+			},
+			ChatStream: func(ctx context.Context, systemPrompt, userMessage string, opts *aichat.ChatOptions, onToken func(string)) (string, error) {
+				return "", errors.New("unexpected ChatStream call")
+			},
+		},
+		LanguageDetector: func(text string) aichat.LanguageDetection {
+			return aichat.LanguageDetection{Language: "en", Confidence: 1}
+		},
+		RejectConcurrentTurns: rejectConcurrentTurns,
+		DevMode:               true,
+		Logger:                slog.New(slog.DiscardHandler),
+		Experts: map[aichat.ExpertType]aichat.Expert{
+			"product": {
+				Name:        "Product Expert",
+				Description: "Questions about products",
+				Handler: func(ctx context.Context, req aichat.ExpertRequest) (*aichat.ExpertResult, error) {
+					if strings.HasPrefix(req.Message, "Blocking:") {
+						release := make(chan struct{})
+						entered <- release
+						<-release
+					}
+					return &aichat.ExpertResult{Answer: "Answer to " + req.Message}, nil
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return sdk, entered
+}
+
+func TestConcurrentTurnsOnOneConversationRunOneAtATime(t *testing.T) {
+	ctx := context.Background()
+	sdk, entered := newBlockingSDK(t, false)
+
+	first, err := sdk.ProcessChat()(ctx, aichat.ChatRequest{Message: "How many speeds?"})
+	if err != nil {
+		t.Fatalf("ProcessChat() error = %v", err)
+	}
+	id := first.ConversationID
+
+	errs := make(chan error, 2)
+	for _, message := range []string{"Blocking: and the weight?", "Blocking: and the price?"} {
+		go func() {
+			_, err := sdk.ProcessChat()(ctx, aichat.ChatRequest{Message: message, ConversationID: id})
+			errs <- err
+		}()
+	}
+
+	// One turn reaches the expert; the other waits for the lock until it is done
+	release := <-entered
+	select {
+	case <-entered:
+		t.Fatal("both turns reached the expert at once")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	close(<-entered)
+
+	for range 2 {
+		if err := <-errs; err != nil {
+			t.Errorf("ProcessChat() error = %v", err)
+		}
+	}
+
+	data, err := sdk.ExportConversation(ctx, id, aichat.ExportFormatOpenAI)
+	if err != nil {
+		t.Fatalf("ExportConversation() error = %v", err)
+	}
+	var messages []aichat.ExportedMessage
+	if err := json.Unmarshal(data, &messages); err != nil {
+		t.Fatalf("failed to decode export: %v", err)
+	}
+
+	// The turns were not interleaved: every question is followed by its answer
+	if len(messages) != 6 {
+		t.Fatalf("conversation has %d messages, want 6", len(messages))
+	}
+	for i := 0; i < len(messages); i += 2 {
+		question, answer := messages[i], messages[i+1]
+		if question.Role != string(aichat.RoleUser) || answer.Content != "Answer to "+question.Content {
+			t.Errorf("messages %d and %d = %q (%s), %q (%s); want a question and its answer",
+				i, i+1, question.Content, question.Role, answer.Content, answer.Role)
+		}
+	}
+}
+
+func TestRejectConcurrentTurnsRespondsConflict(t *testing.T) {
+	ctx := context.Background()
+	sdk, entered := newBlockingSDK(t, true)
+
+	first, err := sdk.ProcessChat()(ctx, aichat.ChatRequest{Message: "How many speeds?"})
+	if err != nil {
+		t.Fatalf("ProcessChat() error = %v", err)
+	}
+	id := first.ConversationID
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := sdk.ProcessChat()(ctx, aichat.ChatRequest{Message: "Blocking: and the weight?", ConversationID: id})
+		done <- err
+	}()
+	release := <-entered
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/chat", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		sdk.HTTPHandler().ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := post(`{"message": "And the price?", "conversationId": "` + id + `"}`)
+	var resp aichat.ErrorResponse
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if rec.Code != http.StatusConflict || resp.Code != aichat.CodeConversationBusy {
+		t.Errorf("concurrent POST /chat = %d %+v, want 409 with code %s", rec.Code, resp, aichat.CodeConversationBusy)
+	}
+
+	// New conversations take no lock
+	if rec := post(`{"message": "What colors are there?"}`); rec.Code != http.StatusOK {
+		t.Errorf("POST /chat for a new conversation = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Errorf("first ProcessChat() error = %v", err)
+	}
+
+	// Once the turn has finished the conversation accepts messages again
+	if rec := post(`{"message": "And the price?", "conversationId": "` + id + `"}`); rec.Code != http.StatusOK {
+		t.Errorf("POST /chat after the turn = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+}
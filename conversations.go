@@ -0,0 +1,52 @@
+package aichat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"time"
+)
+
+// NewConversationUpdater creates a function that updates conversation metadata and tags.
+// lock serializes updates with chat turns, so neither overwrites the other's changes
+// (see NewMemoryConversationLocker).
+func NewConversationUpdater(store ConversationStore, lock LockConversationFn) UpdateConversationFn {
+	return func(ctx context.Context, id string, update ConversationUpdate) (*Conversation, error) {
+		unlock, err := lock(ctx, id, true)
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+
+		conversation, err := store.Get(ctx, id)
+		if err != nil {
+			if errors.Is(err, ErrConversationNotFound) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("failed to get conversation: %w", err)
+		}
+
+		if len(update.Metadata) > 0 {
+			if conversation.Metadata == nil {
+				conversation.Metadata = make(map[string]string, len(update.Metadata))
+			}
+			maps.Copy(conversation.Metadata, update.Metadata)
+			maps.DeleteFunc(conversation.Metadata, func(_, value string) bool {
+				return value == ""
+			})
+		}
+
+		if update.Tags != nil {
+			conversation.Tags = update.Tags
+		}
+
+		conversation.UpdatedAt = time.Now()
+
+		if err := store.Save(ctx, conversation); err != nil {
+			return nil, fmt.Errorf("failed to save conversation: %w", err)
+		}
+
+		return conversation, nil
+	}
+}
@@ -0,0 +1,71 @@
+package aichat_test
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	aichat "github.com/ourstudio-se/ai-chat-sdk"
+	"github.com/ourstudio-se/ai-chat-sdk/aichattest"
+)
+
+func TestCORSCredentials(t *testing.T) {
+	on, off := true, false
+	tests := []struct {
+		name             string
+		origins          []string
+		allowCredentials *bool
+		wantErr          bool
+		wantCredentials  bool
+	}{
+		{name: "listed origin by default", origins: []string{"https://myapp.com"}, wantCredentials: true},
+		{name: "listed origin with credentials off", origins: []string{"https://myapp.com"}, allowCredentials: &off},
+		{name: "wildcard by default", origins: []string{"*"}},
+		{name: "wildcard with credentials off", origins: []string{"*"}, allowCredentials: &off},
+		{name: "wildcard with credentials on", origins: []string{"*"}, allowCredentials: &on, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sdk, err := aichat.New(aichat.Config{
+				LLMClient: aichattest.NewLLM().Client(),
+				Experts: map[aichat.ExpertType]aichat.Expert{
+					"product": {
+						Name:        "Product Expert",
+						Description: "Questions about products",
+						Handler: func(ctx context.Context, req aichat.ExpertRequest) (*aichat.ExpertResult, error) {
+							return &aichat.ExpertResult{Answer: "Yes."}, nil
+						},
+					},
+				},
+				AllowedOrigins:       tt.origins,
+				CORSAllowCredentials: tt.allowCredentials,
+				Logger:               slog.New(slog.DiscardHandler),
+			})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("New() succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			req := httptest.NewRequest(http.MethodOptions, "/chat", nil)
+			req.Header.Set("Origin", "https://myapp.com")
+			req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			rec := httptest.NewRecorder()
+			sdk.HTTPHandler().ServeHTTP(rec, req)
+
+			if rec.Header().Get("Access-Control-Allow-Origin") == "" {
+				t.Fatalf("preflight was not allowed: headers %v", rec.Header())
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Credentials") == "true"; got != tt.wantCredentials {
+				t.Errorf("credentials allowed = %v, want %v", got, tt.wantCredentials)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package aichat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// ValidateDataFn checks the structured data passed to an expert with a chat request.
+type ValidateDataFn func(data any) error
+
+// DecodeData converts the structured data of a chat request, as decoded from JSON,
+// into T. Unknown fields are rejected, so it doubles as a shape check in
+// Expert.ValidateData:
+//
+//	ValidateData: func(data any) error {
+//	    _, err := aichat.DecodeData[OrderData](data)
+//	    return err
+//	},
+func DecodeData[T any](data any) (T, error) {
+	var value T
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return value, fmt.Errorf("failed to encode data: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&value); err != nil {
+		return value, fmt.Errorf("failed to decode data: %w", err)
+	}
+	return value, nil
+}
+
+// withDataValidation returns a copy of experts where every expert with ValidateData
+// rejects requests whose data fails it with ErrInvalidInput, before any other work.
+func withDataValidation(experts map[ExpertType]Expert) map[ExpertType]Expert {
+	wrapped := make(map[ExpertType]Expert, len(experts))
+	for expertType, expert := range experts {
+		if expert.ValidateData != nil {
+			expert = dataValidatingExpert(expertType, expert)
+		}
+		wrapped[expertType] = expert
+	}
+	return wrapped
+}
+
+func dataValidatingExpert(expertType ExpertType, expert Expert) Expert {
+	validate := func(req ExpertRequest) error {
+		if err := expert.ValidateData(req.Data); err != nil {
+			return fmt.Errorf("%w: invalid data for expert %s: %v", ErrInvalidInput, expertType, err)
+		}
+		return nil
+	}
+
+	handler := expert.Handler
+	expert.Handler = func(ctx context.Context, req ExpertRequest) (*ExpertResult, error) {
+		if err := validate(req); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+
+	if streamHandler := expert.StreamHandler; streamHandler != nil {
+		expert.StreamHandler = func(ctx context.Context, req ExpertRequest, stream StreamCallback) (*ExpertResult, error) {
+			if err := validate(req); err != nil {
+				return nil, err
+			}
+			return streamHandler(ctx, req, stream)
+		}
+	}
+
+	return expert
+}
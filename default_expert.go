@@ -0,0 +1,62 @@
+package aichat
+
+import (
+	"context"
+	"log/slog"
+)
+
+// DefaultExpertResolverFn picks the default expert for a request, e.g. by market or
+// locale. It runs only when a default is needed, with the conversation available
+// through ConversationIDFromContext and ConversationMetadataFromContext.
+// Returning "" falls back to Config.DefaultExpert.
+type DefaultExpertResolverFn func(ctx context.Context, req ChatRequest) ExpertType
+
+// defaultExpertKey is the context key for the request's default expert resolver.
+type defaultExpertKey struct{}
+
+// resolveDefaultExpert returns the default expert chosen for the request in ctx, or
+// fallback when no resolver is set or it chooses none.
+func resolveDefaultExpert(ctx context.Context, fallback ExpertType) ExpertType {
+	resolve, ok := ctx.Value(defaultExpertKey{}).(func(ctx context.Context) ExpertType)
+	if !ok {
+		return fallback
+	}
+	if expert := resolve(ctx); expert != "" {
+		return expert
+	}
+	return fallback
+}
+
+// withDefaultExpertResolver returns a context in which the default expert is chosen
+// by resolve for req. Unknown experts are ignored.
+func withDefaultExpertResolver(ctx context.Context, req ChatRequest, resolve DefaultExpertResolverFn, experts map[ExpertType]Expert, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, defaultExpertKey{}, func(ctx context.Context) ExpertType {
+		expert := resolve(ctx, req)
+		if expert == "" {
+			return ""
+		}
+		if _, ok := experts[expert]; !ok {
+			logger.Warn("default expert resolver returned an unknown expert, using DefaultExpert",
+				slog.String("expert_type", string(expert)),
+			)
+			return ""
+		}
+		return expert
+	})
+}
+
+// withDefaultExpertResolvers wraps a chat function to route unmatched questions to
+// the default expert chosen for each request.
+func withDefaultExpertResolvers(processChat ProcessChatFn, resolve DefaultExpertResolverFn, experts map[ExpertType]Expert, logger *slog.Logger) ProcessChatFn {
+	return func(ctx context.Context, req ChatRequest) (*ChatResult, error) {
+		return processChat(withDefaultExpertResolver(ctx, req, resolve, experts, logger), req)
+	}
+}
+
+// withDefaultExpertResolversStreaming wraps a streaming chat function to route
+// unmatched questions to the default expert chosen for each request.
+func withDefaultExpertResolversStreaming(processChatStream ProcessChatStreamFn, resolve DefaultExpertResolverFn, experts map[ExpertType]Expert, logger *slog.Logger) ProcessChatStreamFn {
+	return func(ctx context.Context, req ChatRequest, stream StreamCallback) (*ChatResult, error) {
+		return processChatStream(withDefaultExpertResolver(ctx, req, resolve, experts, logger), req, stream)
+	}
+}
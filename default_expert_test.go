@@ -0,0 +1,103 @@
+package aichat_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	aichat "github.com/ourstudio-se/ai-chat-sdk"
+	"github.com/ourstudio-se/ai-chat-sdk/aichattest"
+)
+
+func TestDefaultExpertResolver(t *testing.T) {
+	// answering returns an expert that answers with its own type
+	answering := func(expertType aichat.ExpertType) aichat.Expert {
+		return aichat.Expert{
+			Name:        string(expertType),
+			Description: "Support for " + string(expertType),
+			Handler: func(ctx context.Context, req aichat.ExpertRequest) (*aichat.ExpertResult, error) {
+				return &aichat.ExpertResult{Answer: string(expertType)}, nil
+			},
+		}
+	}
+	experts := map[aichat.ExpertType]aichat.Expert{
+		"support_se": answering("support_se"),
+		"support_de": answering("support_de"),
+		"general":    answering("general"),
+	}
+	byMarket := func(ctx context.Context, req aichat.ChatRequest) aichat.ExpertType {
+		switch market := aichat.ConversationMetadataFromContext(ctx)["market"]; market {
+		case "se", "de":
+			return aichat.ExpertType("support_" + market)
+		case "fr":
+			return "support_fr" // Not configured
+		}
+		return ""
+	}
+	unmatched := aichattest.Route("billing", "Asks about invoices")
+
+	tests := []struct {
+		name          string
+		resolver      aichat.DefaultExpertResolverFn
+		defaultExpert aichat.ExpertType
+		market        string
+		route         aichattest.Response
+		want          string
+		wantResolves  int
+	}{
+		{name: "unknown expert in se", resolver: byMarket, defaultExpert: "general", market: "se", route: unmatched, want: "support_se", wantResolves: 1},
+		{name: "unknown expert in de", resolver: byMarket, defaultExpert: "general", market: "de", route: unmatched, want: "support_de", wantResolves: 1},
+		{name: "routing error", resolver: byMarket, defaultExpert: "general", market: "de", route: aichattest.Error(errors.New("router unavailable")), want: "support_de", wantResolves: 1},
+		{name: "resolver returns nothing", resolver: byMarket, defaultExpert: "general", market: "us", route: unmatched, want: "general", wantResolves: 1},
+		{name: "resolver returns an unconfigured expert", resolver: byMarket, defaultExpert: "general", market: "fr", route: unmatched, want: "general", wantResolves: 1},
+		{name: "matched expert skips the resolver", resolver: byMarket, defaultExpert: "general", market: "se", route: aichattest.Route("support_de", "Asks in German"), want: "support_de"},
+		{name: "static default without resolver", defaultExpert: "general", market: "se", route: unmatched, want: "general"},
+		{name: "resolver without static default", resolver: byMarket, market: "de", route: unmatched, want: "support_de", wantResolves: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolves := 0
+			var resolver aichat.DefaultExpertResolverFn
+			if tt.resolver != nil {
+				resolver = func(ctx context.Context, req aichat.ChatRequest) aichat.ExpertType {
+					resolves++
+					return tt.resolver(ctx, req)
+				}
+			}
+
+			llm := aichattest.NewLLM(tt.route)
+			sdk, err := aichat.New(aichat.Config{
+				LLMClient:             llm.Client(),
+				Experts:               experts,
+				DefaultExpert:         tt.defaultExpert,
+				DefaultExpertResolver: resolver,
+				LanguageDetector: func(text string) aichat.LanguageDetection {
+					return aichat.LanguageDetection{Language: "en", Confidence: 1}
+				},
+				DevMode: true,
+				Logger:  slog.New(slog.DiscardHandler),
+			})
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			result, err := sdk.ProcessChat()(context.Background(), aichat.ChatRequest{
+				Message:  "Why was I charged twice?",
+				Metadata: map[string]string{"market": tt.market},
+			})
+			if err != nil {
+				t.Fatalf("ProcessChat() error = %v", err)
+			}
+
+			if result.ExpertResult.Answer != tt.want {
+				t.Errorf("answered by %q, want %q", result.ExpertResult.Answer, tt.want)
+			}
+			if resolves != tt.wantResolves {
+				t.Errorf("resolver ran %d times, want %d", resolves, tt.wantResolves)
+			}
+			llm.AssertDone(t)
+		})
+	}
+}
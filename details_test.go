@@ -0,0 +1,100 @@
+package aichat_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	aichat "github.com/ourstudio-se/ai-chat-sdk"
+)
+
+type productDetails struct {
+	ProductID string      `json:"productId"`
+	Product   productSpec `json:"product"`
+	Related   []string    `json:"related"`
+}
+
+type productSpec struct {
+	Name   string            `json:"name"`
+	Price  float64           `json:"price"`
+	Specs  map[string]string `json:"specs"`
+	Speeds []int             `json:"speeds"`
+}
+
+func TestDecodeDetails(t *testing.T) {
+	want := productDetails{
+		ProductID: "widget-pro",
+		Product: productSpec{
+			Name:   "Widget Pro",
+			Price:  249.5,
+			Specs:  map[string]string{"weight": "1.2 kg", "color": "black"},
+			Speeds: []int{1, 2, 3},
+		},
+		Related: []string{"widget-mini", "widget-max"},
+	}
+	result := &aichat.ChatResult{ExpertResult: &aichat.ExpertResult{Answer: "It has three speeds.", Details: want}}
+
+	t.Run("from ChatResult", func(t *testing.T) {
+		got, err := aichat.DecodeDetails[productDetails](result.Details())
+		if err != nil {
+			t.Fatalf("DecodeDetails() error = %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("DecodeDetails() = %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("from an HTTP response", func(t *testing.T) {
+		body, err := json.Marshal(aichat.HTTPChatResponse{Response: "It has three speeds.", Data: want})
+		if err != nil {
+			t.Fatalf("failed to marshal response: %v", err)
+		}
+		var resp struct {
+			Data json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(body, &resp); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+
+		got, err := aichat.DecodeDetails[productDetails](resp.Data)
+		if err != nil {
+			t.Fatalf("DecodeDetails() error = %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("DecodeDetails() = %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("missing details", func(t *testing.T) {
+		for _, raw := range []json.RawMessage{nil, json.RawMessage("null")} {
+			if _, err := aichat.DecodeDetails[productDetails](raw); err == nil {
+				t.Errorf("DecodeDetails(%q) error = nil, want an error", raw)
+			}
+		}
+		for _, result := range []*aichat.ChatResult{{}, {ExpertResult: &aichat.ExpertResult{}}} {
+			if raw := result.Details(); raw != nil {
+				t.Errorf("Details() = %s, want nil", raw)
+			}
+		}
+	})
+
+	t.Run("mismatched type", func(t *testing.T) {
+		if _, err := aichat.DecodeDetails[[]productDetails](result.Details()); err == nil {
+			t.Error("DecodeDetails() into a slice error = nil, want an error")
+		}
+	})
+
+	// GetDetails keeps working on the original value, before any JSON round trip
+	t.Run("GetDetails", func(t *testing.T) {
+		got, err := aichat.GetDetails[productDetails](result.ExpertResult)
+		if err != nil {
+			t.Fatalf("GetDetails() error = %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("GetDetails() = %+v, want %+v", got, want)
+		}
+		if _, err := aichat.GetDetails[*productDetails](result.ExpertResult); err == nil {
+			t.Error("GetDetails() with the wrong type error = nil, want an error")
+		}
+	})
+}
@@ -4,8 +4,25 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"maps"
 )
 
+// noMatchExpert is the expert that answers questions no configured expert matches.
+const noMatchExpert ExpertType = "_no_match"
+
+// withNoMatchExpert returns a copy of experts with a noMatchExpert answering response.
+// Pass noMatchExpert as the dispatcher's default expert; it is not offered to the router.
+func withNoMatchExpert(experts map[ExpertType]Expert, response string) map[ExpertType]Expert {
+	wrapped := maps.Clone(experts)
+	wrapped[noMatchExpert] = Expert{
+		Name: "No Match",
+		Handler: func(ctx context.Context, req ExpertRequest) (*ExpertResult, error) {
+			return &ExpertResult{Answer: response}, nil
+		},
+	}
+	return wrapped
+}
+
 // NewDispatcher creates a dispatcher function that routes and processes questions.
 func NewDispatcher(
 	routeQuestion RouteQuestionFn,
@@ -29,7 +46,7 @@ func NewDispatcher(
 		expert, exists := experts[routeResult.Expert]
 		if !exists {
 			// Try default expert
-			if defaultExpert != "" {
+			if defaultExpert := resolveDefaultExpert(ctx, defaultExpert); defaultExpert != "" {
 				expert, exists = experts[defaultExpert]
 			}
 
@@ -49,7 +66,7 @@ func NewDispatcher(
 
 		// 3. Process with expert
 		req.RoutingReasoning = routeResult.Reasoning
-		result, err := expert.Handler(ctx, req)
+		result, err := expert.Handler(withTraceStep(ctx, TraceExpert), req)
 		if err != nil {
 			return nil, fmt.Errorf("expert processing failed: %w", err)
 		}
@@ -93,7 +110,7 @@ func NewDispatcherStreaming(
 		// 2. Get expert implementation
 		expert, exists := experts[routeResult.Expert]
 		if !exists {
-			if defaultExpert != "" {
+			if defaultExpert := resolveDefaultExpert(ctx, defaultExpert); defaultExpert != "" {
 				expert, exists = experts[defaultExpert]
 			}
 
@@ -120,6 +137,8 @@ func NewDispatcherStreaming(
 		// 3. Process with expert (use streaming handler if available)
 		req.RoutingReasoning = routeResult.Reasoning
 
+		ctx = withTraceStep(ctx, TraceExpert)
+
 		var result *ExpertResult
 		if expert.StreamHandler != nil {
 			result, err = expert.StreamHandler(ctx, req, stream)
@@ -0,0 +1,57 @@
+package aichat
+
+import (
+	"context"
+	"fmt"
+)
+
+// EntityResolverFn loads the entity a conversation is about.
+type EntityResolverFn func(ctx context.Context, entityID string) (any, error)
+
+// withEntityResolver returns a copy of experts whose handlers receive the
+// resolved entity in ExpertRequest.Entity. The entity is resolved only after
+// routing, for the selected expert, and only when the request has an EntityID.
+func withEntityResolver(experts map[ExpertType]Expert, resolve EntityResolverFn) map[ExpertType]Expert {
+	wrapped := make(map[ExpertType]Expert, len(experts))
+	for expertType, expert := range experts {
+		wrapped[expertType] = entityResolvingExpert(expert, resolve)
+	}
+	return wrapped
+}
+
+func entityResolvingExpert(expert Expert, resolve EntityResolverFn) Expert {
+	withEntity := func(ctx context.Context, req ExpertRequest) (ExpertRequest, error) {
+		if req.EntityID == "" || req.Entity != nil {
+			return req, nil
+		}
+
+		entity, err := resolve(ctx, req.EntityID)
+		if err != nil {
+			return req, fmt.Errorf("failed to resolve entity %s: %w", req.EntityID, err)
+		}
+
+		req.Entity = entity
+		return req, nil
+	}
+
+	handler := expert.Handler
+	expert.Handler = func(ctx context.Context, req ExpertRequest) (*ExpertResult, error) {
+		req, err := withEntity(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+
+	if streamHandler := expert.StreamHandler; streamHandler != nil {
+		expert.StreamHandler = func(ctx context.Context, req ExpertRequest, stream StreamCallback) (*ExpertResult, error) {
+			req, err := withEntity(ctx, req)
+			if err != nil {
+				return nil, err
+			}
+			return streamHandler(ctx, req, stream)
+		}
+	}
+
+	return expert
+}
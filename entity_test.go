@@ -0,0 +1,107 @@
+package aichat
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestEntityResolver(t *testing.T) {
+	errUnavailable := errors.New("catalog unavailable")
+
+	var resolved []string
+	resolve := func(ctx context.Context, entityID string) (any, error) {
+		resolved = append(resolved, entityID)
+		if entityID == "broken" {
+			return nil, errUnavailable
+		}
+		return map[string]string{"id": entityID, "name": "Widget Pro"}, nil
+	}
+
+	var received []any
+	record := func(ctx context.Context, req ExpertRequest) (*ExpertResult, error) {
+		received = append(received, req.Entity)
+		return &ExpertResult{Answer: "ok"}, nil
+	}
+	experts := withEntityResolver(map[ExpertType]Expert{
+		"product": {
+			Handler: record,
+			StreamHandler: func(ctx context.Context, req ExpertRequest, stream StreamCallback) (*ExpertResult, error) {
+				return record(ctx, req)
+			},
+		},
+		"support": {Handler: record},
+	}, resolve)
+
+	tests := []struct {
+		name         string
+		call         func() (*ExpertResult, error)
+		wantEntity   any
+		wantErr      error
+		wantResolved []string
+	}{
+		{
+			name: "entity reaches the handler",
+			call: func() (*ExpertResult, error) {
+				return experts["product"].Handler(context.Background(), ExpertRequest{EntityID: "widget-pro"})
+			},
+			wantEntity:   map[string]string{"id": "widget-pro", "name": "Widget Pro"},
+			wantResolved: []string{"widget-pro"},
+		},
+		{
+			name: "entity reaches the stream handler",
+			call: func() (*ExpertResult, error) {
+				return experts["product"].StreamHandler(context.Background(), ExpertRequest{EntityID: "widget-pro"}, func(StreamEvent) {})
+			},
+			wantEntity:   map[string]string{"id": "widget-pro", "name": "Widget Pro"},
+			wantResolved: []string{"widget-pro"},
+		},
+		{
+			name: "no entity ID skips the resolver",
+			call: func() (*ExpertResult, error) {
+				return experts["support"].Handler(context.Background(), ExpertRequest{Message: "Hi"})
+			},
+			wantEntity: nil,
+		},
+		{
+			name: "entity already set is kept",
+			call: func() (*ExpertResult, error) {
+				return experts["support"].Handler(context.Background(), ExpertRequest{EntityID: "widget-pro", Entity: "preloaded"})
+			},
+			wantEntity: "preloaded",
+		},
+		{
+			name: "resolver error stops the expert",
+			call: func() (*ExpertResult, error) {
+				return experts["product"].Handler(context.Background(), ExpertRequest{EntityID: "broken"})
+			},
+			wantErr:      errUnavailable,
+			wantResolved: []string{"broken"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolved, received = nil, nil
+
+			_, err := tt.call()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if !slices.Equal(resolved, tt.wantResolved) {
+				t.Errorf("resolved %v, want %v", resolved, tt.wantResolved)
+			}
+			if tt.wantErr != nil {
+				if len(received) != 0 {
+					t.Errorf("expert ran after a failed resolve")
+				}
+				return
+			}
+			if len(received) != 1 || !reflect.DeepEqual(received[0], tt.wantEntity) {
+				t.Errorf("expert received entity %v, want %v", received, tt.wantEntity)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package aichat
+
+import (
+	"context"
+	"log/slog"
+)
+
+// ephemeralStoreKey is the context key for the scratch store of an ephemeral request.
+type ephemeralStoreKey struct{}
+
+// withEphemeralRequest returns a context in which conversations are kept in a
+// scratch store that is discarded with the request. A conversation ID sent with
+// the request starts out empty, without its stored history.
+func withEphemeralRequest(ctx context.Context, req ChatRequest) (context.Context, error) {
+	scratch := NewMemoryStore(slog.New(slog.DiscardHandler))
+	if req.ConversationID != "" {
+		if err := scratch.Save(ctx, newConversationRecord(req.ConversationID, req)); err != nil {
+			return nil, err
+		}
+	}
+	return context.WithValue(ctx, ephemeralStoreKey{}, scratch), nil
+}
+
+// withEphemeralConversations wraps a store so that ephemeral requests create, read
+// and update conversations in their scratch store instead. Other operations, and
+// all operations outside ephemeral requests, use store.
+func withEphemeralConversations(store ConversationStore) ConversationStore {
+	resolve := func(ctx context.Context) ConversationStore {
+		if scratch, ok := ctx.Value(ephemeralStoreKey{}).(ConversationStore); ok {
+			return scratch
+		}
+		return store
+	}
+
+	wrapped := store
+	wrapped.Create = func(ctx context.Context, entityID string) (*Conversation, error) {
+		return resolve(ctx).Create(ctx, entityID)
+	}
+	wrapped.Get = func(ctx context.Context, id string) (*Conversation, error) {
+		return resolve(ctx).Get(ctx, id)
+	}
+	wrapped.AddMessage = func(ctx context.Context, id string, msg Message) error {
+		return resolve(ctx).AddMessage(ctx, id, msg)
+	}
+	wrapped.Save = func(ctx context.Context, conversation *Conversation) error {
+		return resolve(ctx).Save(ctx, conversation)
+	}
+	return wrapped
+}
+
+// isEphemeral reports whether nothing may be persisted for req.
+func isEphemeral(req ChatRequest, disablePersistence bool) bool {
+	return req.Ephemeral || disablePersistence
+}
+
+// withEphemeralRequests wraps a chat function to keep ephemeral requests out of the store.
+func withEphemeralRequests(processChat ProcessChatFn, disablePersistence bool) ProcessChatFn {
+	return func(ctx context.Context, req ChatRequest) (*ChatResult, error) {
+		if !isEphemeral(req, disablePersistence) {
+			return processChat(ctx, req)
+		}
+		ctx, err := withEphemeralRequest(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return processChat(ctx, req)
+	}
+}
+
+// withEphemeralRequestsStreaming wraps a streaming chat function to keep ephemeral requests out of the store.
+func withEphemeralRequestsStreaming(processChatStream ProcessChatStreamFn, disablePersistence bool) ProcessChatStreamFn {
+	return func(ctx context.Context, req ChatRequest, stream StreamCallback) (*ChatResult, error) {
+		if !isEphemeral(req, disablePersistence) {
+			return processChatStream(ctx, req, stream)
+		}
+		ctx, err := withEphemeralRequest(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return processChatStream(ctx, req, stream)
+	}
+}
@@ -0,0 +1,128 @@
+package aichat_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	aichat "github.com/ourstudio-se/ai-chat-sdk"
+	"github.com/ourstudio-se/ai-chat-sdk/aichattest"
+)
+
+// newEphemeralSDK creates an SDK whose product expert echoes the message.
+func newEphemeralSDK(t *testing.T, llm *aichattest.LLM, disablePersistence bool) *aichat.SDK {
+	t.Helper()
+
+	sdk, err := aichat.New(aichat.Config{
+		LLMClient: llm.Client(),
+		Experts: map[aichat.ExpertType]aichat.Expert{
+			"product": {
+				Name:        "Product Expert",
+				Description: "Answers product questions",
+				Handler: func(ctx context.Context, req aichat.ExpertRequest) (*aichat.ExpertResult, error) {
+					return &aichat.ExpertResult{Answer: "Re: " + req.Message}, nil
+				},
+			},
+		},
+		LanguageDetector: func(text string) aichat.LanguageDetection {
+			return aichat.LanguageDetection{Language: "en", Confidence: 1}
+		},
+		DisablePersistence: disablePersistence,
+		DevMode:            true,
+		Logger:             slog.New(slog.DiscardHandler),
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return sdk
+}
+
+func TestEphemeralRequestsPersistNothing(t *testing.T) {
+	ctx := context.Background()
+	route := aichattest.Route("product", "Asks about a product")
+	llm := aichattest.NewLLM(route, route, route, route)
+	sdk := newEphemeralSDK(t, llm, false)
+	chat := sdk.ProcessChat()
+
+	stored, err := chat(ctx, aichat.ChatRequest{Message: "What does it cost?"})
+	if err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+
+	// A new ephemeral conversation still gets an ID, but is never stored
+	fresh, err := chat(ctx, aichat.ChatRequest{Message: "How heavy is it?", Ephemeral: true})
+	if err != nil {
+		t.Fatalf("Chat(ephemeral) error = %v", err)
+	}
+	if fresh.ConversationID == "" || fresh.ConversationID == stored.ConversationID {
+		t.Errorf("ConversationID = %q, want a new ID", fresh.ConversationID)
+	}
+	if fresh.ExpertResult.Answer != "Re: How heavy is it?" {
+		t.Errorf("Answer = %q, want %q", fresh.ExpertResult.Answer, "Re: How heavy is it?")
+	}
+	if _, err := sdk.ExportConversation(ctx, fresh.ConversationID, aichat.ExportFormatJSONL); !errors.Is(err, aichat.ErrConversationNotFound) {
+		t.Errorf("ExportConversation(ephemeral) error = %v, want ErrConversationNotFound", err)
+	}
+
+	// An ephemeral turn on a stored conversation leaves it unchanged
+	onStored, err := chat(ctx, aichat.ChatRequest{Message: "Is it waterproof?", ConversationID: stored.ConversationID, Ephemeral: true})
+	if err != nil {
+		t.Fatalf("Chat(ephemeral, stored ID) error = %v", err)
+	}
+	if onStored.ConversationID != stored.ConversationID {
+		t.Errorf("ConversationID = %q, want %q", onStored.ConversationID, stored.ConversationID)
+	}
+
+	// An unknown ID is used as sent, without being created
+	unknown, err := chat(ctx, aichat.ChatRequest{Message: "Does it float?", ConversationID: "client-side-id", Ephemeral: true})
+	if err != nil {
+		t.Fatalf("Chat(ephemeral, unknown ID) error = %v", err)
+	}
+	if unknown.ConversationID != "client-side-id" {
+		t.Errorf("ConversationID = %q, want %q", unknown.ConversationID, "client-side-id")
+	}
+
+	conversations, err := sdk.ListConversations(ctx, aichat.ConversationFilter{})
+	if err != nil {
+		t.Fatalf("ListConversations() error = %v", err)
+	}
+	if len(conversations) != 1 || conversations[0].ID != stored.ConversationID {
+		t.Fatalf("ListConversations() = %d conversations, want only %s", len(conversations), stored.ConversationID)
+	}
+	if messages := conversations[0].Messages; len(messages) != 2 || messages[1].Content != "Re: What does it cost?" {
+		t.Errorf("stored messages = %+v, want only the first turn", messages)
+	}
+	llm.AssertDone(t)
+}
+
+func TestDisablePersistenceStoresNoConversations(t *testing.T) {
+	ctx := context.Background()
+	route := aichattest.Route("product", "Asks about a product")
+	llm := aichattest.NewLLM(route, route)
+	sdk := newEphemeralSDK(t, llm, true)
+	chat := sdk.ProcessChat()
+
+	first, err := chat(ctx, aichat.ChatRequest{Message: "What does it cost?"})
+	if err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	if first.ConversationID == "" {
+		t.Error("ConversationID is empty, want a generated ID")
+	}
+	if _, err := chat(ctx, aichat.ChatRequest{Message: "How heavy is it?", ConversationID: first.ConversationID}); err != nil {
+		t.Fatalf("Chat(follow-up) error = %v", err)
+	}
+
+	if _, err := sdk.ExportConversation(ctx, first.ConversationID, aichat.ExportFormatJSONL); !errors.Is(err, aichat.ErrConversationNotFound) {
+		t.Errorf("ExportConversation() error = %v, want ErrConversationNotFound", err)
+	}
+	conversations, err := sdk.ListConversations(ctx, aichat.ConversationFilter{})
+	if err != nil {
+		t.Fatalf("ListConversations() error = %v", err)
+	}
+	if len(conversations) != 0 {
+		t.Errorf("ListConversations() = %d conversations, want none", len(conversations))
+	}
+	llm.AssertDone(t)
+}
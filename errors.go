@@ -1,6 +1,11 @@
 package aichat
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+)
 
 var (
 	// ErrNotFound indicates a resource was not found.
@@ -20,4 +25,137 @@ var (
 
 	// ErrExpertNotFound indicates the requested expert was not found.
 	ErrExpertNotFound = errors.New("expert not found")
+
+	// ErrMessageNotFound indicates the message was not found in the conversation.
+	ErrMessageNotFound = errors.New("message not found")
+
+	// ErrNothingToRegenerate indicates the conversation does not end with an assistant message.
+	ErrNothingToRegenerate = errors.New("the last message is not an assistant message")
+
+	// ErrFeedbackNotFound indicates no feedback was stored for the message.
+	ErrFeedbackNotFound = errors.New("feedback not found")
+
+	// ErrQuotaExceeded indicates an expert's quota has been used up.
+	ErrQuotaExceeded = errors.New("quota exceeded")
+
+	// ErrConversationExists indicates a generated conversation ID is already in use.
+	ErrConversationExists = errors.New("conversation already exists")
+
+	// ErrConversationBusy indicates another turn on the conversation is in progress.
+	ErrConversationBusy = errors.New("conversation busy")
+
+	// ErrTokenBudgetExceeded indicates the conversation has used up Config.MaxConversationTokens.
+	ErrTokenBudgetExceeded = errors.New("conversation token budget exceeded")
+
+	// ErrContextLengthExceeded indicates the prompt does not fit the model's context window.
+	// The built-in clients return a *ContextLengthError with the token counts.
+	ErrContextLengthExceeded = errors.New("context length exceeded")
+
+	// ErrImagesNotSupported indicates the LLM provider cannot process images.
+	// Custom LLM clients without vision support should return it when ChatOptions.Images is set.
+	ErrImagesNotSupported = errors.New("images are not supported by this LLM provider")
+
+	// ErrNotSupported indicates the configured store does not support the operation.
+	ErrNotSupported = errors.New("operation not supported")
+)
+
+// ErrorCode is a machine-readable error code returned in HTTP error responses
+// and streamed error events, so clients can decide whether to retry.
+type ErrorCode string
+
+const (
+	// CodeInvalidRequest indicates a malformed or invalid request. Do not retry.
+	CodeInvalidRequest ErrorCode = "invalid_request"
+
+	// CodePayloadTooLarge indicates the message or batch exceeds a configured limit or the
+	// model's context window. Do not retry.
+	CodePayloadTooLarge ErrorCode = "payload_too_large"
+
+	// CodeUnauthorized indicates missing or invalid credentials. Retry with valid credentials.
+	CodeUnauthorized ErrorCode = "unauthorized"
+
+	// CodeNotFound indicates the referenced resource (e.g. conversation) does not exist. Do not retry.
+	CodeNotFound ErrorCode = "not_found"
+
+	// CodeConversationBusy indicates another message on the conversation is still being
+	// processed. Retry once it has completed.
+	CodeConversationBusy ErrorCode = "conversation_busy"
+
+	// CodeBudgetExhausted indicates the conversation's token budget is used up.
+	// Do not retry; start a new conversation.
+	CodeBudgetExhausted ErrorCode = "budget_exhausted"
+
+	// CodeRateLimited indicates a quota was exceeded. Retry after the quota window.
+	CodeRateLimited ErrorCode = "rate_limited"
+
+	// CodeImagesNotSupported indicates the message has images the LLM provider cannot
+	// process. Do not retry with the images.
+	CodeImagesNotSupported ErrorCode = "images_not_supported"
+
+	// CodeTimeout indicates the request exceeded its deadline. Safe to retry.
+	CodeTimeout ErrorCode = "timeout"
+
+	// CodeInternal indicates an unexpected server-side failure. May be retried.
+	CodeInternal ErrorCode = "internal_error"
 )
+
+// errorCodeForStatus returns the generic error code for an HTTP status, for
+// errors that were not classified by classifyChatError.
+func errorCodeForStatus(status int) ErrorCode {
+	switch status {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return CodeInvalidRequest
+	case http.StatusRequestEntityTooLarge:
+		return CodePayloadTooLarge
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConversationBusy
+	case http.StatusPaymentRequired:
+		return CodeBudgetExhausted
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusGatewayTimeout:
+		return CodeTimeout
+	default:
+		return CodeInternal
+	}
+}
+
+// classifyChatError maps an error from chat processing to an HTTP status, an error
+// code and a client-safe message.
+func classifyChatError(err error) (int, ErrorCode, string) {
+	switch {
+	case errors.Is(err, ErrConversationNotFound):
+		return http.StatusNotFound, CodeNotFound, "Conversation not found"
+	case errors.Is(err, ErrInvalidInput):
+		return http.StatusBadRequest, CodeInvalidRequest, invalidInputMessage(err)
+	case errors.Is(err, ErrConversationBusy):
+		return http.StatusConflict, CodeConversationBusy, "Another message in this conversation is still being processed"
+	case errors.Is(err, ErrTokenBudgetExceeded):
+		return http.StatusPaymentRequired, CodeBudgetExhausted, "This conversation has reached its token limit, please start a new one"
+	case errors.Is(err, ErrContextLengthExceeded):
+		return http.StatusRequestEntityTooLarge, CodePayloadTooLarge, "The message is too long to process, please shorten it"
+	case errors.Is(err, ErrImagesNotSupported):
+		return http.StatusUnprocessableEntity, CodeImagesNotSupported, "Images are not supported, please send the message without them"
+	case errors.Is(err, ErrQuotaExceeded):
+		return http.StatusTooManyRequests, CodeRateLimited, "Rate limit exceeded, please try again later"
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, CodeTimeout, "The request timed out"
+	default:
+		return http.StatusInternalServerError, CodeInternal, "An error occurred while processing your message"
+	}
+}
+
+// invalidInputMessage returns the message of an ErrInvalidInput error without the
+// context added by the layers that wrapped it. Validation errors describe what the
+// caller sent, so they are safe to return.
+func invalidInputMessage(err error) string {
+	message := err.Error()
+	if i := strings.Index(message, ErrInvalidInput.Error()); i >= 0 {
+		return message[i:]
+	}
+	return message
+}
@@ -0,0 +1,181 @@
+package aichat_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	aichat "github.com/ourstudio-se/ai-chat-sdk"
+	"github.com/ourstudio-se/ai-chat-sdk/aichattest"
+)
+
+func TestSlowLLMCallsTimeOutWith504(t *testing.T) {
+	// An LLM that doesn't answer within the request timeout
+	slow := func(ctx context.Context) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(5 * time.Second):
+			return nil
+		}
+	}
+	sdk, err := aichat.New(aichat.Config{
+		LLMClient: aichat.LLMClient{
+			Chat: func(ctx context.Context, systemPrompt, userMessage string, opts *aichat.ChatOptions) (string, error) {
+				return "", slow(ctx)
+			},
+			ChatJSON: func(ctx context.Context, systemPrompt, userMessage string, opts *aichat.ChatJSONOptions, result any) error {
+				return slow(ctx)
+			},
+			ChatStream: func(ctx context.Context, systemPrompt, userMessage string, opts *aichat.ChatOptions, onToken func(string)) (string, error) {
+				return "", slow(ctx)
+			},
+		},
+		LanguageDetector: func(text string) aichat.LanguageDetection {
+			return aichat.LanguageDetection{Language: "en", Confidence: 1}
+		},
+		RequestTimeout: 50 * time.Millisecond,
+		DevMode:        true,
+		Logger:         slog.New(slog.DiscardHandler),
+		Experts: map[aichat.ExpertType]aichat.Expert{
+			"product": {
+				Name:        "Product Expert",
+				Description: "Questions about products",
+				Handler: func(ctx context.Context, req aichat.ExpertRequest) (*aichat.ExpertResult, error) {
+					return &aichat.ExpertResult{Answer: "It has three speeds."}, nil
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	post := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"message": "How many speeds?"}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		start := time.Now()
+		sdk.HTTPHandler().ServeHTTP(rec, req)
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("POST %s took %v, want it cut off by the request timeout", path, elapsed)
+		}
+		return rec
+	}
+
+	rec := post("/chat")
+	var resp aichat.ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response %q: %v", rec.Body.String(), err)
+	}
+	if rec.Code != http.StatusGatewayTimeout || resp.Code != aichat.CodeTimeout {
+		t.Errorf("POST /chat = %d %+v, want 504 with code %s", rec.Code, resp, aichat.CodeTimeout)
+	}
+
+	// A stream that has started reports the timeout in its error event
+	rec = post("/chat/stream")
+	var event aichat.StreamEvent
+	for line := range strings.SplitSeq(rec.Body.String(), "\n") {
+		data, ok := strings.CutPrefix(line, "data: ")
+		if ok && json.Unmarshal([]byte(data), &event) == nil && event.Type == aichat.EventError {
+			break
+		}
+	}
+	if event.Type != aichat.EventError || event.Code != aichat.CodeTimeout {
+		t.Errorf("POST /chat/stream last event = %+v, want an error event with code %s", event, aichat.CodeTimeout)
+	}
+}
+
+func TestChatErrorsCarryTheirCode(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   aichat.ErrorCode
+	}{
+		{name: "images not supported", err: aichat.ErrImagesNotSupported, wantStatus: http.StatusUnprocessableEntity, wantCode: aichat.CodeImagesNotSupported},
+		{name: "context length", err: aichat.ErrContextLengthExceeded, wantStatus: http.StatusRequestEntityTooLarge, wantCode: aichat.CodePayloadTooLarge},
+		{name: "quota", err: aichat.ErrQuotaExceeded, wantStatus: http.StatusTooManyRequests, wantCode: aichat.CodeRateLimited},
+		{name: "invalid input", err: aichat.ErrInvalidInput, wantStatus: http.StatusBadRequest, wantCode: aichat.CodeInvalidRequest},
+		{name: "unclassified", err: errors.New("backend unavailable"), wantStatus: http.StatusInternalServerError, wantCode: aichat.CodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			llm := aichattest.NewLLM(aichattest.Route(testExpert, "Asks about a product"))
+			sdk := newTestSDK(t, llm, aichat.Config{
+				Experts: map[aichat.ExpertType]aichat.Expert{
+					testExpert: {
+						Name:        "Product Expert",
+						Description: "Questions about products",
+						Handler: func(ctx context.Context, req aichat.ExpertRequest) (*aichat.ExpertResult, error) {
+							return nil, fmt.Errorf("expert failed: %w", tt.err)
+						},
+					},
+				},
+			})
+
+			rec := serve(t, sdk, http.MethodPost, "/chat", "", aichat.HTTPChatRequest{Message: "How many speeds?"})
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if body := decode[aichat.ErrorResponse](t, rec); body.Code != tt.wantCode {
+				t.Errorf("code = %q, want %q", body.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestInvalidRequestsReturnTheValidationError(t *testing.T) {
+	temperature := float32(2.5)
+	tests := []struct {
+		name      string
+		req       aichat.HTTPChatRequest
+		wantError string
+	}{
+		{name: "model not allowed", req: aichat.HTTPChatRequest{Message: "How many speeds?", Model: "gpt-unlisted"}, wantError: `invalid input: model "gpt-unlisted" is not allowed`},
+		{name: "temperature out of range", req: aichat.HTTPChatRequest{Message: "How many speeds?", Temperature: &temperature}, wantError: "invalid input: temperature must be between 0 and 2"},
+		{name: "invalid data", req: aichat.HTTPChatRequest{Message: "How many speeds?", Data: map[string]any{}}, wantError: "invalid input: invalid data for expert product: orderId is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			llm := aichattest.NewLLM(aichattest.Route(testExpert, "Asks about a product"))
+			sdk := newTestSDK(t, llm, aichat.Config{
+				AllowedModels: []string{"gpt-4o"},
+				Experts: map[aichat.ExpertType]aichat.Expert{
+					testExpert: {
+						Name:        "Product Expert",
+						Description: "Questions about products",
+						ValidateData: func(data any) error {
+							if data != nil {
+								return errors.New("orderId is required")
+							}
+							return nil
+						},
+						Handler: func(ctx context.Context, req aichat.ExpertRequest) (*aichat.ExpertResult, error) {
+							return &aichat.ExpertResult{Answer: "It has three speeds."}, nil
+						},
+					},
+				},
+			})
+
+			rec := serve(t, sdk, http.MethodPost, "/chat", "", tt.req)
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusBadRequest, rec.Body)
+			}
+			body := decode[aichat.ErrorResponse](t, rec)
+			if body.Code != aichat.CodeInvalidRequest || body.Error != tt.wantError {
+				t.Errorf("error = %q (%s), want %q (%s)", body.Error, body.Code, tt.wantError, aichat.CodeInvalidRequest)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package aichat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ExportFormat identifies the output format of a conversation export.
+type ExportFormat string
+
+const (
+	// ExportFormatOpenAI exports a JSON array of OpenAI chat messages.
+	ExportFormatOpenAI ExportFormat = "openai"
+
+	// ExportFormatJSONL exports one OpenAI chat message per line.
+	ExportFormatJSONL ExportFormat = "jsonl"
+)
+
+// ExportedMessage is a conversation message in OpenAI chat messages format.
+type ExportedMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// NewConversationExporter creates a function that exports stored conversations.
+func NewConversationExporter(store ConversationStore) ExportConversationFn {
+	return func(ctx context.Context, id string, format ExportFormat) ([]byte, error) {
+		if format == "" {
+			format = ExportFormatOpenAI
+		}
+		if format != ExportFormatOpenAI && format != ExportFormatJSONL {
+			return nil, fmt.Errorf("%w: unsupported export format %q", ErrInvalidInput, format)
+		}
+
+		conversation, err := store.Get(ctx, id)
+		if err != nil {
+			if errors.Is(err, ErrConversationNotFound) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("failed to get conversation: %w", err)
+		}
+
+		messages := toExportedMessages(conversation.Messages)
+
+		if format == ExportFormatJSONL {
+			var buf bytes.Buffer
+			enc := json.NewEncoder(&buf)
+			for _, msg := range messages {
+				if err := enc.Encode(msg); err != nil {
+					return nil, fmt.Errorf("failed to encode message: %w", err)
+				}
+			}
+			return buf.Bytes(), nil
+		}
+
+		data, err := json.Marshal(messages)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode messages: %w", err)
+		}
+		return data, nil
+	}
+}
+
+func toExportedMessages(messages []Message) []ExportedMessage {
+	exported := make([]ExportedMessage, 0, len(messages))
+	for _, msg := range messages {
+		exported = append(exported, ExportedMessage{
+			Role:    string(msg.Role),
+			Content: msg.Content,
+		})
+	}
+	return exported
+}
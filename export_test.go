@@ -0,0 +1,119 @@
+package aichat_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+
+	aichat "github.com/ourstudio-se/ai-chat-sdk"
+)
+
+func TestExportConversation(t *testing.T) {
+	ctx := context.Background()
+	store := aichat.NewMemoryStore(slog.New(slog.DiscardHandler))
+	export := aichat.NewConversationExporter(store)
+
+	conversation, err := store.Create(ctx, "widget-pro")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	empty, err := store.Create(ctx, "")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	expert := "product"
+	messages := []aichat.Message{
+		{Role: aichat.RoleUser, Content: "Does it come in \"black\"?"},
+		{Role: aichat.RoleAssistant, Content: "Yes:\n- black\n- white", Expert: &expert, Data: map[string]string{"sku": "WP-1"}},
+	}
+	for _, msg := range messages {
+		if err := store.AddMessage(ctx, conversation.ID, msg); err != nil {
+			t.Fatalf("AddMessage() error = %v", err)
+		}
+	}
+	want := []aichat.ExportedMessage{
+		{Role: "user", Content: "Does it come in \"black\"?"},
+		{Role: "assistant", Content: "Yes:\n- black\n- white"},
+	}
+
+	t.Run("openai round-trips to chat messages", func(t *testing.T) {
+		for _, format := range []aichat.ExportFormat{aichat.ExportFormatOpenAI, ""} {
+			data, err := export(ctx, conversation.ID, format)
+			if err != nil {
+				t.Fatalf("export(%q) error = %v", format, err)
+			}
+
+			// Only role and content are exported, so expert names and data stay internal
+			var raw []map[string]any
+			if err := json.Unmarshal(data, &raw); err != nil {
+				t.Fatalf("export(%q) is not a JSON array: %v", format, err)
+			}
+			for _, msg := range raw {
+				if len(msg) != 2 || (msg["role"] != "user" && msg["role"] != "assistant") {
+					t.Errorf("export(%q) message %v is not an OpenAI chat message", format, msg)
+				}
+			}
+
+			var got []aichat.ExportedMessage
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatalf("export(%q) error = %v", format, err)
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("export(%q) = %+v, want %+v", format, got, want)
+			}
+		}
+	})
+
+	t.Run("jsonl writes one message per line", func(t *testing.T) {
+		data, err := export(ctx, conversation.ID, aichat.ExportFormatJSONL)
+		if err != nil {
+			t.Fatalf("export() error = %v", err)
+		}
+
+		lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+		if len(lines) != len(want) {
+			t.Fatalf("export() wrote %d lines, want %d: %q", len(lines), len(want), data)
+		}
+		for i, line := range lines {
+			var got aichat.ExportedMessage
+			if err := json.Unmarshal([]byte(line), &got); err != nil {
+				t.Fatalf("line %d %q is not a JSON message: %v", i+1, line, err)
+			}
+			if got != want[i] {
+				t.Errorf("line %d = %+v, want %+v", i+1, got, want[i])
+			}
+		}
+	})
+
+	t.Run("empty conversation", func(t *testing.T) {
+		data, err := export(ctx, empty.ID, aichat.ExportFormatOpenAI)
+		if err != nil {
+			t.Fatalf("export(openai) error = %v", err)
+		}
+		if string(data) != "[]" {
+			t.Errorf("export(openai) = %s, want []", data)
+		}
+
+		data, err = export(ctx, empty.ID, aichat.ExportFormatJSONL)
+		if err != nil {
+			t.Fatalf("export(jsonl) error = %v", err)
+		}
+		if len(data) != 0 {
+			t.Errorf("export(jsonl) = %q, want no lines", data)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		if _, err := export(ctx, conversation.ID, "csv"); !errors.Is(err, aichat.ErrInvalidInput) {
+			t.Errorf("export(csv) error = %v, want ErrInvalidInput", err)
+		}
+		if _, err := export(ctx, "missing", aichat.ExportFormatOpenAI); !errors.Is(err, aichat.ErrConversationNotFound) {
+			t.Errorf("export(missing) error = %v, want ErrConversationNotFound", err)
+		}
+	})
+}
@@ -0,0 +1,55 @@
+package aichat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// maxFeedbackCommentLength is the maximum length of a feedback comment in characters.
+const maxFeedbackCommentLength = 2000
+
+// NewFeedbackRecorder creates a function that stores feedback on an assistant message
+// after checking that the message exists in the referenced conversation.
+func NewFeedbackRecorder(store ConversationStore) SubmitFeedbackFn {
+	return func(ctx context.Context, feedback Feedback) (*Feedback, error) {
+		if store.SaveFeedback == nil {
+			return nil, ErrNotSupported
+		}
+
+		if feedback.Rating != RatingPositive && feedback.Rating != RatingNegative {
+			return nil, fmt.Errorf("%w: rating must be %q or %q", ErrInvalidInput, RatingPositive, RatingNegative)
+		}
+
+		if len(feedback.Comment) > maxFeedbackCommentLength {
+			return nil, fmt.Errorf("%w: comment exceeds %d characters", ErrInvalidInput, maxFeedbackCommentLength)
+		}
+
+		conversation, err := store.Get(ctx, feedback.ConversationID)
+		if err != nil {
+			if errors.Is(err, ErrConversationNotFound) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("failed to get conversation: %w", err)
+		}
+
+		found := false
+		for _, msg := range conversation.Messages {
+			if msg.ID == feedback.MessageID && msg.Role == RoleAssistant {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, ErrMessageNotFound
+		}
+
+		feedback.CreatedAt = time.Now()
+		if err := store.SaveFeedback(ctx, feedback); err != nil {
+			return nil, fmt.Errorf("failed to save feedback: %w", err)
+		}
+
+		return &feedback, nil
+	}
+}
@@ -0,0 +1,79 @@
+package aichat_test
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	aichat "github.com/ourstudio-se/ai-chat-sdk"
+)
+
+func TestSaveFeedbackUpsertsByMessageID(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+	dir := t.TempDir()
+
+	stores := map[string]func(t *testing.T) aichat.ConversationStore{
+		"memory": func(t *testing.T) aichat.ConversationStore {
+			return aichat.NewMemoryStore(logger)
+		},
+		"file": func(t *testing.T) aichat.ConversationStore {
+			store, err := aichat.NewFileStore(dir, logger)
+			if err != nil {
+				t.Fatalf("NewFileStore() error = %v", err)
+			}
+			return store
+		},
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			store := newStore(t)
+
+			created := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+			ratings := []aichat.Feedback{
+				{MessageID: "msg-1", ConversationID: "conv-1", Rating: aichat.RatingPositive, CreatedAt: created},
+				{MessageID: "msg-1", ConversationID: "conv-1", Rating: aichat.RatingNegative, Comment: "Wrong size", CreatedAt: created.Add(time.Minute)},
+				{MessageID: "msg-2", ConversationID: "conv-1", Rating: aichat.RatingPositive, CreatedAt: created.Add(2 * time.Minute)},
+			}
+			for _, fb := range ratings {
+				if err := store.SaveFeedback(ctx, fb); err != nil {
+					t.Fatalf("SaveFeedback() error = %v", err)
+				}
+			}
+
+			got, err := store.GetFeedback(ctx, "msg-1")
+			if err != nil {
+				t.Fatalf("GetFeedback() error = %v", err)
+			}
+			if got.Rating != aichat.RatingNegative || got.Comment != "Wrong size" {
+				t.Errorf("GetFeedback() = %+v, want the latest negative rating", got)
+			}
+
+			list, err := store.ListFeedback(ctx, "conv-1")
+			if err != nil {
+				t.Fatalf("ListFeedback() error = %v", err)
+			}
+			if len(list) != 2 {
+				t.Fatalf("ListFeedback() returned %d records, want one per message: %+v", len(list), list)
+			}
+			if list[0].MessageID != "msg-1" || list[0].Rating != aichat.RatingNegative || list[1].MessageID != "msg-2" {
+				t.Errorf("ListFeedback() = %+v, want msg-1 negative then msg-2", list)
+			}
+		})
+	}
+
+	// A file store reopened on the same directory sees the replaced record only
+	reopened, err := aichat.NewFileStore(dir, logger)
+	if err != nil {
+		t.Fatalf("NewFileStore() error = %v", err)
+	}
+	list, err := reopened.ListFeedback(context.Background(), "conv-1")
+	if err != nil {
+		t.Fatalf("ListFeedback() error = %v", err)
+	}
+	if len(list) != 2 || list[0].Rating != aichat.RatingNegative {
+		t.Errorf("reopened ListFeedback() = %+v, want 2 records with msg-1 negative", list)
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !unix
+
+package aichat
+
+import "os"
+
+// fileLockingSupported reports whether WithFileLocking can be used on this platform.
+const fileLockingSupported = false
+
+func lockFile(f *os.File) error {
+	return errFileLockingUnsupported
+}
+
+func unlockFile(f *os.File) error {
+	return errFileLockingUnsupported
+}
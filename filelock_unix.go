@@ -0,0 +1,21 @@
+//go:build unix
+
+package aichat
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileLockingSupported reports whether WithFileLocking can be used on this platform.
+const fileLockingSupported = true
+
+// lockFile acquires an exclusive advisory lock on f, blocking until it is available.
+func lockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+}
+
+// unlockFile releases the lock acquired by lockFile.
+func unlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,58 @@
+//go:build unix
+
+package aichat_test
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"testing"
+
+	aichat "github.com/ourstudio-se/ai-chat-sdk"
+)
+
+func TestFileStoreWithFileLockingKeepsConcurrentMessages(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	logger := slog.New(slog.DiscardHandler)
+
+	// Two stores on one directory stand in for two processes sharing a volume
+	var stores []aichat.ConversationStore
+	for range 2 {
+		store, err := aichat.NewFileStore(dir, logger, aichat.WithFileLocking())
+		if err != nil {
+			t.Fatalf("NewFileStore() error = %v", err)
+		}
+		stores = append(stores, store)
+	}
+
+	conversation, err := stores[0].Create(ctx, "")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	const perStore = 25
+	var wg sync.WaitGroup
+	for i, store := range stores {
+		for j := range perStore {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				msg := aichat.Message{Role: aichat.RoleUser, Content: fmt.Sprintf("message %d-%d", i, j)}
+				if err := store.AddMessage(ctx, conversation.ID, msg); err != nil {
+					t.Errorf("AddMessage() error = %v", err)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+
+	stored, err := stores[1].Get(ctx, conversation.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got, want := len(stored.Messages), len(stores)*perStore; got != want {
+		t.Errorf("stored %d messages, want %d", got, want)
+	}
+}
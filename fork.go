@@ -0,0 +1,132 @@
+package aichat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+)
+
+// newConversationForker creates a function that forks conversations. The new
+// conversation keeps the entity, metadata and tags. Copied messages get new IDs,
+// since feedback is stored per message ID, and the tokens they used count towards
+// the new conversation's budget. Their usage stays with the source conversation,
+// so usage reports of a fork and its source don't count it twice.
+func newConversationForker(store ConversationStore, idGenerator IDGenerator) ForkConversationFn {
+	return func(ctx context.Context, id, fromMessageID string) (string, error) {
+		source, err := getConversation(ctx, store, id)
+		if err != nil {
+			return "", err
+		}
+
+		index := messageIndex(source.Messages, fromMessageID)
+		if index < 0 {
+			return "", ErrMessageNotFound
+		}
+
+		ctx = withIDGenerator(ctx, idGenerator)
+		fork, err := createConversation(ctx, store, ChatRequest{EntityID: source.EntityID})
+		if err != nil {
+			return "", fmt.Errorf("failed to create conversation: %w", err)
+		}
+
+		fork.Metadata = maps.Clone(source.Metadata)
+		fork.Tags = slices.Clone(source.Tags)
+		fork.Usage = &ConversationUsage{}
+		for _, msg := range source.Messages[:index+1] {
+			if msg.ID, err = newMessageID(ctx); err != nil {
+				return "", err
+			}
+			appendMessage(fork, msg)
+		}
+
+		if err := store.Save(ctx, fork); err != nil {
+			return "", fmt.Errorf("failed to save conversation: %w", err)
+		}
+
+		return fork.ID, nil
+	}
+}
+
+// newConversationTruncator creates a function that truncates conversations, e.g. so
+// a user can edit a message and continue from there. lock serializes truncating with
+// chat turns. The tokens used by removed messages still count towards the budget.
+func newConversationTruncator(store ConversationStore, lock LockConversationFn) TruncateConversationFn {
+	return func(ctx context.Context, id, afterMessageID string) (*Conversation, error) {
+		return truncateConversation(ctx, store, lock, id, func(messages []Message) (int, error) {
+			index := messageIndex(messages, afterMessageID)
+			if index < 0 {
+				return 0, ErrMessageNotFound
+			}
+			return index + 1, nil
+		})
+	}
+}
+
+// truncateConversation keeps the first keep(messages) messages of a conversation
+// under the conversation lock.
+func truncateConversation(
+	ctx context.Context,
+	store ConversationStore,
+	lock LockConversationFn,
+	id string,
+	keep func(messages []Message) (int, error),
+) (*Conversation, error) {
+	unlock, err := lock(ctx, id, true)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	conversation, err := getConversation(ctx, store, id)
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := keep(conversation.Messages)
+	if err != nil {
+		return nil, err
+	}
+	if err := saveTruncated(ctx, store, conversation, n); err != nil {
+		return nil, err
+	}
+	return conversation, nil
+}
+
+// saveTruncated keeps the first n messages of a conversation and saves it. The
+// caller must hold the conversation lock.
+func saveTruncated(ctx context.Context, store ConversationStore, conversation *Conversation, n int) error {
+	if n == len(conversation.Messages) {
+		return nil
+	}
+
+	conversation.Messages = conversation.Messages[:n]
+	conversation.Summary = nil
+	conversation.UpdatedAt = time.Now()
+
+	if err := store.Save(ctx, conversation); err != nil {
+		return fmt.Errorf("failed to save conversation: %w", err)
+	}
+	return nil
+}
+
+// getConversation returns a stored conversation, passing ErrConversationNotFound through unwrapped.
+func getConversation(ctx context.Context, store ConversationStore, id string) (*Conversation, error) {
+	conversation, err := store.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, ErrConversationNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to get conversation: %w", err)
+	}
+	return conversation, nil
+}
+
+// messageIndex returns the index of the message with the given ID, or -1.
+func messageIndex(messages []Message, messageID string) int {
+	return slices.IndexFunc(messages, func(msg Message) bool {
+		return msg.ID == messageID
+	})
+}
@@ -32,7 +32,7 @@ Please translate this answer to %s while maintaining:
 			req.DetectedLanguage,
 		)
 
-		translated, err := chat(ctx, systemPrompt, userPrompt, nil)
+		translated, err := chat(withTraceStep(ctx, TraceFormat), systemPrompt, userPrompt, nil)
 		if err != nil {
 			logger.Warn("translation failed, using original answer", slog.String("error", err.Error()))
 			return &FormatResponse{
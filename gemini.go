@@ -0,0 +1,492 @@
+package aichat
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// GeminiBaseURL is the base URL for the Gemini API.
+	GeminiBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+)
+
+// GeminiConfig holds configuration for creating a Gemini client.
+type GeminiConfig struct {
+	// APIKey is your Gemini API key (required).
+	APIKey string
+
+	// BaseURL overrides the API base URL (optional, defaults to GeminiBaseURL).
+	BaseURL string
+
+	// ModelMap maps model tiers to Gemini model names (optional, defaults to DefaultGeminiModelMap).
+	ModelMap map[ModelTier]string
+
+	// ModelFallbacks lists model names to try in order when a request fails with a
+	// rate limit, server or input-too-long error (optional).
+	ModelFallbacks []string
+
+	// HTTPClient is the HTTP client to use (optional, defaults to http.DefaultClient).
+	HTTPClient *http.Client
+
+	// Logger is the structured logger to use. If nil, a default logger is used.
+	Logger *slog.Logger
+}
+
+// DefaultGeminiModelMap returns a model map using Gemini Flash-Lite and Flash for
+// the smaller tiers and Gemini Pro for reasoning.
+func DefaultGeminiModelMap() map[ModelTier]string {
+	return map[ModelTier]string{
+		ModelNano:      "gemini-2.5-flash-lite",
+		ModelMini:      "gemini-2.5-flash",
+		ModelStandard:  "gemini-2.5-flash",
+		ModelReasoning: "gemini-2.5-pro",
+	}
+}
+
+// geminiRequest is a generateContent request body. The model is part of the URL.
+type geminiRequest struct {
+	model string
+
+	SystemInstruction *geminiContent         `json:"systemInstruction,omitempty"`
+	Contents          []geminiContent        `json:"contents"`
+	GenerationConfig  geminiGenerationConfig `json:"generationConfig"`
+}
+
+// geminiContent is a message in the Gemini format. Roles are "user" and "model".
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+// geminiPart is a text or inline image part.
+type geminiPart struct {
+	Text       string            `json:"text,omitempty"`
+	InlineData *geminiInlineData `json:"inlineData,omitempty"`
+
+	// Thought marks a part of the model's reasoning, which is not part of the answer.
+	Thought bool `json:"thought,omitempty"`
+}
+
+// geminiInlineData is base64-encoded image data.
+type geminiInlineData struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+type geminiGenerationConfig struct {
+	Temperature      float32 `json:"temperature"`
+	MaxOutputTokens  int     `json:"maxOutputTokens,omitempty"`
+	ResponseMimeType string  `json:"responseMimeType,omitempty"`
+}
+
+// geminiResponse is a generateContent response body, or one chunk of a stream.
+type geminiResponse struct {
+	Candidates []struct {
+		Content      geminiContent `json:"content"`
+		FinishReason string        `json:"finishReason"`
+	} `json:"candidates"`
+	PromptFeedback *struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback"`
+	UsageMetadata *geminiUsage `json:"usageMetadata"`
+}
+
+// text returns the answer text of the first candidate.
+func (r geminiResponse) text() string {
+	if len(r.Candidates) == 0 {
+		return ""
+	}
+	var text strings.Builder
+	for _, part := range r.Candidates[0].Content.Parts {
+		if !part.Thought {
+			text.WriteString(part.Text)
+		}
+	}
+	return text.String()
+}
+
+// finishReason returns the finish reason of the first candidate, or the reason
+// the prompt was blocked.
+func (r geminiResponse) finishReason() string {
+	if r.PromptFeedback != nil && r.PromptFeedback.BlockReason != "" {
+		return r.PromptFeedback.BlockReason
+	}
+	if len(r.Candidates) == 0 {
+		return ""
+	}
+	return r.Candidates[0].FinishReason
+}
+
+// geminiUsage is the token usage of a request. Cached tokens are counted in
+// PromptTokenCount, and thinking tokens are billed as output.
+type geminiUsage struct {
+	PromptTokenCount        int `json:"promptTokenCount"`
+	CandidatesTokenCount    int `json:"candidatesTokenCount"`
+	ThoughtsTokenCount      int `json:"thoughtsTokenCount"`
+	CachedContentTokenCount int `json:"cachedContentTokenCount"`
+}
+
+// completionTokens returns the output tokens, including thinking tokens.
+func (u geminiUsage) completionTokens() int {
+	return u.CandidatesTokenCount + u.ThoughtsTokenCount
+}
+
+// recordGeminiUsage records the token usage of a response served by model.
+func recordGeminiUsage(ctx context.Context, model string, usage geminiUsage) {
+	recordModelUsage(ctx, model, TokenUsage{
+		PromptTokens:     usage.PromptTokenCount,
+		CompletionTokens: usage.completionTokens(),
+		TotalTokens:      usage.PromptTokenCount + usage.completionTokens(),
+		CachedTokens:     usage.CachedContentTokenCount,
+	})
+}
+
+type geminiErrorDetail struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Status  string `json:"status"`
+}
+
+// GeminiError is an error returned by the Gemini API.
+type GeminiError struct {
+	StatusCode int
+	Status     string // e.g. "RESOURCE_EXHAUSTED" or "INVALID_ARGUMENT"
+	Message    string
+}
+
+func (e *GeminiError) Error() string {
+	return fmt.Sprintf("Gemini API error (status %d): %s: %s", e.StatusCode, e.Status, e.Message)
+}
+
+// NewGeminiClient creates an LLM client backed by the Gemini API.
+// Use it with Config.LLMClient to run the SDK on Gemini models.
+func NewGeminiClient(cfg GeminiConfig) LLMClient {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = GeminiBaseURL
+	}
+	if cfg.ModelMap == nil {
+		cfg.ModelMap = DefaultGeminiModelMap()
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+
+	c := &geminiClient{cfg: cfg, logger: cfg.Logger}
+
+	return LLMClient{
+		Chat:       c.chat,
+		ChatJSON:   c.chatJSON,
+		ChatStream: c.chatStream,
+	}
+}
+
+// NewGeminiHealthCheck creates a health check that lists models on the Gemini
+// API, verifying both reachability and the API key.
+func NewGeminiHealthCheck(cfg GeminiConfig) HealthCheckFn {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = GeminiBaseURL
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, cfg.BaseURL+"/models?pageSize=1", nil)
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("x-goog-api-key", cfg.APIKey)
+
+		resp, err := cfg.HTTPClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("LLM provider unreachable: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("LLM provider unreachable: %w", readGeminiError(resp))
+		}
+		return nil
+	}
+}
+
+type geminiClient struct {
+	cfg    GeminiConfig
+	logger *slog.Logger
+}
+
+func (c *geminiClient) chat(ctx context.Context, systemPrompt, userMessage string, opts *ChatOptions) (string, error) {
+	if opts == nil {
+		defaultOpts := defaultChatOptions()
+		opts = &defaultOpts
+	}
+
+	body, err := c.newRequest(systemPrompt, userMessage, opts.Images, opts.Model, opts.Temperature, opts.MaxTokens)
+	if err != nil {
+		return "", err
+	}
+	body.model, body.GenerationConfig.Temperature = applyModelOverride(ctx, body.model, body.GenerationConfig.Temperature)
+	return c.complete(ctx, body)
+}
+
+func (c *geminiClient) chatJSON(ctx context.Context, systemPrompt, userMessage string, opts *ChatJSONOptions, result any) error {
+	if opts == nil {
+		defaultOpts := defaultChatJSONOptions()
+		opts = &defaultOpts
+	}
+
+	body, err := c.newRequest(systemPrompt, userMessage, opts.Images, opts.Model, opts.Temperature, opts.MaxTokens)
+	if err != nil {
+		return err
+	}
+	body.GenerationConfig.ResponseMimeType = "application/json"
+
+	content, err := c.complete(ctx, body)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal([]byte(content), result); err != nil {
+		return &responseParseError{provider: "Gemini", content: content, err: err}
+	}
+
+	return nil
+}
+
+func (c *geminiClient) chatStream(ctx context.Context, systemPrompt, userMessage string, opts *ChatOptions, onToken func(token string)) (string, error) {
+	if opts == nil {
+		defaultOpts := defaultChatOptions()
+		opts = &defaultOpts
+	}
+
+	body, err := c.newRequest(systemPrompt, userMessage, opts.Images, opts.Model, opts.Temperature, opts.MaxTokens)
+	if err != nil {
+		return "", err
+	}
+	body.model, body.GenerationConfig.Temperature = applyModelOverride(ctx, body.model, body.GenerationConfig.Temperature)
+
+	c.logger.Debug("creating streaming Gemini content",
+		slog.String("model", body.model),
+		slog.Int("user_message_len", len(userMessage)),
+	)
+
+	resp, err := c.postWithFallback(ctx, &body, "streamGenerateContent?alt=sse")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var content strings.Builder
+	finishReason := ""
+	var usage *geminiUsage
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+
+		var chunk geminiResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			continue
+		}
+
+		if text := chunk.text(); text != "" {
+			content.WriteString(text)
+			if onToken != nil {
+				onToken(text)
+			}
+		}
+		if reason := chunk.finishReason(); reason != "" {
+			finishReason = reason
+		}
+		// Every chunk reports the usage so far, so only the last one is recorded
+		if chunk.UsageMetadata != nil {
+			usage = chunk.UsageMetadata
+		}
+	}
+	if usage != nil {
+		recordGeminiUsage(ctx, body.model, *usage)
+	}
+	if err := scanner.Err(); err != nil {
+		if ctx.Err() != nil {
+			return content.String(), ctx.Err()
+		}
+		return content.String(), fmt.Errorf("Gemini streaming error: %w", err)
+	}
+
+	if err := c.checkFinishReason(body.model, finishReason); err != nil {
+		return content.String(), err
+	}
+
+	c.logger.Debug("streaming Gemini content successful",
+		slog.String("model", body.model),
+		slog.Int("response_len", content.Len()),
+	)
+
+	return content.String(), nil
+}
+
+func (c *geminiClient) newRequest(systemPrompt, userMessage string, images []Image, tier ModelTier, temperature float32, maxTokens int) (geminiRequest, error) {
+	parts, err := newGeminiUserParts(userMessage, images)
+	if err != nil {
+		return geminiRequest{}, err
+	}
+
+	body := geminiRequest{
+		model:            getModelName(tier, c.cfg.ModelMap),
+		Contents:         []geminiContent{{Role: "user", Parts: parts}},
+		GenerationConfig: geminiGenerationConfig{Temperature: temperature, MaxOutputTokens: max(maxTokens, 0)},
+	}
+	if systemPrompt != "" {
+		body.SystemInstruction = &geminiContent{Parts: []geminiPart{{Text: systemPrompt}}}
+	}
+	return body, nil
+}
+
+// newGeminiUserParts returns the user message as parts with the images first.
+// Inline data is the only image source that needs no upload, so images given as
+// remote URLs fail with ErrImagesNotSupported.
+func newGeminiUserParts(userMessage string, images []Image) ([]geminiPart, error) {
+	parts := make([]geminiPart, 0, len(images)+1)
+	for _, img := range images {
+		mediaType, data, ok := img.base64Data()
+		if !ok {
+			return nil, fmt.Errorf("%w: Gemini requires image data instead of a URL", ErrImagesNotSupported)
+		}
+		parts = append(parts, geminiPart{InlineData: &geminiInlineData{MimeType: mediaType, Data: data}})
+	}
+	parts = append(parts, geminiPart{Text: userMessage})
+
+	return parts, nil
+}
+
+// complete sends a non-streaming request and returns the answer text.
+func (c *geminiClient) complete(ctx context.Context, body geminiRequest) (string, error) {
+	c.logger.Debug("creating Gemini content",
+		slog.String("model", body.model),
+		slog.Float64("temperature", float64(body.GenerationConfig.Temperature)),
+		slog.Int("max_tokens", body.GenerationConfig.MaxOutputTokens),
+	)
+
+	resp, err := c.postWithFallback(ctx, &body, "generateContent")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var result geminiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode Gemini response: %w", err)
+	}
+	var usage geminiUsage
+	if result.UsageMetadata != nil {
+		usage = *result.UsageMetadata
+		recordGeminiUsage(ctx, body.model, usage)
+	}
+
+	if err := c.checkFinishReason(body.model, result.finishReason()); err != nil {
+		return "", err
+	}
+
+	content := result.text()
+	if content == "" {
+		return "", errors.New("empty response from Gemini")
+	}
+
+	c.logger.Debug("Gemini content successful",
+		slog.String("model", body.model),
+		slog.Int("response_len", len(content)),
+		slog.Int("prompt_tokens", usage.PromptTokenCount),
+		slog.Int("cached_tokens", usage.CachedContentTokenCount),
+		slog.Int("completion_tokens", usage.completionTokens()),
+	)
+
+	return content, nil
+}
+
+// checkFinishReason turns finish reasons that mean the answer is unusable into errors.
+func (c *geminiClient) checkFinishReason(model, finishReason string) error {
+	switch finishReason {
+	case "", "STOP", "FINISH_REASON_UNSPECIFIED":
+		return nil
+	case "MAX_TOKENS":
+		c.logger.Warn("Gemini response truncated at max tokens", slog.String("model", model))
+		return nil
+	default:
+		// SAFETY, RECITATION, BLOCKLIST, PROHIBITED_CONTENT and the like
+		return fmt.Errorf("Gemini response blocked: %s", finishReason)
+	}
+}
+
+// postWithFallback posts body to the given method, retrying with the configured
+// fallback models. On return body.model is the model that served the request.
+func (c *geminiClient) postWithFallback(ctx context.Context, body *geminiRequest, method string) (*http.Response, error) {
+	resp, model, err := withModelFallback(ctx, body.model, c.cfg.ModelFallbacks, isGeminiFallbackError, c.logger,
+		func(model string) (*http.Response, error) {
+			body.model = model
+			return c.post(ctx, *body, method)
+		},
+	)
+	return resp, asContextLengthError(err, model)
+}
+
+func (c *geminiClient) post(ctx context.Context, body geminiRequest, method string) (*http.Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal Gemini request: %w", err)
+	}
+
+	endpoint := c.cfg.BaseURL + "/models/" + url.PathEscape(strings.TrimPrefix(body.model, "models/")) + ":" + method
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("x-goog-api-key", c.cfg.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Gemini API error: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, readGeminiError(resp)
+	}
+
+	return resp, nil
+}
+
+// readGeminiError builds a GeminiError from a non-200 response.
+func readGeminiError(resp *http.Response) error {
+	apiErr := &GeminiError{StatusCode: resp.StatusCode, Message: resp.Status}
+
+	// Errors are sent as a one-element array in streams
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	data = bytes.TrimPrefix(bytes.TrimSuffix(bytes.TrimSpace(data), []byte("]")), []byte("["))
+
+	var body struct {
+		Error geminiErrorDetail `json:"error"`
+	}
+	if json.Unmarshal(data, &body) == nil && body.Error.Message != "" {
+		apiErr.Status = body.Error.Status
+		apiErr.Message = body.Error.Message
+	}
+
+	return apiErr
+}
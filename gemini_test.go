@@ -0,0 +1,178 @@
+package aichat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGeminiChat(t *testing.T) {
+	var got struct {
+		path, apiKey string
+		body         geminiRequest
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.path = r.URL.Path
+		got.apiKey = r.Header.Get("x-goog-api-key")
+		if err := json.NewDecoder(r.Body).Decode(&got.body); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		w.Write([]byte(`{
+			"candidates": [{
+				"content": {"role": "model", "parts": [{"text": "Counting the settings", "thought": true}, {"text": "The Widget Pro has three speeds."}]},
+				"finishReason": "STOP"
+			}],
+			"usageMetadata": {"promptTokenCount": 120, "candidatesTokenCount": 8, "thoughtsTokenCount": 30, "cachedContentTokenCount": 100, "totalTokenCount": 158}
+		}`))
+	}))
+	defer server.Close()
+
+	client := newTestGeminiClient(server.URL)
+	ctx, lastCall := withLLMCallRecord(context.Background())
+	content, err := client.Chat(ctx, "You are a product expert.", "How many speeds?", &ChatOptions{
+		Model:       ModelReasoning,
+		Temperature: 1.5,
+		Images:      []Image{{URL: "data:image/png;base64,aGVsbG8="}},
+	})
+	if err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	if content != "The Widget Pro has three speeds." {
+		t.Errorf("Chat() = %q, want the answer without the thought", content)
+	}
+
+	if got.path != "/models/gemini-2.5-pro:generateContent" || got.apiKey != "test-key" {
+		t.Errorf("request to %s with key %q", got.path, got.apiKey)
+	}
+	body := got.body
+	if body.SystemInstruction == nil || len(body.SystemInstruction.Parts) != 1 || body.SystemInstruction.Parts[0].Text != "You are a product expert." {
+		t.Errorf("systemInstruction = %+v", body.SystemInstruction)
+	}
+	if len(body.Contents) != 1 || body.Contents[0].Role != "user" || len(body.Contents[0].Parts) != 2 ||
+		body.Contents[0].Parts[0].InlineData == nil || body.Contents[0].Parts[0].InlineData.MimeType != "image/png" ||
+		body.Contents[0].Parts[1].Text != "How many speeds?" {
+		t.Errorf("contents = %+v, want the image followed by the text", body.Contents)
+	}
+	// Gemini accepts temperatures up to 2
+	if body.GenerationConfig.Temperature != 1.5 || body.GenerationConfig.ResponseMimeType != "" {
+		t.Errorf("generationConfig = %+v", body.GenerationConfig)
+	}
+
+	want := TokenUsage{PromptTokens: 120, CompletionTokens: 38, TotalTokens: 158, CachedTokens: 100}
+	if record := lastCall(); record.Usage != want || record.Model != "gemini-2.5-pro" {
+		t.Errorf("recorded usage %+v of %s, want %+v", record.Usage, record.Model, want)
+	}
+
+	_, err = client.Chat(context.Background(), "", "What is this?", &ChatOptions{Images: []Image{{URL: "https://example.com/widget.png"}}})
+	if !errors.Is(err, ErrImagesNotSupported) {
+		t.Errorf("Chat(image URL) error = %v, want ErrImagesNotSupported", err)
+	}
+}
+
+func TestGeminiChatJSON(t *testing.T) {
+	var responseMimeType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body geminiRequest
+		json.NewDecoder(r.Body).Decode(&body)
+		responseMimeType = body.GenerationConfig.ResponseMimeType
+		w.Write([]byte(`{"candidates": [{"content": {"parts": [{"text": "{\"expert\": \"product\"}"}]}, "finishReason": "STOP"}]}`))
+	}))
+	defer server.Close()
+
+	var result struct {
+		Expert string `json:"expert"`
+	}
+	if err := newTestGeminiClient(server.URL).ChatJSON(context.Background(), "Route the question.", "How many speeds?", nil, &result); err != nil {
+		t.Fatalf("ChatJSON() error = %v", err)
+	}
+	if result.Expert != "product" || responseMimeType != "application/json" {
+		t.Errorf("ChatJSON() = %+v with responseMimeType %q", result, responseMimeType)
+	}
+}
+
+func TestGeminiChatStream(t *testing.T) {
+	chunks := []string{
+		`{"candidates": [{"content": {"role": "model", "parts": [{"text": "Three "}]}}], "usageMetadata": {"promptTokenCount": 12, "candidatesTokenCount": 1}}`,
+		`{"candidates": [{"content": {"role": "model", "parts": [{"text": "speeds."}]}, "finishReason": "STOP"}], "usageMetadata": {"promptTokenCount": 12, "candidatesTokenCount": 3}}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models/gemini-2.5-flash:streamGenerateContent" || r.URL.Query().Get("alt") != "sse" {
+			t.Errorf("request to %s, want a server-sent event stream", r.URL)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range chunks {
+			fmt.Fprintf(w, "data: %s\r\n\r\n", chunk)
+		}
+	}))
+	defer server.Close()
+
+	client := newTestGeminiClient(server.URL)
+	ctx, lastCall := withLLMCallRecord(context.Background())
+	var tokens []string
+	content, err := client.ChatStream(ctx, "", "How many speeds?", nil, func(token string) {
+		tokens = append(tokens, token)
+	})
+	if err != nil {
+		t.Fatalf("ChatStream() error = %v", err)
+	}
+	if content != "Three speeds." || len(tokens) != 2 {
+		t.Errorf("ChatStream() = %q with tokens %q", content, tokens)
+	}
+	// Usage is cumulative, so only the last chunk counts
+	if want := (TokenUsage{PromptTokens: 12, CompletionTokens: 3, TotalTokens: 15}); lastCall().Usage != want {
+		t.Errorf("recorded usage %+v, want %+v", lastCall().Usage, want)
+	}
+
+	chunks = []string{`{"candidates": [{"content": {"parts": [{"text": "Three "}]}, "finishReason": "SAFETY"}]}`}
+	if _, err := client.ChatStream(context.Background(), "", "How many speeds?", nil, nil); err == nil {
+		t.Error("ChatStream() succeeded, want an error for a blocked response")
+	}
+}
+
+func TestGeminiErrors(t *testing.T) {
+	var models []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		models = append(models, r.URL.Path)
+		if r.URL.Path == "/models/gemini-2.5-flash:generateContent" {
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error": {"code": 429, "message": "Resource has been exhausted", "status": "RESOURCE_EXHAUSTED"}}`))
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": {"code": 400, "message": "The input token count (1200000) exceeds the maximum number of tokens allowed (1048576).", "status": "INVALID_ARGUMENT"}}`))
+	}))
+	defer server.Close()
+
+	client := NewGeminiClient(GeminiConfig{
+		APIKey:         "test-key",
+		BaseURL:        server.URL,
+		ModelFallbacks: []string{"gemini-2.5-pro"},
+		Logger:         slog.New(slog.DiscardHandler),
+	})
+	_, err := client.Chat(context.Background(), "", "A very long question", &ChatOptions{Model: ModelMini})
+
+	if len(models) != 2 {
+		t.Errorf("called %v, want the fallback model after the rate limit", models)
+	}
+	var lengthErr *ContextLengthError
+	if !errors.As(err, &lengthErr) || lengthErr.Model != "gemini-2.5-pro" || lengthErr.PromptTokens != 1_200_000 || lengthErr.Limit != 1_048_576 {
+		t.Fatalf("Chat() error = %v, want a ContextLengthError with the reported sizes", err)
+	}
+	var apiErr *GeminiError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest || apiErr.Status != "INVALID_ARGUMENT" {
+		t.Errorf("Chat() error = %v, want the Gemini error", err)
+	}
+}
+
+func newTestGeminiClient(baseURL string) LLMClient {
+	return NewGeminiClient(GeminiConfig{
+		APIKey:  "test-key",
+		BaseURL: baseURL,
+		Logger:  slog.New(slog.DiscardHandler),
+	})
+}
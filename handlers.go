@@ -2,9 +2,13 @@ package aichat
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -15,6 +19,7 @@ import (
 // HealthResponse represents the health check response.
 type HealthResponse struct {
 	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
 }
 
 // newHealthHandler returns a handler for health check requests.
@@ -26,6 +31,22 @@ func newHealthHandler() http.HandlerFunc {
 	}
 }
 
+// newReadinessHandler returns a handler for readiness checks that verifies the LLM provider is reachable.
+func newReadinessHandler(checkHealth HealthCheckFn, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := checkHealth(r.Context()); err != nil {
+			logger.Warn("readiness check failed", "error", err)
+			respondJSON(w, http.StatusServiceUnavailable, HealthResponse{
+				Status: "unavailable",
+				Error:  "LLM provider unreachable",
+			})
+			return
+		}
+
+		respondJSON(w, http.StatusOK, HealthResponse{Status: "ready"})
+	}
+}
+
 // newChatHandler returns a handler for POST /chat requests.
 func newChatHandler(processChat ProcessChatFn, maxMessageLength int, logger *slog.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -37,35 +58,28 @@ func newChatHandler(processChat ProcessChatFn, maxMessageLength int, logger *slo
 		}
 
 		// 2. Validate
-		if httpReq.Message == "" {
-			respondError(w, http.StatusBadRequest, "Message cannot be empty")
-			return
-		}
-
-		if len(httpReq.Message) > maxMessageLength {
-			respondError(w, http.StatusRequestEntityTooLarge,
-				fmt.Sprintf("Message exceeds maximum length of %d characters", maxMessageLength))
+		if status, message := validateChatRequest(httpReq, maxMessageLength); status != 0 {
+			respondError(w, status, message)
 			return
 		}
 
 		// 3. Convert to service request
-		serviceReq := ChatRequest{
-			Message:        httpReq.Message,
-			ConversationID: stringValue(httpReq.ConversationID),
-			EntityID:       stringValue(httpReq.EntityID),
-			Data:           httpReq.Data,
-		}
+		serviceReq := toChatRequest(httpReq)
 
 		// 4. Call service (business logic)
 		result, err := processChat(r.Context(), serviceReq)
 		if err != nil {
 			logger.Error("failed to process chat message", "error", err)
-			respondError(w, http.StatusInternalServerError, "An error occurred while processing your message")
+			status, code, message := classifyChatError(err)
+			respondErrorCode(w, status, code, message)
 			return
 		}
 
 		// 5. Build HTTP response
-		response := buildChatResponse(result, httpReq.Message)
+		if result.TokensRemaining != nil {
+			w.Header().Set("X-Conversation-Tokens-Remaining", strconv.Itoa(*result.TokensRemaining))
+		}
+		response := buildChatResponse(result, httpReq.Message, httpReq.Trace)
 		respondJSON(w, http.StatusOK, response)
 	}
 }
@@ -76,19 +90,13 @@ func newChatStreamHandler(processChatStream ProcessChatStreamFn, maxMessageLengt
 		// 1. Parse request
 		var httpReq HTTPChatRequest
 		if err := json.NewDecoder(r.Body).Decode(&httpReq); err != nil {
-			sendStreamEvent(w, errorStreamEvent("Invalid request body"), logger)
+			respondStreamError(w, http.StatusBadRequest, "Invalid request body", logger)
 			return
 		}
 
 		// 2. Validate
-		if httpReq.Message == "" {
-			sendStreamEvent(w, errorStreamEvent("Message cannot be empty"), logger)
-			return
-		}
-
-		if len(httpReq.Message) > maxMessageLength {
-			sendStreamEvent(w, errorStreamEvent(
-				fmt.Sprintf("Message exceeds maximum length of %d characters", maxMessageLength)), logger)
+		if status, message := validateChatRequest(httpReq, maxMessageLength); status != 0 {
+			respondStreamError(w, status, message, logger)
 			return
 		}
 
@@ -96,12 +104,7 @@ func newChatStreamHandler(processChatStream ProcessChatStreamFn, maxMessageLengt
 		setSSEHeaders(w)
 
 		// 4. Convert to service request
-		serviceReq := ChatRequest{
-			Message:        httpReq.Message,
-			ConversationID: stringValue(httpReq.ConversationID),
-			EntityID:       stringValue(httpReq.EntityID),
-			Data:           httpReq.Data,
-		}
+		serviceReq := toChatRequest(httpReq)
 
 		// 5. Send "thinking" event immediately
 		sendStreamEvent(w, StreamEvent{
@@ -117,44 +120,463 @@ func newChatStreamHandler(processChatStream ProcessChatStreamFn, maxMessageLengt
 		result, err := processChatStream(r.Context(), serviceReq, streamCallback)
 		if err != nil {
 			logger.Error("failed to process chat message", "error", err)
-			sendStreamEvent(w, errorStreamEvent("An error occurred while processing your message"), logger)
+			_, code, message := classifyChatError(err)
+			sendStreamEvent(w, errorStreamEvent(code, message), logger)
 			return
 		}
 
 		// 8. Send "done" event
-		sendStreamEvent(w, buildDoneStreamEvent(result), logger)
+		sendStreamEvent(w, buildDoneStreamEvent(result, httpReq.Trace), logger)
 	}
 }
 
-func buildChatResponse(result *ChatResult, message string) HTTPChatResponse {
-	return HTTPChatResponse{
+// newChatBatchHandler returns a handler for POST /chat/batch requests.
+// Items are processed concurrently by at most concurrency workers and results are
+// returned in request order; a failing item yields an error entry instead of failing the batch.
+func newChatBatchHandler(
+	processChat ProcessChatFn,
+	maxMessageLength int,
+	maxBatchSize int,
+	concurrency int,
+	logger *slog.Logger,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// 1. Parse request
+		var httpReqs []HTTPChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&httpReqs); err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		// 2. Validate batch
+		if len(httpReqs) == 0 {
+			respondError(w, http.StatusBadRequest, "Batch cannot be empty")
+			return
+		}
+
+		if len(httpReqs) > maxBatchSize {
+			respondError(w, http.StatusRequestEntityTooLarge,
+				fmt.Sprintf("Batch exceeds maximum size of %d requests", maxBatchSize))
+			return
+		}
+
+		// 3. Process items with a bounded worker pool
+		results := make([]HTTPChatBatchResult, len(httpReqs))
+		sem := make(chan struct{}, concurrency)
+		var wg sync.WaitGroup
+
+		for i, httpReq := range httpReqs {
+			if status, message := validateChatRequest(httpReq, maxMessageLength); status != 0 {
+				results[i] = HTTPChatBatchResult{Status: status, Error: message, Code: errorCodeForStatus(status)}
+				continue
+			}
+
+			wg.Add(1)
+			go func(i int, httpReq HTTPChatRequest) {
+				defer wg.Done()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+
+				result, err := processChat(r.Context(), toChatRequest(httpReq))
+				if err != nil {
+					logger.Error("failed to process batch chat message", "error", err, "index", i)
+					status, code, message := classifyChatError(err)
+					results[i] = HTTPChatBatchResult{Status: status, Error: message, Code: code}
+					return
+				}
+
+				response := buildChatResponse(result, httpReq.Message, httpReq.Trace)
+				results[i] = HTTPChatBatchResult{Status: http.StatusOK, Response: &response}
+			}(i, httpReq)
+		}
+
+		wg.Wait()
+
+		// 4. Build HTTP response
+		respondJSON(w, http.StatusOK, results)
+	}
+}
+
+// newExportHandler returns a handler for GET /conversations/{id}/export requests.
+func newExportHandler(exportConversation ExportConversationFn, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+		format := ExportFormat(r.URL.Query().Get("format"))
+
+		data, err := exportConversation(r.Context(), id, format)
+		if err != nil {
+			switch {
+			case errors.Is(err, ErrConversationNotFound):
+				respondError(w, http.StatusNotFound, "Conversation not found")
+			case errors.Is(err, ErrInvalidInput):
+				respondError(w, http.StatusBadRequest, "Unsupported export format")
+			default:
+				logger.Error("failed to export conversation", "error", err, "conversation_id", id)
+				respondError(w, http.StatusInternalServerError, "An error occurred while exporting the conversation")
+			}
+			return
+		}
+
+		contentType := "application/json"
+		if format == ExportFormatJSONL {
+			contentType = "application/x-ndjson"
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	}
+}
+
+// validateChatRequest returns an HTTP status and error message for an invalid request, or 0 if it is valid.
+func validateChatRequest(httpReq HTTPChatRequest, maxMessageLength int) (int, string) {
+	if httpReq.Message == "" {
+		return http.StatusBadRequest, "Message cannot be empty"
+	}
+
+	if len(httpReq.Message) > maxMessageLength {
+		return http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("Message exceeds maximum length of %d characters", maxMessageLength)
+	}
+
+	if len(httpReq.Images) > maxImagesPerMessage {
+		return http.StatusBadRequest,
+			fmt.Sprintf("A message can include at most %d images", maxImagesPerMessage)
+	}
+
+	for _, image := range httpReq.Images {
+		if err := image.validate(); err != nil {
+			return http.StatusBadRequest, err.Error()
+		}
+	}
+
+	return 0, ""
+}
+
+func toChatRequest(httpReq HTTPChatRequest) ChatRequest {
+	return ChatRequest{
+		Message:        httpReq.Message,
+		ConversationID: stringValue(httpReq.ConversationID),
+		EntityID:       stringValue(httpReq.EntityID),
+		Data:           httpReq.Data,
+		Images:         httpReq.Images,
+		Metadata:       httpReq.Metadata,
+		Tags:           httpReq.Tags,
+		Seed:           httpReq.Seed,
+		Model:          httpReq.Model,
+		Temperature:    httpReq.Temperature,
+		Persona:        httpReq.Persona,
+		Ephemeral:      httpReq.Ephemeral,
+	}
+}
+
+// newSummaryHandler returns a handler for GET /conversations/{id}/summary requests.
+func newSummaryHandler(summarizeConversation SummarizeConversationFn, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+
+		summary, err := summarizeConversation(r.Context(), id)
+		if err != nil {
+			if errors.Is(err, ErrConversationNotFound) {
+				respondError(w, http.StatusNotFound, "Conversation not found")
+				return
+			}
+			logger.Error("failed to summarize conversation", "error", err, "conversation_id", id)
+			status, code, message := classifyChatError(err)
+			if status == http.StatusInternalServerError {
+				message = "An error occurred while summarizing the conversation"
+			}
+			respondErrorCode(w, status, code, message)
+			return
+		}
+
+		respondJSON(w, http.StatusOK, HTTPConversationSummaryResponse{
+			ConversationID: id,
+			Summary:        summary,
+		})
+	}
+}
+
+// newUsageHandler returns a handler for GET /conversations/{id}/usage requests.
+func newUsageHandler(conversationUsage ConversationUsageFn, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+
+		report, err := conversationUsage(r.Context(), id)
+		if err != nil {
+			if errors.Is(err, ErrConversationNotFound) {
+				respondError(w, http.StatusNotFound, "Conversation not found")
+				return
+			}
+			logger.Error("failed to report conversation usage", "error", err, "conversation_id", id)
+			respondError(w, http.StatusInternalServerError, "An error occurred while reading the conversation")
+			return
+		}
+
+		respondJSON(w, http.StatusOK, report)
+	}
+}
+
+// newUpdateConversationHandler returns a handler for PATCH /conversations/{id} requests.
+func newUpdateConversationHandler(updateConversation UpdateConversationFn, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+
+		var update ConversationUpdate
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		conversation, err := updateConversation(r.Context(), id, update)
+		if err != nil {
+			if errors.Is(err, ErrConversationNotFound) {
+				respondError(w, http.StatusNotFound, "Conversation not found")
+				return
+			}
+			logger.Error("failed to update conversation", "error", err, "conversation_id", id)
+			respondError(w, http.StatusInternalServerError, "An error occurred while updating the conversation")
+			return
+		}
+
+		respondJSON(w, http.StatusOK, HTTPConversationResponse{
+			ID:        conversation.ID,
+			CreatedAt: conversation.CreatedAt,
+			EntityID:  conversation.EntityID,
+			Metadata:  conversation.Metadata,
+			Tags:      conversation.Tags,
+		})
+	}
+}
+
+// newRegenerateHandler returns a handler for POST /conversations/{id}/regenerate requests.
+func newRegenerateHandler(processChat ProcessChatFn, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+
+		// The body is optional
+		var httpReq HTTPRegenerateRequest
+		if err := json.NewDecoder(r.Body).Decode(&httpReq); err != nil && !errors.Is(err, io.EOF) {
+			respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		regen := &regeneration{}
+		req := newRegenerateRequest(id, RegenerateOptions{
+			Seed:        httpReq.Seed,
+			Model:       httpReq.Model,
+			Temperature: httpReq.Temperature,
+			Persona:     httpReq.Persona,
+		})
+		result, err := processChat(withRegenerationRequest(r.Context(), regen), req)
+		if err != nil {
+			if errors.Is(err, ErrNothingToRegenerate) {
+				respondError(w, http.StatusBadRequest, "The last message is not an assistant response")
+				return
+			}
+			logger.Error("failed to regenerate response", "error", err, "conversation_id", id)
+			status, code, message := classifyChatError(err)
+			respondErrorCode(w, status, code, message)
+			return
+		}
+
+		if result.TokensRemaining != nil {
+			w.Header().Set("X-Conversation-Tokens-Remaining", strconv.Itoa(*result.TokensRemaining))
+		}
+		respondJSON(w, http.StatusOK, buildChatResponse(result, regen.Message, httpReq.Trace))
+	}
+}
+
+// newForkConversationHandler returns a handler for POST /conversations/{id}/fork requests.
+func newForkConversationHandler(forkConversation ForkConversationFn, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+
+		var req HTTPForkConversationRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.FromMessageID == "" {
+			respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		forkID, err := forkConversation(r.Context(), id, req.FromMessageID)
+		if err != nil {
+			switch {
+			case errors.Is(err, ErrConversationNotFound):
+				respondError(w, http.StatusNotFound, "Conversation not found")
+			case errors.Is(err, ErrMessageNotFound):
+				respondError(w, http.StatusNotFound, "Message not found")
+			default:
+				logger.Error("failed to fork conversation", "error", err, "conversation_id", id)
+				respondError(w, http.StatusInternalServerError, "An error occurred while forking the conversation")
+			}
+			return
+		}
+
+		respondJSON(w, http.StatusCreated, HTTPForkConversationResponse{ConversationID: forkID})
+	}
+}
+
+// newTruncateConversationHandler returns a handler for POST /conversations/{id}/truncate requests.
+func newTruncateConversationHandler(truncateConversation TruncateConversationFn, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+
+		var req HTTPTruncateConversationRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.AfterMessageID == "" {
+			respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		conversation, err := truncateConversation(r.Context(), id, req.AfterMessageID)
+		if err != nil {
+			switch {
+			case errors.Is(err, ErrConversationNotFound):
+				respondError(w, http.StatusNotFound, "Conversation not found")
+			case errors.Is(err, ErrMessageNotFound):
+				respondError(w, http.StatusNotFound, "Message not found")
+			default:
+				logger.Error("failed to truncate conversation", "error", err, "conversation_id", id)
+				status, code, message := classifyChatError(err)
+				if status == http.StatusInternalServerError {
+					message = "An error occurred while truncating the conversation"
+				}
+				respondErrorCode(w, status, code, message)
+			}
+			return
+		}
+
+		respondJSON(w, http.StatusOK, HTTPTruncateConversationResponse{
+			ConversationID: conversation.ID,
+			MessageCount:   len(conversation.Messages),
+		})
+	}
+}
+
+// newSubmitFeedbackHandler returns a handler for POST /feedback requests.
+func newSubmitFeedbackHandler(submitFeedback SubmitFeedbackFn, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req Feedback
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		feedback, err := submitFeedback(r.Context(), req)
+		if err != nil {
+			switch {
+			case errors.Is(err, ErrConversationNotFound):
+				respondError(w, http.StatusNotFound, "Conversation not found")
+			case errors.Is(err, ErrMessageNotFound):
+				respondError(w, http.StatusNotFound, "Message not found")
+			case errors.Is(err, ErrInvalidInput):
+				respondError(w, http.StatusBadRequest, err.Error())
+			case errors.Is(err, ErrNotSupported):
+				respondError(w, http.StatusNotImplemented, "Feedback is not supported by the configured store")
+			default:
+				logger.Error("failed to submit feedback", "error", err, "message_id", req.MessageID)
+				respondError(w, http.StatusInternalServerError, "An error occurred while saving feedback")
+			}
+			return
+		}
+
+		respondJSON(w, http.StatusCreated, feedback)
+	}
+}
+
+// newGetFeedbackHandler returns a handler for GET /feedback requests.
+// ?messageId= returns the feedback on one message; ?conversationId= returns all feedback in a conversation.
+func newGetFeedbackHandler(store ConversationStore, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if store.GetFeedback == nil || store.ListFeedback == nil {
+			respondError(w, http.StatusNotImplemented, "Feedback is not supported by the configured store")
+			return
+		}
+
+		query := r.URL.Query()
+		messageID := query.Get("messageId")
+		conversationID := query.Get("conversationId")
+
+		switch {
+		case messageID != "":
+			feedback, err := store.GetFeedback(r.Context(), messageID)
+			if err != nil {
+				if errors.Is(err, ErrFeedbackNotFound) {
+					respondError(w, http.StatusNotFound, "Feedback not found")
+					return
+				}
+				logger.Error("failed to get feedback", "error", err, "message_id", messageID)
+				respondError(w, http.StatusInternalServerError, "An error occurred while reading feedback")
+				return
+			}
+			respondJSON(w, http.StatusOK, feedback)
+
+		case conversationID != "":
+			feedback, err := store.ListFeedback(r.Context(), conversationID)
+			if err != nil {
+				if errors.Is(err, ErrConversationNotFound) {
+					respondError(w, http.StatusNotFound, "Conversation not found")
+					return
+				}
+				logger.Error("failed to list feedback", "error", err, "conversation_id", conversationID)
+				respondError(w, http.StatusInternalServerError, "An error occurred while reading feedback")
+				return
+			}
+			respondJSON(w, http.StatusOK, feedback)
+
+		default:
+			respondError(w, http.StatusBadRequest, "messageId or conversationId is required")
+		}
+	}
+}
+
+// buildChatResponse converts a chat result to the HTTP response, with the trace if includeTrace is set.
+func buildChatResponse(result *ChatResult, message string, includeTrace bool) HTTPChatResponse {
+	response := HTTPChatResponse{
 		ConversationID: result.ConversationID,
+		MessageID:      result.MessageID,
 		Expert:         result.ExpertResult.ExpertType,
 		ExpertName:     result.ExpertResult.ExpertName,
 		Message:        message,
 		Reasoning:      result.ExpertResult.Reasoning,
 		Response:       result.ExpertResult.Answer,
 		Data:           result.ExpertResult.Details,
+		Cached:         result.Cached,
+		Blocked:        result.Blocked,
+
+		SuggestedFollowups: result.SuggestedFollowups,
 	}
+	if includeTrace {
+		response.Trace = result.Trace
+	}
+	return response
 }
 
-func buildDoneStreamEvent(result *ChatResult) StreamEvent {
+// buildDoneStreamEvent converts a chat result to the done event, with the trace if includeTrace is set.
+func buildDoneStreamEvent(result *ChatResult, includeTrace bool) StreamEvent {
 	expertType := result.ExpertResult.ExpertType
-	return StreamEvent{
+	event := StreamEvent{
 		Type:           EventDone,
 		ConversationID: &result.ConversationID,
 		Expert:         &expertType,
 		ExpertName:     &result.ExpertResult.ExpertName,
 		Content:        &result.ExpertResult.Answer,
 		Data:           result.ExpertResult.Details,
+
+		TokensRemaining:    result.TokensRemaining,
+		SuggestedFollowups: result.SuggestedFollowups,
+	}
+	if result.MessageID != "" {
+		event.MessageID = &result.MessageID
+	}
+	if includeTrace {
+		event.Trace = result.Trace
 	}
+	return event
 }
 
 func setSSEHeaders(w http.ResponseWriter) {
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
 }
 
 func sendStreamEvent(w http.ResponseWriter, event StreamEvent, logger *slog.Logger) {
@@ -169,13 +591,22 @@ func sendStreamEvent(w http.ResponseWriter, event StreamEvent, logger *slog.Logg
 	flush(w)
 }
 
-func errorStreamEvent(message string) StreamEvent {
+func errorStreamEvent(code ErrorCode, message string) StreamEvent {
 	return StreamEvent{
 		Type:    EventError,
 		Content: stringPtr(message),
+		Code:    code,
 	}
 }
 
+// respondStreamError rejects a streaming request before the stream starts,
+// sending the error event with an HTTP status that reflects the failure.
+func respondStreamError(w http.ResponseWriter, status int, message string, logger *slog.Logger) {
+	setSSEHeaders(w)
+	w.WriteHeader(status)
+	sendStreamEvent(w, errorStreamEvent(errorCodeForStatus(status), message), logger)
+}
+
 func flush(w http.ResponseWriter) {
 	if flusher, ok := w.(http.Flusher); ok {
 		flusher.Flush()
@@ -200,19 +631,38 @@ func respondJSON(w http.ResponseWriter, status int, data any) {
 }
 
 func respondError(w http.ResponseWriter, status int, message string) {
-	respondJSON(w, status, map[string]string{"error": message})
+	respondErrorCode(w, status, errorCodeForStatus(status), message)
+}
+
+func respondErrorCode(w http.ResponseWriter, status int, code ErrorCode, message string) {
+	respondJSON(w, status, ErrorResponse{Error: message, Code: code})
 }
 
 // newHTTPRouter creates and configures the Chi router with all middleware and routes.
+// It also returns the router for authenticated routes, where custom routes are registered.
 func newHTTPRouter(
-	allowedOrigins []string,
+	corsOptions cors.Options,
 	requestTimeout time.Duration,
+	batchTimeout time.Duration,
 	maxRequestBodySize int64,
 	logger *slog.Logger,
+	middleware []func(http.Handler) http.Handler,
+	authenticate AuthenticateFn,
 	healthHandler http.HandlerFunc,
+	readinessHandler http.HandlerFunc,
 	chatHandler http.HandlerFunc,
 	chatStreamHandler http.HandlerFunc,
-) *chi.Mux {
+	chatBatchHandler http.HandlerFunc,
+	exportHandler http.HandlerFunc,
+	summaryHandler http.HandlerFunc,
+	usageHandler http.HandlerFunc,
+	updateConversationHandler http.HandlerFunc,
+	regenerateHandler http.HandlerFunc,
+	forkConversationHandler http.HandlerFunc,
+	truncateConversationHandler http.HandlerFunc,
+	submitFeedbackHandler http.HandlerFunc,
+	getFeedbackHandler http.HandlerFunc,
+) (*chi.Mux, chi.Router) {
 	r := chi.NewRouter()
 
 	// Middleware stack
@@ -220,22 +670,39 @@ func newHTTPRouter(
 	r.Use(recoveryMiddleware(logger))
 	r.Use(loggingMiddleware(logger))
 	r.Use(chimiddleware.RealIP)
-	r.Use(timeoutMiddleware(requestTimeout))
+	r.Use(timeoutMiddleware(requestTimeout, map[string]time.Duration{
+		http.MethodPost + " /chat/batch": batchTimeout,
+	}))
 	r.Use(bodySizeLimitMiddleware(maxRequestBodySize))
 
 	// CORS middleware
-	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   allowedOrigins,
-		AllowedMethods:   []string{"GET", "POST", "OPTIONS"},
-		AllowedHeaders:   []string{"Content-Type", "Authorization"},
-		AllowCredentials: true,
-		MaxAge:           300, // 5 minutes
-	}))
+	r.Use(cors.Handler(corsOptions))
+
+	// Application middleware
+	r.Use(middleware...)
 
-	// Routes
+	// Routes; health checks stay unauthenticated for load balancers and probes
 	r.Get("/health", healthHandler)
-	r.Post("/chat", chatHandler)
-	r.Post("/chat/stream", chatStreamHandler)
+	r.Get("/health/ready", readinessHandler)
+
+	authenticated := r.Group(func(r chi.Router) {
+		if authenticate != nil {
+			r.Use(authMiddleware(authenticate, logger))
+		}
 
-	return r
+		r.Post("/chat", chatHandler)
+		r.Post("/chat/stream", chatStreamHandler)
+		r.Post("/chat/batch", chatBatchHandler)
+		r.Get("/conversations/{id}/export", exportHandler)
+		r.Get("/conversations/{id}/summary", summaryHandler)
+		r.Get("/conversations/{id}/usage", usageHandler)
+		r.Patch("/conversations/{id}", updateConversationHandler)
+		r.Post("/conversations/{id}/regenerate", regenerateHandler)
+		r.Post("/conversations/{id}/fork", forkConversationHandler)
+		r.Post("/conversations/{id}/truncate", truncateConversationHandler)
+		r.Post("/feedback", submitFeedbackHandler)
+		r.Get("/feedback", getFeedbackHandler)
+	})
+
+	return r, authenticated
 }
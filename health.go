@@ -0,0 +1,55 @@
+package aichat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// HealthCheckFn performs a lightweight check that a dependency is reachable.
+type HealthCheckFn func(ctx context.Context) error
+
+// NewOpenAIHealthCheck creates a health check that lists models on the
+// OpenAI-compatible API, verifying both reachability and the API key.
+func NewOpenAIHealthCheck(client *openai.Client) HealthCheckFn {
+	return func(ctx context.Context) error {
+		if _, err := client.ListModels(ctx); err != nil {
+			return fmt.Errorf("LLM provider unreachable: %w", err)
+		}
+		return nil
+	}
+}
+
+// cachedHealthCheck wraps check so that results are reused for ttl and each
+// check is bounded by timeout, keeping frequent readiness probes cheap. Checks
+// run detached from the probe's cancellation, so a probe that disconnects doesn't
+// cache a failure for the next ones.
+func cachedHealthCheck(check HealthCheckFn, ttl, timeout time.Duration) HealthCheckFn {
+	var mu sync.Mutex
+	var lastErr error
+	var checkedAt time.Time
+
+	return func(ctx context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if !checkedAt.IsZero() && time.Since(checkedAt) < ttl {
+			return lastErr
+		}
+
+		checkCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+		defer cancel()
+
+		err := check(checkCtx)
+		if errors.Is(err, context.Canceled) {
+			return err
+		}
+		lastErr = err
+		checkedAt = time.Now()
+		return lastErr
+	}
+}
@@ -0,0 +1,44 @@
+package aichat
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCachedHealthCheckIgnoresProbeCancellation(t *testing.T) {
+	calls := 0
+	check := cachedHealthCheck(func(ctx context.Context) error {
+		calls++
+		return ctx.Err()
+	}, time.Minute, time.Second)
+
+	// A disconnected probe still gets a real answer, which is cached
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := check(ctx); err != nil {
+		t.Fatalf("check(cancelled ctx) error = %v, want nil", err)
+	}
+	if err := check(context.Background()); err != nil || calls != 1 {
+		t.Errorf("second check error = %v after %d calls, want a cached nil", err, calls)
+	}
+}
+
+func TestCachedHealthCheckDoesNotCacheCancellation(t *testing.T) {
+	calls := 0
+	check := cachedHealthCheck(func(ctx context.Context) error {
+		calls++
+		if calls == 1 {
+			return context.Canceled
+		}
+		return nil
+	}, time.Minute, time.Second)
+
+	if err := check(context.Background()); !errors.Is(err, context.Canceled) {
+		t.Fatalf("first check error = %v, want context.Canceled", err)
+	}
+	if err := check(context.Background()); err != nil || calls != 2 {
+		t.Errorf("second check error = %v after %d calls, want nil after a fresh check", err, calls)
+	}
+}
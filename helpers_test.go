@@ -0,0 +1,122 @@
+package aichat_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"testing"
+
+	aichat "github.com/ourstudio-se/ai-chat-sdk"
+	"github.com/ourstudio-se/ai-chat-sdk/aichattest"
+)
+
+// testExpert is the expert type of the SDKs created by newTestSDK.
+const testExpert aichat.ExpertType = "product"
+
+// newTestSDK creates an SDK backed by llm. Unless config sets Experts, it has a
+// single testExpert that answers with the next scripted Chat response. Messages
+// are detected as English, so a turn makes a routing call and the expert's calls;
+// see turn.
+func newTestSDK(t *testing.T, llm *aichattest.LLM, config aichat.Config) *aichat.SDK {
+	t.Helper()
+
+	var sdk *aichat.SDK
+	if config.Experts == nil {
+		config.Experts = map[aichat.ExpertType]aichat.Expert{
+			testExpert: {
+				Name:        "Product Expert",
+				Description: "Questions about products",
+				Handler: func(ctx context.Context, req aichat.ExpertRequest) (*aichat.ExpertResult, error) {
+					answer, err := sdk.LLMClient().Chat(ctx, "You answer product questions.", req.Message, nil)
+					if err != nil {
+						return nil, err
+					}
+					return &aichat.ExpertResult{Answer: answer}, nil
+				},
+			},
+		}
+	}
+	config.LLMClient = llm.Client()
+	config.DevMode = true
+	if config.Logger == nil {
+		config.Logger = slog.New(slog.DiscardHandler)
+	}
+	if config.LanguageDetector == nil {
+		config.LanguageDetector = func(text string) aichat.LanguageDetection {
+			return aichat.LanguageDetection{Language: "en", Confidence: 1}
+		}
+	}
+
+	sdk, err := aichat.New(config)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return sdk
+}
+
+// turn returns the scripted responses of a chat turn answered by testExpert.
+func turn(answer string) []aichattest.Response {
+	return []aichattest.Response{
+		aichattest.Route(testExpert, "Asks about a product"),
+		aichattest.Text(answer).ExpectMethod(aichattest.MethodChat),
+	}
+}
+
+// script concatenates scripted turns.
+func script(turns ...[]aichattest.Response) []aichattest.Response {
+	var responses []aichattest.Response
+	for _, t := range turns {
+		responses = append(responses, t...)
+	}
+	return responses
+}
+
+// serve sends a request with an optional JSON body and bearer token to the SDK's
+// HTTP handler and returns the recorded response.
+func serve(t *testing.T, sdk *aichat.SDK, method, path, token string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("failed to marshal request body: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	rec := httptest.NewRecorder()
+	sdk.HTTPHandler().ServeHTTP(rec, req)
+	return rec
+}
+
+// decode unmarshals a recorded JSON response into T.
+func decode[T any](t *testing.T, rec *httptest.ResponseRecorder) T {
+	t.Helper()
+
+	var v T
+	if err := json.Unmarshal(rec.Body.Bytes(), &v); err != nil {
+		t.Fatalf("failed to decode response %q: %v", rec.Body.String(), err)
+	}
+	return v
+}
+
+// mustChat processes a chat request and fails the test on error.
+func mustChat(t *testing.T, sdk *aichat.SDK, req aichat.ChatRequest) *aichat.ChatResult {
+	t.Helper()
+
+	result, err := sdk.ProcessChat()(context.Background(), req)
+	if err != nil {
+		t.Fatalf("ProcessChat(%q) error = %v", req.Message, err)
+	}
+	return result
+}
@@ -0,0 +1,113 @@
+// Package idgen provides ID generators for aichat.Config.IDGenerator.
+//
+//	IDGenerator: idgen.Prefixed(idgen.NewULIDGenerator(), "conv_", "msg_"),
+//
+// generates IDs such as "conv_01J9ZQ3V6N8X4M2K7B5C0D1E2F", which sort by creation time.
+package idgen
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	aichat "github.com/ourstudio-se/ai-chat-sdk"
+)
+
+// crockford is the Crockford base32 alphabet used by ULIDs.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULIDSource generates ULIDs: 26-character, lexicographically sortable IDs made of a
+// millisecond timestamp and 80 random bits. IDs from the same source within the
+// same millisecond increment the random part, so they stay strictly ordered.
+type ULIDSource struct {
+	mu       sync.Mutex
+	lastMs   uint64
+	lastHigh uint16 // Top 16 random bits
+	lastLow  uint64 // Bottom 64 random bits
+}
+
+// New returns a new ULID.
+func (s *ULIDSource) New() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ms := uint64(time.Now().UnixMilli())
+	if ms <= s.lastMs {
+		// Same millisecond, or the clock went backwards: keep the last timestamp and increment
+		ms = s.lastMs
+		s.lastLow++
+		if s.lastLow == 0 {
+			s.lastHigh++
+		}
+	} else {
+		var random [10]byte
+		if _, err := rand.Read(random[:]); err != nil {
+			panic("idgen: failed to read random bytes: " + err.Error())
+		}
+		s.lastHigh = binary.BigEndian.Uint16(random[:2])
+		s.lastLow = binary.BigEndian.Uint64(random[2:])
+	}
+	s.lastMs = ms
+
+	var id [16]byte
+	id[0] = byte(ms >> 40)
+	id[1] = byte(ms >> 32)
+	id[2] = byte(ms >> 24)
+	id[3] = byte(ms >> 16)
+	id[4] = byte(ms >> 8)
+	id[5] = byte(ms)
+	binary.BigEndian.PutUint16(id[6:8], s.lastHigh)
+	binary.BigEndian.PutUint64(id[8:], s.lastLow)
+
+	return encodeULID(id)
+}
+
+// encodeULID encodes 128 bits as 26 Crockford base32 characters.
+func encodeULID(id [16]byte) string {
+	hi := binary.BigEndian.Uint64(id[:8])
+	lo := binary.BigEndian.Uint64(id[8:])
+
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+// NewULIDGenerator returns a generator of ULIDs for conversations and messages.
+func NewULIDGenerator() aichat.IDGenerator {
+	source := &ULIDSource{}
+	return aichat.IDGenerator{
+		NewConversationID: source.New,
+		NewMessageID:      source.New,
+	}
+}
+
+// NewUUIDGenerator returns a generator of random UUIDs for conversations and messages,
+// the SDK's default format, e.g. for use with Prefixed.
+func NewUUIDGenerator() aichat.IDGenerator {
+	newUUID := func() string { return uuid.New().String() }
+	return aichat.IDGenerator{
+		NewConversationID: newUUID,
+		NewMessageID:      newUUID,
+	}
+}
+
+// Prefixed returns a generator that prefixes gen's conversation and message IDs,
+// e.g. with "conv_" and "msg_". Prefixes may only contain letters, digits, '-' and '_'.
+// Functions that are nil in gen stay nil.
+func Prefixed(gen aichat.IDGenerator, conversationPrefix, messagePrefix string) aichat.IDGenerator {
+	var prefixed aichat.IDGenerator
+	if next := gen.NewConversationID; next != nil {
+		prefixed.NewConversationID = func() string { return conversationPrefix + next() }
+	}
+	if next := gen.NewMessageID; next != nil {
+		prefixed.NewMessageID = func() string { return messagePrefix + next() }
+	}
+	return prefixed
+}
@@ -0,0 +1,88 @@
+package aichat
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// maxImagesPerMessage is the maximum number of images attached to one chat message.
+const maxImagesPerMessage = 4
+
+// supportedImageTypes are the media types accepted for base64-encoded images.
+var supportedImageTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp"}
+
+// Image is an image attached to a user message, given either as a URL or as base64 data.
+type Image struct {
+	// URL is an https URL or a data URL (data:image/png;base64,...).
+	URL string `json:"url,omitempty"`
+
+	// Data is the base64-encoded image, used when URL is empty.
+	Data string `json:"data,omitempty"`
+
+	// MediaType is the image type for Data, e.g. "image/png".
+	MediaType string `json:"mediaType,omitempty"`
+}
+
+// validate checks that the image is given in exactly one supported form.
+func (img Image) validate() error {
+	switch {
+	case img.URL != "" && img.Data != "":
+		return fmt.Errorf("%w: image must have either url or data, not both", ErrInvalidInput)
+	case img.URL != "":
+		if !strings.HasPrefix(img.URL, "https://") && !strings.HasPrefix(img.URL, "data:image/") {
+			return fmt.Errorf("%w: image url must be an https or data URL", ErrInvalidInput)
+		}
+	case img.Data != "":
+		if !slices.Contains(supportedImageTypes, img.MediaType) {
+			return fmt.Errorf("%w: image mediaType must be one of %s", ErrInvalidInput, strings.Join(supportedImageTypes, ", "))
+		}
+	default:
+		return fmt.Errorf("%w: image must have a url or data", ErrInvalidInput)
+	}
+	return nil
+}
+
+// withoutImages wraps a chat function to reject messages with images.
+func withoutImages(processChat ProcessChatFn) ProcessChatFn {
+	return func(ctx context.Context, req ChatRequest) (*ChatResult, error) {
+		if len(req.Images) > 0 {
+			return nil, ErrImagesNotSupported
+		}
+		return processChat(ctx, req)
+	}
+}
+
+// withoutImagesStreaming wraps a streaming chat function to reject messages with images.
+func withoutImagesStreaming(processChatStream ProcessChatStreamFn) ProcessChatStreamFn {
+	return func(ctx context.Context, req ChatRequest, stream StreamCallback) (*ChatResult, error) {
+		if len(req.Images) > 0 {
+			return nil, ErrImagesNotSupported
+		}
+		return processChatStream(ctx, req, stream)
+	}
+}
+
+// dataURL returns the image as a URL, encoding base64 data as a data URL.
+func (img Image) dataURL() string {
+	if img.URL != "" {
+		return img.URL
+	}
+	return "data:" + img.MediaType + ";base64," + img.Data
+}
+
+// base64Data returns the media type and base64 data for an image given as
+// base64 data or a data URL, and false for remote URLs.
+func (img Image) base64Data() (mediaType, data string, ok bool) {
+	if img.Data != "" {
+		return img.MediaType, img.Data, true
+	}
+
+	rest, ok := strings.CutPrefix(img.URL, "data:")
+	if !ok {
+		return "", "", false
+	}
+	mediaType, data, ok = strings.Cut(rest, ";base64,")
+	return mediaType, data, ok
+}
@@ -0,0 +1,27 @@
+package aichat_test
+
+import (
+	"net/http"
+	"testing"
+
+	aichat "github.com/ourstudio-se/ai-chat-sdk"
+	"github.com/ourstudio-se/ai-chat-sdk/aichattest"
+)
+
+func TestDisableImagesRejectsImagesBeforeAnyLLMCall(t *testing.T) {
+	llm := aichattest.NewLLM()
+	sdk := newTestSDK(t, llm, aichat.Config{DisableImages: true})
+
+	rec := serve(t, sdk, http.MethodPost, "/chat", "", map[string]any{
+		"message": "Is this part covered by the warranty?",
+		"images":  []map[string]string{{"url": "https://example.com/photos/part.jpg"}},
+	})
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("POST /chat status = %d, want 422 (body %s)", rec.Code, rec.Body)
+	}
+	if body := decode[aichat.ErrorResponse](t, rec); body.Code != aichat.CodeImagesNotSupported {
+		t.Errorf("error code = %q, want %q", body.Code, aichat.CodeImagesNotSupported)
+	}
+
+	llm.AssertDone(t)
+}
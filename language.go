@@ -0,0 +1,232 @@
+package aichat
+
+import (
+	"strings"
+	"unicode"
+)
+
+// LanguageDetection is the result of detecting the language of a text.
+type LanguageDetection struct {
+	// Language is a two-letter ISO 639-1 code, or empty if unknown.
+	Language string
+
+	// Confidence is between 0 and 1.
+	Confidence float64
+}
+
+// DetectLanguageFn detects the language of a text without calling an LLM.
+type DetectLanguageFn func(text string) LanguageDetection
+
+// scriptLanguages maps non-Latin scripts to the language they most likely indicate.
+var scriptLanguages = []struct {
+	table    *unicode.RangeTable
+	language string
+}{
+	{unicode.Hiragana, "ja"},
+	{unicode.Katakana, "ja"},
+	{unicode.Hangul, "ko"},
+	{unicode.Han, "zh"},
+	{unicode.Cyrillic, "ru"},
+	{unicode.Greek, "el"},
+	{unicode.Arabic, "ar"},
+	{unicode.Hebrew, "he"},
+	{unicode.Thai, "th"},
+	{unicode.Devanagari, "hi"},
+}
+
+// latinStopwords holds frequent function words for languages written in Latin script.
+var latinStopwords = map[string][]string{
+	"en": {
+		"the", "is", "are", "and", "of", "to", "in", "it", "what", "how", "does", "do",
+		"can", "my", "this", "that", "with", "for", "have", "has", "which", "many", "much", "i", "you",
+	},
+	"sv": {
+		"och", "är", "att", "det", "som", "en", "ett", "på", "för", "med", "har", "jag", "hur",
+		"många", "får", "kan", "min", "mitt", "vad", "vilken", "till", "från", "inte", "bilen",
+	},
+	"de": {
+		"und", "ist", "der", "die", "das", "nicht", "ich", "wie", "viele", "kann", "mein", "welche",
+		"hat", "für", "mit", "auf", "ein", "eine", "was", "zu",
+	},
+	"no": {
+		"og", "er", "det", "ikke", "jeg", "hvor", "mange", "hva", "på", "til", "min", "bil",
+		"kan", "med", "har", "som", "en", "et", "hvordan", "bagasje",
+	},
+	"da": {
+		"og", "er", "det", "ikke", "jeg", "hvor", "mange", "hvad", "på", "til", "min", "bil",
+		"kan", "med", "har", "som", "en", "et", "hvordan", "bagage",
+	},
+	"fi": {
+		"ja", "on", "ei", "se", "että", "mikä", "kuinka", "paljon", "minun", "voiko", "onko",
+		"mitä", "tämä", "auto", "kanssa",
+	},
+	"fr": {
+		"le", "la", "les", "est", "et", "de", "des", "un", "une", "que", "qui", "pour", "avec",
+		"combien", "comment", "mon", "ma", "je", "pas", "quelle",
+	},
+	"es": {
+		"el", "la", "los", "las", "es", "y", "de", "que", "un", "una", "por", "para", "con",
+		"cuántos", "cómo", "mi", "yo", "no", "qué", "cuál",
+	},
+	"it": {
+		"il", "lo", "la", "gli", "è", "e", "di", "che", "un", "una", "per", "con", "quanti",
+		"come", "mio", "mia", "io", "non", "quale",
+	},
+	"nl": {
+		"de", "het", "een", "is", "en", "van", "dat", "niet", "ik", "hoe", "veel", "mijn",
+		"wat", "welke", "met", "voor", "kan", "heeft",
+	},
+	"pt": {
+		"o", "a", "os", "as", "é", "e", "de", "que", "um", "uma", "para", "com", "quantos",
+		"como", "meu", "minha", "eu", "não", "qual",
+	},
+	"pl": {
+		"i", "jest", "nie", "to", "że", "na", "w", "z", "jak", "ile", "mój", "moja", "co",
+		"który", "czy", "dla", "mam",
+	},
+}
+
+// latinMarkers are characters that strongly indicate a subset of languages.
+var latinMarkers = map[rune][]string{
+	'å': {"sv", "no", "da", "fi"},
+	'ä': {"sv", "de", "fi"},
+	'ö': {"sv", "de", "fi"},
+	'ü': {"de"},
+	'ß': {"de"},
+	'æ': {"no", "da"},
+	'ø': {"no", "da"},
+	'ñ': {"es"},
+	'¿': {"es"},
+	'¡': {"es"},
+	'ç': {"fr", "pt"},
+	'ã': {"pt"},
+	'õ': {"pt"},
+	'ł': {"pl"},
+	'ś': {"pl"},
+	'ż': {"pl"},
+	'ź': {"pl"},
+	'ę': {"pl"},
+	'ą': {"pl"},
+	'œ': {"fr"},
+}
+
+// NewLanguageDetector creates a script- and stopword-based language detector.
+// Non-Latin scripts are identified by Unicode block; Latin-script languages are
+// scored by function-word hits and language-specific characters. Latin-script text
+// without any sign of another language, such as "XC90 price", is taken to be English.
+func NewLanguageDetector() DetectLanguageFn {
+	stopwords := make(map[string]map[string]bool, len(latinStopwords))
+	for language, words := range latinStopwords {
+		set := make(map[string]bool, len(words))
+		for _, word := range words {
+			set[word] = true
+		}
+		stopwords[language] = set
+	}
+
+	return func(text string) LanguageDetection {
+		if detection, ok := detectByScript(text); ok {
+			return detection
+		}
+
+		scores := make(map[string]float64)
+		total := 0.0
+		foreign := 0 // Evidence English doesn't share, including accented letters
+
+		for _, r := range strings.ToLower(text) {
+			for _, language := range latinMarkers[r] {
+				scores[language] += 1.5
+				total += 1.5
+			}
+			if len(latinMarkers[r]) > 0 || (r > unicode.MaxASCII && unicode.IsLetter(r)) {
+				foreign++
+			}
+		}
+
+		words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+			return !unicode.IsLetter(r) && r != '\''
+		})
+		for _, word := range words {
+			for language, set := range stopwords {
+				if set[word] {
+					scores[language]++
+					total++
+					if !stopwords["en"][word] {
+						foreign++
+					}
+				}
+			}
+		}
+
+		if foreign == 0 {
+			if len(words) == 0 {
+				return LanguageDetection{}
+			}
+			// Short or stopword-free English is common ("XC90 price"), so lack of
+			// evidence isn't held against it; English function words add confidence
+			english := scores["en"]
+			return LanguageDetection{
+				Language:   "en",
+				Confidence: 0.7 + 0.3*english/(english+2),
+			}
+		}
+
+		best, bestScore := "", 0.0
+		for language, score := range scores {
+			if score > bestScore || (score == bestScore && language < best) {
+				best, bestScore = language, score
+			}
+		}
+
+		// Confidence grows with the winner's share of the evidence and with the amount of evidence
+		share := bestScore / total
+		evidence := bestScore / (bestScore + 2)
+		return LanguageDetection{
+			Language:   best,
+			Confidence: share * evidence,
+		}
+	}
+}
+
+// detectByScript identifies text that is predominantly written in a non-Latin script.
+func detectByScript(text string) (LanguageDetection, bool) {
+	counts := make(map[string]int)
+	letters := 0
+
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		for _, script := range scriptLanguages {
+			if unicode.Is(script.table, r) {
+				counts[script.language]++
+				break
+			}
+		}
+	}
+
+	if letters == 0 {
+		return LanguageDetection{}, false
+	}
+
+	// Japanese text mixes kana with Han characters
+	if counts["ja"] > 0 {
+		counts["ja"] += counts["zh"]
+		delete(counts, "zh")
+	}
+
+	best, bestCount := "", 0
+	for language, count := range counts {
+		if count > bestCount || (count == bestCount && language < best) {
+			best, bestCount = language, count
+		}
+	}
+
+	share := float64(bestCount) / float64(letters)
+	if share < 0.5 {
+		return LanguageDetection{}, false
+	}
+
+	return LanguageDetection{Language: best, Confidence: share}, true
+}
@@ -0,0 +1,34 @@
+package aichat
+
+import "testing"
+
+func TestLanguageDetector(t *testing.T) {
+	detect := NewLanguageDetector()
+	const threshold = 0.6
+
+	tests := []struct {
+		text    string
+		want    string
+		english bool // Confident enough to skip translation
+	}{
+		{"XC90 price", "en", true},
+		{"How many seats?", "en", true},
+		{"What is the price of the XC60?", "en", true},
+		{"Hur många säten har bilen?", "sv", false},
+		{"Wie viele Sitze hat das Auto?", "de", false},
+		{"Combien de places dans la voiture?", "fr", false},
+		{"Prix café", "", false},
+		{"12345", "", false},
+	}
+
+	for _, tt := range tests {
+		got := detect(tt.text)
+		if tt.want != "" && got.Language != tt.want {
+			t.Errorf("detect(%q) = %q, want %q", tt.text, got.Language, tt.want)
+		}
+		if english := got.Language == "en" && got.Confidence >= threshold; english != tt.english {
+			t.Errorf("detect(%q) = %q with confidence %.2f, skips translation = %v, want %v",
+				tt.text, got.Language, got.Confidence, english, tt.english)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package aichat
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// llmCallRecordKey is the context key for the LLM call recorder.
+type llmCallRecordKey struct{}
+
+// llmCallRecord describes the most recent LLM call made for a chat request.
+type llmCallRecord struct {
+	// Model is the model that served the call, after any fallback.
+	Model string
+
+	// ExpertModel is the model that served the expert's most recent call.
+	ExpertModel string
+
+	// SystemFingerprint identifies the provider's backend configuration, if reported.
+	SystemFingerprint string
+
+	// Usage is the total token usage of all calls so far, not just the most recent.
+	Usage TokenUsage
+
+	// ModelUsage breaks Usage down by the model that served the calls.
+	ModelUsage map[string]TokenUsage
+
+	// Trace lists all calls so far when Config.ReturnTrace is set.
+	Trace []TraceEntry
+}
+
+// llmCallRecorder collects llmCallRecord values from concurrent LLM calls.
+type llmCallRecorder struct {
+	mu     sync.Mutex
+	record llmCallRecord
+}
+
+// withLLMCallRecord returns a context in which LLM clients record details of each
+// call, and a function returning the details of the most recent call.
+func withLLMCallRecord(ctx context.Context) (context.Context, func() llmCallRecord) {
+	recorder := &llmCallRecorder{}
+	return context.WithValue(ctx, llmCallRecordKey{}, recorder), recorder.snapshot
+}
+
+// snapshot returns a copy of the record.
+func (r *llmCallRecorder) snapshot() llmCallRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record := r.record
+	record.ModelUsage = maps.Clone(r.record.ModelUsage)
+	return record
+}
+
+func recordServedModel(ctx context.Context, model string) {
+	updateLLMCallRecord(ctx, func(record *llmCallRecord) {
+		record.Model = model
+		record.SystemFingerprint = ""
+		if step, _ := ctx.Value(traceStepKey{}).(TraceStep); step == TraceExpert {
+			record.ExpertModel = model
+		}
+	})
+}
+
+func recordSystemFingerprint(ctx context.Context, fingerprint string) {
+	updateLLMCallRecord(ctx, func(record *llmCallRecord) {
+		record.SystemFingerprint = fingerprint
+	})
+}
+
+// RecordTokenUsage adds the tokens used by an LLM call to the chat request being
+// processed in ctx. The built-in clients call it; custom LLM clients should too,
+// so their usage counts towards ChatResult.Usage and Config.MaxConversationTokens.
+// The tokens are attributed to the model last recorded as serving a call, if any.
+func RecordTokenUsage(ctx context.Context, promptTokens, completionTokens int) {
+	updateLLMCallRecord(ctx, func(record *llmCallRecord) {
+		record.addUsage(record.Model, TokenUsage{
+			PromptTokens:     promptTokens,
+			CompletionTokens: completionTokens,
+			TotalTokens:      promptTokens + completionTokens,
+		})
+	})
+}
+
+// RecordCachedTokens adds prompt tokens that the provider served from its prompt
+// cache to the chat request being processed in ctx. They must also be counted in
+// the prompt tokens passed to RecordTokenUsage.
+func RecordCachedTokens(ctx context.Context, cachedTokens int) {
+	updateLLMCallRecord(ctx, func(record *llmCallRecord) {
+		record.addUsage(record.Model, TokenUsage{CachedTokens: cachedTokens})
+	})
+}
+
+// recordModelUsage adds the tokens used by an LLM call served by model.
+func recordModelUsage(ctx context.Context, model string, usage TokenUsage) {
+	updateLLMCallRecord(ctx, func(record *llmCallRecord) {
+		record.addUsage(model, usage)
+	})
+}
+
+// addUsage adds usage to the totals and to model's share.
+func (r *llmCallRecord) addUsage(model string, usage TokenUsage) {
+	r.Usage = r.Usage.add(usage)
+	if r.ModelUsage == nil {
+		r.ModelUsage = make(map[string]TokenUsage)
+	}
+	r.ModelUsage[model] = r.ModelUsage[model].add(usage)
+}
+
+// modelUsage returns ModelUsage as a list sorted by model.
+func (r llmCallRecord) modelUsage() []ModelUsage {
+	var usage []ModelUsage
+	for _, model := range slices.Sorted(maps.Keys(r.ModelUsage)) {
+		usage = append(usage, ModelUsage{Model: model, TokenUsage: r.ModelUsage[model]})
+	}
+	return usage
+}
+
+func updateLLMCallRecord(ctx context.Context, update func(record *llmCallRecord)) {
+	recorder, ok := ctx.Value(llmCallRecordKey{}).(*llmCallRecorder)
+	if !ok {
+		return
+	}
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	update(&recorder.record)
+}
+
+// seedKey is the context key for a per-request seed.
+type seedKey struct{}
+
+// withSeed returns a context whose LLM calls use seed unless their options set one.
+func withSeed(ctx context.Context, seed *int) context.Context {
+	if seed == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, seedKey{}, *seed)
+}
+
+// resolveSeed returns the seed for an LLM call: the call's own seed, else the
+// request's seed from ctx, else the configured default.
+func resolveSeed(ctx context.Context, callSeed, defaultSeed *int) *int {
+	if callSeed != nil {
+		return callSeed
+	}
+	if seed, ok := ctx.Value(seedKey{}).(int); ok {
+		return &seed
+	}
+	return defaultSeed
+}
@@ -0,0 +1,134 @@
+package aichat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// applyLLMMiddleware wraps client with the given middleware, first entry outermost.
+func applyLLMMiddleware(client LLMClient, middleware []LLMMiddleware) LLMClient {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		client = middleware[i](client)
+	}
+	return client
+}
+
+// LLMLoggingMiddleware returns a middleware that logs every LLM call at debug level,
+// including the model tier, prompts, response and duration.
+// To log without raw PII, use Config.LogPrompts instead.
+func LLMLoggingMiddleware(logger *slog.Logger) LLMMiddleware {
+	return llmLoggingMiddleware(logger, func(text string) string { return text })
+}
+
+// llmLoggingMiddleware logs every LLM call at debug level, passing prompts and
+// responses through transform before they are logged.
+func llmLoggingMiddleware(logger *slog.Logger, transform func(text string) string) LLMMiddleware {
+	return func(next LLMClient) LLMClient {
+		return LLMClient{
+			Chat: func(ctx context.Context, systemPrompt, userMessage string, opts *ChatOptions) (string, error) {
+				start := time.Now()
+				response, err := next.Chat(ctx, systemPrompt, userMessage, opts)
+				logLLMCall(ctx, logger, "chat", chatOptionsModel(opts),
+					transform(systemPrompt), transform(userMessage), transformResponse(response, transform), start, err)
+				return response, err
+			},
+			ChatJSON: func(ctx context.Context, systemPrompt, userMessage string, opts *ChatJSONOptions, result any) error {
+				start := time.Now()
+				err := next.ChatJSON(ctx, systemPrompt, userMessage, opts, result)
+				response := ""
+				var parseErr *responseParseError
+				if err == nil {
+					if data, marshalErr := json.Marshal(result); marshalErr == nil {
+						response = string(data)
+					}
+				} else if errors.As(err, &parseErr) {
+					response = parseErr.content
+				}
+				logLLMCall(ctx, logger, "chat_json", chatJSONOptionsModel(opts),
+					transform(systemPrompt), transform(userMessage), transformResponse(response, transform), start, err)
+				return err
+			},
+			ChatStream: func(ctx context.Context, systemPrompt, userMessage string, opts *ChatOptions, onToken func(token string)) (string, error) {
+				start := time.Now()
+				response, err := next.ChatStream(ctx, systemPrompt, userMessage, opts, onToken)
+				logLLMCall(ctx, logger, "chat_stream", chatOptionsModel(opts),
+					transform(systemPrompt), transform(userMessage), transformResponse(response, transform), start, err)
+				return response, err
+			},
+		}
+	}
+}
+
+// transformResponse applies transform to a non-empty response.
+func transformResponse(response string, transform func(text string) string) string {
+	if response == "" {
+		return ""
+	}
+	return transform(response)
+}
+
+// LLMPromptPrefixMiddleware returns a middleware that prepends prefix to the system prompt of every LLM call.
+func LLMPromptPrefixMiddleware(prefix string) LLMMiddleware {
+	return func(next LLMClient) LLMClient {
+		withPrefix := func(systemPrompt string) string {
+			if systemPrompt == "" {
+				return prefix
+			}
+			return prefix + "\n\n" + systemPrompt
+		}
+
+		return LLMClient{
+			Chat: func(ctx context.Context, systemPrompt, userMessage string, opts *ChatOptions) (string, error) {
+				return next.Chat(ctx, withPrefix(systemPrompt), userMessage, opts)
+			},
+			ChatJSON: func(ctx context.Context, systemPrompt, userMessage string, opts *ChatJSONOptions, result any) error {
+				return next.ChatJSON(ctx, withPrefix(systemPrompt), userMessage, opts, result)
+			},
+			ChatStream: func(ctx context.Context, systemPrompt, userMessage string, opts *ChatOptions, onToken func(token string)) (string, error) {
+				return next.ChatStream(ctx, withPrefix(systemPrompt), userMessage, opts, onToken)
+			},
+		}
+	}
+}
+
+func chatOptionsModel(opts *ChatOptions) ModelTier {
+	if opts == nil {
+		return ""
+	}
+	return opts.Model
+}
+
+func chatJSONOptionsModel(opts *ChatJSONOptions) ModelTier {
+	if opts == nil {
+		return ""
+	}
+	return opts.Model
+}
+
+func logLLMCall(
+	ctx context.Context,
+	logger *slog.Logger,
+	method string,
+	model ModelTier,
+	systemPrompt, userMessage, response string,
+	start time.Time,
+	err error,
+) {
+	attrs := []slog.Attr{
+		slog.String("method", method),
+		slog.String("model_tier", string(model)),
+		slog.String("system_prompt", systemPrompt),
+		slog.String("user_message", userMessage),
+		slog.Duration("duration", time.Since(start)),
+	}
+	if response != "" {
+		attrs = append(attrs, slog.String("response", response))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	logger.LogAttrs(ctx, slog.LevelDebug, "llm call", attrs...)
+}
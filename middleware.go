@@ -12,9 +12,14 @@ import (
 )
 
 // timeoutMiddleware returns a middleware that adds a context timeout to requests.
-func timeoutMiddleware(timeout time.Duration) func(http.Handler) http.Handler {
+func timeoutMiddleware(timeout time.Duration, routeTimeouts map[string]time.Duration) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			timeout := timeout
+			if routeTimeout, ok := routeTimeouts[r.Method+" "+r.URL.Path]; ok {
+				timeout = routeTimeout
+			}
+
 			ctx, cancel := context.WithTimeout(r.Context(), timeout)
 			defer cancel()
 			next.ServeHTTP(w, r.WithContext(ctx))
@@ -32,10 +37,6 @@ func bodySizeLimitMiddleware(maxBytes int64) func(http.Handler) http.Handler {
 	}
 }
 
-type contextKey string
-
-const requestIDKey contextKey = "request_id"
-
 // requestIDMiddleware is a middleware that generates a unique request ID for each request.
 func requestIDMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -48,14 +49,6 @@ func requestIDMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-// getRequestID retrieves the request ID from the context.
-func getRequestID(ctx context.Context) string {
-	if requestID, ok := ctx.Value(requestIDKey).(string); ok {
-		return requestID
-	}
-	return ""
-}
-
 type responseWriter struct {
 	http.ResponseWriter
 	statusCode int
@@ -90,7 +83,7 @@ func loggingMiddleware(logger *slog.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
-			requestID := getRequestID(r.Context())
+			requestID := RequestIDFromContext(r.Context())
 
 			rw := &responseWriter{
 				ResponseWriter: w,
@@ -126,7 +119,7 @@ func recoveryMiddleware(logger *slog.Logger) func(http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			defer func() {
 				if err := recover(); err != nil {
-					requestID := getRequestID(r.Context())
+					requestID := RequestIDFromContext(r.Context())
 					stack := string(debug.Stack())
 
 					logger.Error("panic recovered",
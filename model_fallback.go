@@ -0,0 +1,125 @@
+package aichat
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// withModelFallback calls call with primary and then each fallback model in order,
+// moving on only while shouldFallback reports the error as one another model may not hit.
+// It returns the result and the model that produced it, recording that model in ctx.
+func withModelFallback[T any](
+	ctx context.Context,
+	primary string,
+	fallbacks []string,
+	shouldFallback func(error) bool,
+	logger *slog.Logger,
+	call func(model string) (T, error),
+) (T, string, error) {
+	models := []string{primary}
+	for _, model := range fallbacks {
+		if model != primary {
+			models = append(models, model)
+		}
+	}
+
+	var result T
+	var err error
+	for i, model := range models {
+		result, err = call(model)
+		if err == nil {
+			recordServedModel(ctx, model)
+			return result, model, nil
+		}
+
+		if i == len(models)-1 || ctx.Err() != nil || !shouldFallback(err) {
+			return result, model, err
+		}
+
+		logger.Warn("model failed, falling back",
+			slog.String("model", model),
+			slog.String("fallback_model", models[i+1]),
+			slog.String("error", err.Error()),
+		)
+	}
+
+	return result, primary, err
+}
+
+// isOpenAIFallbackError reports whether err is a rate limit, server error or
+// context-length error, for which a different model may succeed.
+func isOpenAIFallbackError(err error) bool {
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		if code, ok := apiErr.Code.(string); ok && code == "context_length_exceeded" {
+			return true
+		}
+		return isRetryableStatus(apiErr.HTTPStatusCode)
+	}
+
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) {
+		return isRetryableStatus(reqErr.HTTPStatusCode)
+	}
+
+	return false
+}
+
+// isAnthropicFallbackError reports whether err is a rate limit, overload, server
+// error or prompt-too-long error, for which a different model may succeed.
+func isAnthropicFallbackError(err error) bool {
+	var apiErr *AnthropicError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	if apiErr.Type == "overloaded_error" || apiErr.StatusCode == 529 {
+		return true
+	}
+	if apiErr.Type == "invalid_request_error" && strings.Contains(apiErr.Message, "prompt is too long") {
+		return true
+	}
+	return isRetryableStatus(apiErr.StatusCode)
+}
+
+// isBedrockFallbackError reports whether err is a throttling, server or
+// input-too-long error, for which a different model may succeed. Exceptions in a
+// stream carry no status, so they are matched by type.
+func isBedrockFallbackError(err error) bool {
+	var apiErr *BedrockError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	switch strings.ToLower(apiErr.Type) {
+	case "throttlingexception", "servicequotaexceededexception", "modelnotreadyexception",
+		"serviceunavailableexception", "internalserverexception", "modelstreamerrorexception":
+		return true
+	case "validationexception":
+		return isBedrockContextLengthMessage(apiErr.Message)
+	}
+	return isRetryableStatus(apiErr.StatusCode)
+}
+
+// isGeminiFallbackError reports whether err is a rate limit, server or
+// input-too-long error, for which a different model may succeed.
+func isGeminiFallbackError(err error) bool {
+	var apiErr *GeminiError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	if apiErr.Status == "INVALID_ARGUMENT" && geminiContextLengthPattern.MatchString(apiErr.Message) {
+		return true
+	}
+	return isRetryableStatus(apiErr.StatusCode)
+}
+
+func isRetryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
@@ -0,0 +1,287 @@
+package aichat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+func TestWithModelFallback(t *testing.T) {
+	rateLimited := &openai.APIError{HTTPStatusCode: http.StatusTooManyRequests, Message: "Rate limit reached"}
+	contextLength := &openai.APIError{HTTPStatusCode: http.StatusBadRequest, Code: "context_length_exceeded", Message: "maximum context length"}
+	invalidSchema := &openai.APIError{HTTPStatusCode: http.StatusBadRequest, Code: "invalid_json_schema", Message: "Invalid schema for response_format"}
+
+	tests := []struct {
+		name       string
+		fallbacks  []string
+		failures   map[string]error
+		wantCalls  []string
+		wantModel  string
+		wantErr    error
+		wantServed string
+	}{
+		{
+			name:       "primary succeeds",
+			fallbacks:  []string{"fallback-a"},
+			wantCalls:  []string{"primary"},
+			wantModel:  "primary",
+			wantServed: "primary",
+		},
+		{
+			name:       "rate limit falls back",
+			fallbacks:  []string{"fallback-a", "fallback-b"},
+			failures:   map[string]error{"primary": rateLimited},
+			wantCalls:  []string{"primary", "fallback-a"},
+			wantModel:  "fallback-a",
+			wantServed: "fallback-a",
+		},
+		{
+			name:       "context length falls back",
+			fallbacks:  []string{"fallback-a"},
+			failures:   map[string]error{"primary": contextLength},
+			wantCalls:  []string{"primary", "fallback-a"},
+			wantModel:  "fallback-a",
+			wantServed: "fallback-a",
+		},
+		{
+			name:      "schema validation failure does not fall back",
+			fallbacks: []string{"fallback-a"},
+			failures:  map[string]error{"primary": invalidSchema},
+			wantCalls: []string{"primary"},
+			wantModel: "primary",
+			wantErr:   invalidSchema,
+		},
+		{
+			name:      "last model's error is returned",
+			fallbacks: []string{"fallback-a"},
+			failures:  map[string]error{"primary": rateLimited, "fallback-a": contextLength},
+			wantCalls: []string{"primary", "fallback-a"},
+			wantModel: "fallback-a",
+			wantErr:   contextLength,
+		},
+		{
+			name:      "primary listed as fallback is not retried",
+			fallbacks: []string{"primary"},
+			failures:  map[string]error{"primary": rateLimited},
+			wantCalls: []string{"primary"},
+			wantModel: "primary",
+			wantErr:   rateLimited,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, lastCall := withLLMCallRecord(context.Background())
+
+			var calls []string
+			result, model, err := withModelFallback(ctx, "primary", tt.fallbacks, isOpenAIFallbackError, slog.New(slog.DiscardHandler),
+				func(model string) (string, error) {
+					calls = append(calls, model)
+					if err := tt.failures[model]; err != nil {
+						return "", err
+					}
+					return "answer from " + model, nil
+				},
+			)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if !slices.Equal(calls, tt.wantCalls) {
+				t.Errorf("called models %v, want %v", calls, tt.wantCalls)
+			}
+			if model != tt.wantModel {
+				t.Errorf("model = %q, want %q", model, tt.wantModel)
+			}
+			if err == nil && result != "answer from "+tt.wantModel {
+				t.Errorf("result = %q, want the answer from %s", result, tt.wantModel)
+			}
+			if got := lastCall().Model; got != tt.wantServed {
+				t.Errorf("served model = %q, want %q", got, tt.wantServed)
+			}
+		})
+	}
+}
+
+func TestWithModelFallbackStopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var calls []string
+	_, _, err := withModelFallback(ctx, "primary", []string{"fallback-a"}, isOpenAIFallbackError, slog.New(slog.DiscardHandler),
+		func(model string) (string, error) {
+			calls = append(calls, model)
+			cancel()
+			return "", &openai.APIError{HTTPStatusCode: http.StatusServiceUnavailable}
+		},
+	)
+
+	if err == nil || len(calls) != 1 {
+		t.Errorf("called models %v with error %v, want one failed call", calls, err)
+	}
+}
+
+func TestIsAnthropicFallbackError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&AnthropicError{StatusCode: 529, Type: "overloaded_error"}, true},
+		{&AnthropicError{StatusCode: http.StatusTooManyRequests, Type: "rate_limit_error"}, true},
+		{&AnthropicError{StatusCode: http.StatusBadRequest, Type: "invalid_request_error", Message: "prompt is too long: 210000 tokens > 200000 maximum"}, true},
+		{&AnthropicError{StatusCode: http.StatusBadRequest, Type: "invalid_request_error", Message: "messages: field required"}, false},
+		{&AnthropicError{StatusCode: http.StatusUnauthorized, Type: "authentication_error"}, false},
+		{errors.New("failed to decode Anthropic response"), false},
+	}
+
+	for _, tt := range tests {
+		if got := isAnthropicFallbackError(tt.err); got != tt.want {
+			t.Errorf("isAnthropicFallbackError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestIsBedrockFallbackError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&BedrockError{StatusCode: http.StatusTooManyRequests, Type: "ThrottlingException"}, true},
+		{&BedrockError{StatusCode: http.StatusServiceUnavailable, Type: "ServiceUnavailableException"}, true},
+		{&BedrockError{Type: "throttlingException", Message: "Too many requests"}, true},
+		{&BedrockError{StatusCode: http.StatusBadRequest, Type: "ValidationException", Message: "Input is too long for requested model."}, true},
+		{&BedrockError{StatusCode: http.StatusBadRequest, Type: "ValidationException", Message: "Malformed input request"}, false},
+		{&BedrockError{StatusCode: http.StatusForbidden, Type: "AccessDeniedException"}, false},
+		{errors.New("failed to decode Bedrock response"), false},
+	}
+
+	for _, tt := range tests {
+		if got := isBedrockFallbackError(tt.err); got != tt.want {
+			t.Errorf("isBedrockFallbackError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestIsGeminiFallbackError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&GeminiError{StatusCode: http.StatusTooManyRequests, Status: "RESOURCE_EXHAUSTED"}, true},
+		{&GeminiError{StatusCode: http.StatusServiceUnavailable, Status: "UNAVAILABLE"}, true},
+		{&GeminiError{StatusCode: http.StatusBadRequest, Status: "INVALID_ARGUMENT", Message: "The input token count (1200000) exceeds the maximum number of tokens allowed (1048576)."}, true},
+		{&GeminiError{StatusCode: http.StatusBadRequest, Status: "INVALID_ARGUMENT", Message: "API key not valid"}, false},
+		{&GeminiError{StatusCode: http.StatusForbidden, Status: "PERMISSION_DENIED"}, false},
+		{errors.New("failed to decode Gemini response"), false},
+	}
+
+	for _, tt := range tests {
+		if got := isGeminiFallbackError(tt.err); got != tt.want {
+			t.Errorf("isGeminiFallbackError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestChatResultModelReportsTheExpertsFallbackModel(t *testing.T) {
+	var mu sync.Mutex
+	var expertModels []string
+
+	// An OpenAI-compatible API on which the primary model is rate limited for the expert
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req openai.ChatCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		system := req.Messages[0].Content
+
+		var content string
+		switch {
+		case strings.Contains(system, "You answer product questions."):
+			mu.Lock()
+			expertModels = append(expertModels, req.Model)
+			mu.Unlock()
+			if req.Model == "primary-model" {
+				w.WriteHeader(http.StatusTooManyRequests)
+				w.Write([]byte(`{"error": {"message": "Rate limit reached", "type": "rate_limit_error"}}`))
+				return
+			}
+			content = "The Widget Pro has three speeds."
+		case strings.Contains(system, "follow-up questions"):
+			content = `{"suggestions": ["How much does it weigh?"]}`
+		default:
+			content = `{"expert": "product", "reasoning": "Asks about a product"}`
+		}
+
+		json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+			Model: req.Model,
+			Choices: []openai.ChatCompletionChoice{{
+				Message:      openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: content},
+				FinishReason: openai.FinishReasonStop,
+			}},
+			Usage: openai.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+		})
+	}))
+	defer server.Close()
+
+	clientConfig := openai.DefaultConfig("test-key")
+	clientConfig.BaseURL = server.URL + "/v1"
+
+	var sdk *SDK
+	sdk, err := New(Config{
+		OpenAIClient: openai.NewClientWithConfig(clientConfig),
+		ModelMap: map[ModelTier]string{
+			ModelNano:     "primary-model",
+			ModelMini:     "primary-model",
+			ModelStandard: "primary-model",
+		},
+		ModelFallbacks:      []string{"fallback-model"},
+		GenerateSuggestions: true,
+		DevMode:             true,
+		Logger:              slog.New(slog.DiscardHandler),
+		LanguageDetector: func(text string) LanguageDetection {
+			return LanguageDetection{Language: "en", Confidence: 1}
+		},
+		Experts: map[ExpertType]Expert{
+			"product": {
+				Name:        "Product Expert",
+				Description: "Questions about products",
+				Handler: func(ctx context.Context, req ExpertRequest) (*ExpertResult, error) {
+					answer, err := sdk.LLMClient().Chat(ctx, "You answer product questions.", req.Message, nil)
+					if err != nil {
+						return nil, err
+					}
+					return &ExpertResult{Answer: answer}, nil
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	result, err := sdk.ProcessChat()(context.Background(), ChatRequest{Message: "How many speeds does the Widget Pro have?"})
+	if err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+
+	if want := []string{"primary-model", "fallback-model"}; !slices.Equal(expertModels, want) {
+		t.Errorf("expert called models %v, want %v", expertModels, want)
+	}
+	if len(result.SuggestedFollowups) == 0 {
+		t.Errorf("SuggestedFollowups is empty, want a suggestion served by primary-model after the expert call")
+	}
+	// The suggestion call that ran last was served by the primary model
+	if result.Model != "fallback-model" {
+		t.Errorf("Model = %q, want %q", result.Model, "fallback-model")
+	}
+}
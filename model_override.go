@@ -0,0 +1,90 @@
+package aichat
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// maxTemperature is the highest sampling temperature a request may ask for.
+const maxTemperature = 2
+
+// modelOverrideKey is the context key for a per-request model and temperature override.
+type modelOverrideKey struct{}
+
+type modelOverride struct {
+	model       string
+	temperature *float32
+}
+
+// withModelOverride returns a context whose Chat and ChatStream calls use model
+// and temperature instead of the values chosen by the SDK or expert.
+func withModelOverride(ctx context.Context, model string, temperature *float32) context.Context {
+	if model == "" && temperature == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, modelOverrideKey{}, modelOverride{model: model, temperature: temperature})
+}
+
+// ModelOverrideFromContext returns the model name and temperature requested for
+// the current chat, for custom LLM clients to honor in Chat and ChatStream.
+// model is empty and temperature nil when not overridden.
+//
+// ChatJSON calls (routing, translation, suggestions) ignore the override. The
+// Anthropic client caps an overridden temperature at 1, the highest it accepts.
+func ModelOverrideFromContext(ctx context.Context) (model string, temperature *float32) {
+	override, _ := ctx.Value(modelOverrideKey{}).(modelOverride)
+	return override.model, override.temperature
+}
+
+// applyModelOverride returns the model name and temperature for a Chat or
+// ChatStream call, replacing either with the request's override if set.
+func applyModelOverride(ctx context.Context, model string, temperature float32) (string, float32) {
+	overrideModel, overrideTemperature := ModelOverrideFromContext(ctx)
+	if overrideModel != "" {
+		model = overrideModel
+	}
+	if overrideTemperature != nil {
+		temperature = *overrideTemperature
+	}
+	return model, temperature
+}
+
+// hasModelOverride reports whether the request overrides the model or temperature.
+func hasModelOverride(ctx context.Context) bool {
+	_, ok := ctx.Value(modelOverrideKey{}).(modelOverride)
+	return ok
+}
+
+// validateModelOverride checks a request's model against allowedModels and its temperature range.
+func validateModelOverride(req ChatRequest, allowedModels []string) error {
+	if req.Model != "" && !slices.Contains(allowedModels, req.Model) {
+		return fmt.Errorf("%w: model %q is not allowed", ErrInvalidInput, req.Model)
+	}
+	if req.Temperature != nil && (*req.Temperature < 0 || *req.Temperature > maxTemperature) {
+		return fmt.Errorf("%w: temperature must be between 0 and %d", ErrInvalidInput, maxTemperature)
+	}
+	return nil
+}
+
+// withModelOverrides wraps a chat function to validate and apply per-request
+// model and temperature overrides.
+func withModelOverrides(processChat ProcessChatFn, allowedModels []string) ProcessChatFn {
+	return func(ctx context.Context, req ChatRequest) (*ChatResult, error) {
+		if err := validateModelOverride(req, allowedModels); err != nil {
+			return nil, err
+		}
+		return processChat(withModelOverride(ctx, req.Model, req.Temperature), req)
+	}
+}
+
+// withModelOverridesStreaming wraps a streaming chat function to validate and
+// apply per-request model and temperature overrides.
+func withModelOverridesStreaming(processChatStream ProcessChatStreamFn, allowedModels []string) ProcessChatStreamFn {
+	return func(ctx context.Context, req ChatRequest, stream StreamCallback) (*ChatResult, error) {
+		if err := validateModelOverride(req, allowedModels); err != nil {
+			return nil, err
+		}
+		return processChatStream(withModelOverride(ctx, req.Model, req.Temperature), req, stream)
+	}
+}
@@ -0,0 +1,255 @@
+package aichat_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	aichat "github.com/ourstudio-se/ai-chat-sdk"
+)
+
+// modelCall is the model and temperature an LLM call was sent with.
+type modelCall struct {
+	model       string
+	temperature float32
+}
+
+// modelCalls records the expert and routing calls an LLM test server receives.
+type modelCalls struct {
+	mu      sync.Mutex
+	expert  []modelCall
+	routing []modelCall
+}
+
+func (c *modelCalls) record(systemPrompt string, call modelCall) (isExpert bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if strings.Contains(systemPrompt, "You answer product questions.") {
+		c.expert = append(c.expert, call)
+		return true
+	}
+	c.routing = append(c.routing, call)
+	return false
+}
+
+// newModelOverrideSDK creates an SDK whose product expert makes one Chat call with
+// default options, so its model and temperature come from config unless overridden.
+func newModelOverrideSDK(t *testing.T, config aichat.Config) *aichat.SDK {
+	t.Helper()
+
+	var sdk *aichat.SDK
+	config.DevMode = true
+	config.Logger = slog.New(slog.DiscardHandler)
+	config.LanguageDetector = func(text string) aichat.LanguageDetection {
+		return aichat.LanguageDetection{Language: "en", Confidence: 1}
+	}
+	config.Experts = map[aichat.ExpertType]aichat.Expert{
+		"product": {
+			Name:        "Product Expert",
+			Description: "Questions about products",
+			Handler: func(ctx context.Context, req aichat.ExpertRequest) (*aichat.ExpertResult, error) {
+				answer, err := sdk.LLMClient().Chat(ctx, "You answer product questions.", req.Message, nil)
+				if err != nil {
+					return nil, err
+				}
+				return &aichat.ExpertResult{Answer: answer}, nil
+			},
+		},
+	}
+
+	sdk, err := aichat.New(config)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return sdk
+}
+
+func TestModelOverridePrecedence(t *testing.T) {
+	tests := []struct {
+		name          string
+		allowedModels []string
+		model         string
+		temperature   *float32
+		wantErr       bool
+		wantExpert    modelCall
+	}{
+		{
+			name:       "config without override",
+			wantExpert: modelCall{model: "configured-model", temperature: 0.7},
+		},
+		{
+			name:          "request model replaces configured model",
+			allowedModels: []string{"override-model"},
+			model:         "override-model",
+			wantExpert:    modelCall{model: "override-model", temperature: 0.7},
+		},
+		{
+			name:        "request temperature replaces call temperature",
+			temperature: temperature(0.2),
+			wantExpert:  modelCall{model: "configured-model", temperature: 0.2},
+		},
+		{
+			name:          "request model and temperature",
+			allowedModels: []string{"override-model"},
+			model:         "override-model",
+			temperature:   temperature(2),
+			wantExpert:    modelCall{model: "override-model", temperature: 2},
+		},
+		{
+			name:          "model not in AllowedModels",
+			allowedModels: []string{"override-model"},
+			model:         "expensive-model",
+			wantErr:       true,
+		},
+		{
+			name:    "model without AllowedModels",
+			model:   "configured-model",
+			wantErr: true,
+		},
+		{
+			name:        "temperature above 2",
+			temperature: temperature(2.1),
+			wantErr:     true,
+		},
+		{
+			name:        "negative temperature",
+			temperature: temperature(-0.1),
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls modelCalls
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var req openai.ChatCompletionRequest
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					t.Errorf("failed to decode request: %v", err)
+					return
+				}
+
+				content := `{"expert": "product", "reasoning": "Asks about a product"}`
+				if calls.record(req.Messages[0].Content, modelCall{model: req.Model, temperature: req.Temperature}) {
+					content = "The Widget Pro has three speeds."
+				}
+				json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+					Choices: []openai.ChatCompletionChoice{{
+						Message: openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: content},
+					}},
+				})
+			}))
+			defer server.Close()
+
+			clientConfig := openai.DefaultConfig("test-key")
+			clientConfig.BaseURL = server.URL + "/v1"
+			sdk := newModelOverrideSDK(t, aichat.Config{
+				OpenAIClient: openai.NewClientWithConfig(clientConfig),
+				ModelMap: map[aichat.ModelTier]string{
+					aichat.ModelNano:     "configured-model",
+					aichat.ModelMini:     "configured-model",
+					aichat.ModelStandard: "configured-model",
+				},
+				AllowedModels: tt.allowedModels,
+			})
+
+			_, err := sdk.ProcessChat()(context.Background(), aichat.ChatRequest{
+				Message:     "How many speeds does the Widget Pro have?",
+				Model:       tt.model,
+				Temperature: tt.temperature,
+			})
+
+			if tt.wantErr {
+				if !errors.Is(err, aichat.ErrInvalidInput) {
+					t.Fatalf("ProcessChat() error = %v, want ErrInvalidInput", err)
+				}
+				if n := len(calls.expert) + len(calls.routing); n > 0 {
+					t.Errorf("rejected request made %d LLM calls, want none", n)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ProcessChat() error = %v", err)
+			}
+
+			if len(calls.expert) != 1 || calls.expert[0] != tt.wantExpert {
+				t.Errorf("expert calls = %+v, want [%+v]", calls.expert, tt.wantExpert)
+			}
+			// Routing goes through ChatJSON, which ignores the override
+			if len(calls.routing) == 0 {
+				t.Fatal("no routing call recorded")
+			}
+			for _, call := range calls.routing {
+				if call != (modelCall{model: "configured-model", temperature: 0.3}) {
+					t.Errorf("routing call = %+v, want the configured model at 0.3", call)
+				}
+			}
+		})
+	}
+}
+
+func TestAnthropicCapsOverriddenTemperature(t *testing.T) {
+	var calls modelCalls
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model       string          `json:"model"`
+			Temperature float32         `json:"temperature"`
+			System      json.RawMessage `json:"system"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+			return
+		}
+
+		text := `{"expert": "product", "reasoning": "Asks about a product"}`
+		if calls.record(string(req.System), modelCall{model: req.Model, temperature: req.Temperature}) {
+			text = "The Widget Pro has three speeds."
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"content":     []map[string]string{{"type": "text", "text": text}},
+			"stop_reason": "end_turn",
+		})
+	}))
+	defer server.Close()
+
+	sdk := newModelOverrideSDK(t, aichat.Config{
+		LLMClient: aichat.NewAnthropicClient(aichat.AnthropicConfig{
+			APIKey:  "test-key",
+			BaseURL: server.URL,
+			Logger:  slog.New(slog.DiscardHandler),
+		}),
+		AllowedModels: []string{"claude-override"},
+	})
+
+	_, err := sdk.ProcessChat()(context.Background(), aichat.ChatRequest{
+		Message:     "How many speeds does the Widget Pro have?",
+		Model:       "claude-override",
+		Temperature: temperature(1.8),
+	})
+	if err != nil {
+		t.Fatalf("ProcessChat() error = %v", err)
+	}
+
+	// The Messages API accepts temperatures up to 1
+	if want := (modelCall{model: "claude-override", temperature: 1}); len(calls.expert) != 1 || calls.expert[0] != want {
+		t.Errorf("expert calls = %+v, want [%+v]", calls.expert, want)
+	}
+	for _, call := range calls.routing {
+		if call.model == "claude-override" {
+			t.Errorf("routing call = %+v, want the configured model", call)
+		}
+	}
+}
+
+// temperature returns a pointer to a request temperature.
+func temperature(v float32) *float32 {
+	return &v
+}
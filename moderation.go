@@ -0,0 +1,123 @@
+package aichat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// ModerationResult is the outcome of moderating a text.
+type ModerationResult struct {
+	// Flagged reports whether the text violates the moderation policy.
+	Flagged bool
+
+	// Categories lists the policy categories the text was flagged for.
+	Categories []string
+}
+
+// ModerateFn classifies a text against a content policy.
+type ModerateFn func(ctx context.Context, text string) (*ModerationResult, error)
+
+// NewOpenAIModerator creates a moderator backed by the OpenAI moderation endpoint.
+// If model is empty, omni-moderation-latest is used.
+func NewOpenAIModerator(client *openai.Client, model string) ModerateFn {
+	if model == "" {
+		model = openai.ModerationOmniLatest
+	}
+
+	return func(ctx context.Context, text string) (*ModerationResult, error) {
+		resp, err := client.Moderations(ctx, openai.ModerationRequest{
+			Input: text,
+			Model: model,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("OpenAI moderation API error: %w", err)
+		}
+
+		if len(resp.Results) == 0 {
+			return nil, errors.New("no moderation result returned from OpenAI")
+		}
+
+		result := resp.Results[0]
+		return &ModerationResult{
+			Flagged:    result.Flagged,
+			Categories: flaggedCategories(result.Categories),
+		}, nil
+	}
+}
+
+// flaggedCategories returns the JSON names of the categories set in categories.
+func flaggedCategories(categories openai.ResultCategories) []string {
+	data, err := json.Marshal(categories)
+	if err != nil {
+		return nil
+	}
+
+	var flags map[string]bool
+	if err := json.Unmarshal(data, &flags); err != nil {
+		return nil
+	}
+
+	var result []string
+	for category, flagged := range flags {
+		if flagged {
+			result = append(result, category)
+		}
+	}
+	return result
+}
+
+// withOutputModeration wraps a formatter so that every answer, including the
+// unformatted fallback used when formatting fails, is moderated before it is
+// stored or returned. Flagged answers are replaced with fallbackMessage.
+// If the moderator fails, the answer is returned unmoderated.
+func withOutputModeration(formatResponse FormatResponseFn, moderate ModerateFn, fallbackMessage string, logger *slog.Logger) FormatResponseFn {
+	return func(ctx context.Context, req FormatRequest) (*FormatResponse, error) {
+		resp, err := formatResponse(ctx, req)
+		if err != nil {
+			logger.Warn("formatting failed, using fallback answer", "error", err)
+			resp = &FormatResponse{
+				FormattedAnswer: req.Answer,
+				Language:        req.DetectedLanguage,
+			}
+		}
+
+		moderation, err := moderate(ctx, resp.FormattedAnswer)
+		if err != nil {
+			logger.Warn("output moderation failed, returning unmoderated answer",
+				slog.String("expert_type", string(req.ExpertType)),
+				slog.String("error", err.Error()),
+			)
+			return resp, nil
+		}
+
+		if moderation.Flagged {
+			resp.FormattedAnswer = fallbackMessage
+			resp.Moderation = moderation
+		}
+
+		return resp, nil
+	}
+}
+
+// withBufferedContent wraps a streaming chat function so that content events are
+// withheld and the final (moderated) answer is sent as a single content event.
+func withBufferedContent(processChatStream ProcessChatStreamFn) ProcessChatStreamFn {
+	return func(ctx context.Context, req ChatRequest, stream StreamCallback) (*ChatResult, error) {
+		result, err := processChatStream(ctx, req, func(event StreamEvent) {
+			if event.Type != EventContent {
+				stream(event)
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		stream(StreamEvent{Type: EventContent, Content: &result.ExpertResult.Answer})
+		return result, nil
+	}
+}
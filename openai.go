@@ -10,13 +10,6 @@ import (
 	openai "github.com/sashabaranov/go-openai"
 )
 
-// openaiClient is an internal struct of functions for OpenAI API access.
-type openaiClient struct {
-	Chat       ChatFn
-	ChatJSON   ChatJSONFn
-	ChatStream ChatStreamFn
-}
-
 // defaultModelMap maps model tiers to actual OpenAI model names.
 var defaultModelMap = map[ModelTier]string{
 	ModelNano:      "gpt-4o-mini",
@@ -58,27 +51,47 @@ func defaultChatJSONOptions() ChatJSONOptions {
 	}
 }
 
+// responseParseError reports an LLM response that could not be decoded as JSON.
+// The raw response is kept out of the error message, since it may echo user data;
+// the prompt logging middleware logs it according to Config.LogPrompts.
+type responseParseError struct {
+	provider string
+	content  string
+	err      error
+}
+
+func (e *responseParseError) Error() string {
+	return fmt.Sprintf("failed to parse %s JSON response: %v", e.provider, e.err)
+}
+
+func (e *responseParseError) Unwrap() error {
+	return e.err
+}
+
 // newInternalOpenAIClient wraps an *openai.Client with the internal function-based API.
-func newInternalOpenAIClient(client *openai.Client, logger *slog.Logger, modelMap map[ModelTier]string) *openaiClient {
-	return &openaiClient{
-		Chat:       newChatFn(client, logger, modelMap),
-		ChatJSON:   newChatJSONFn(client, logger, modelMap),
-		ChatStream: newChatStreamFn(client, logger, modelMap),
+// Calls to reasoningModels omit the temperature and send max_completion_tokens and the reasoning effort.
+// Calls that fail with a retryable or context-length error are retried with each of fallbacks in order.
+// seed is the default sampling seed for calls that don't set one (nil for none).
+func newInternalOpenAIClient(client *openai.Client, logger *slog.Logger, modelMap map[ModelTier]string, fallbacks []string, seed *int, reasoningModels []string) LLMClient {
+	return LLMClient{
+		Chat:       newChatFn(client, logger, modelMap, fallbacks, seed, reasoningModels),
+		ChatJSON:   newChatJSONFn(client, logger, modelMap, fallbacks, seed, reasoningModels),
+		ChatStream: newChatStreamFn(client, logger, modelMap, fallbacks, seed, reasoningModels),
 	}
 }
 
-func newChatFn(client *openai.Client, logger *slog.Logger, modelMap map[ModelTier]string) ChatFn {
+func newChatFn(client *openai.Client, logger *slog.Logger, modelMap map[ModelTier]string, fallbacks []string, seed *int, reasoningModels []string) ChatFn {
 	return func(ctx context.Context, systemPrompt, userMessage string, opts *ChatOptions) (string, error) {
 		if opts == nil {
 			defaultOpts := defaultChatOptions()
 			opts = &defaultOpts
 		}
 
-		modelName := getModelName(opts.Model, modelMap)
+		modelName, temperature := applyModelOverride(ctx, getModelName(opts.Model, modelMap), opts.Temperature)
 
 		logger.Debug("creating chat completion",
 			slog.String("model", modelName),
-			slog.Float64("temperature", float64(opts.Temperature)),
+			slog.Float64("temperature", float64(temperature)),
 			slog.Int("user_message_len", len(userMessage)),
 		)
 
@@ -89,23 +102,28 @@ func newChatFn(client *openai.Client, logger *slog.Logger, modelMap map[ModelTie
 					Role:    openai.ChatMessageRoleSystem,
 					Content: systemPrompt,
 				},
-				{
-					Role:    openai.ChatMessageRoleUser,
-					Content: userMessage,
-				},
+				newOpenAIUserMessage(userMessage, opts.Images),
 			},
-			Temperature: opts.Temperature,
+			Temperature: temperature,
+			Seed:        resolveSeed(ctx, opts.Seed, seed),
 		}
 
 		if opts.MaxTokens > 0 {
 			req.MaxTokens = opts.MaxTokens
 		}
 
-		resp, err := client.CreateChatCompletion(ctx, req)
+		resp, modelName, err := withModelFallback(ctx, modelName, fallbacks, isOpenAIFallbackError, logger,
+			func(model string) (openai.ChatCompletionResponse, error) {
+				return client.CreateChatCompletion(ctx, adaptOpenAIRequest(req, model, opts.ReasoningEffort, reasoningModels))
+			},
+		)
 		if err != nil {
-			return "", fmt.Errorf("OpenAI API error: %w", err)
+			return "", fmt.Errorf("OpenAI API error: %w", asContextLengthError(err, modelName))
 		}
 
+		recordSystemFingerprint(ctx, resp.SystemFingerprint)
+		recordOpenAIUsage(ctx, modelName, resp.Usage)
+
 		if len(resp.Choices) == 0 {
 			return "", errors.New("no response from OpenAI")
 		}
@@ -126,7 +144,7 @@ func newChatFn(client *openai.Client, logger *slog.Logger, modelMap map[ModelTie
 	}
 }
 
-func newChatJSONFn(client *openai.Client, logger *slog.Logger, modelMap map[ModelTier]string) ChatJSONFn {
+func newChatJSONFn(client *openai.Client, logger *slog.Logger, modelMap map[ModelTier]string, fallbacks []string, seed *int, reasoningModels []string) ChatJSONFn {
 	return func(ctx context.Context, systemPrompt, userMessage string, opts *ChatJSONOptions, result any) error {
 		if opts == nil {
 			defaultOpts := defaultChatJSONOptions()
@@ -148,12 +166,10 @@ func newChatJSONFn(client *openai.Client, logger *slog.Logger, modelMap map[Mode
 					Role:    openai.ChatMessageRoleSystem,
 					Content: systemPrompt,
 				},
-				{
-					Role:    openai.ChatMessageRoleUser,
-					Content: userMessage,
-				},
+				newOpenAIUserMessage(userMessage, opts.Images),
 			},
 			Temperature: opts.Temperature,
+			Seed:        resolveSeed(ctx, opts.Seed, seed),
 			ResponseFormat: &openai.ChatCompletionResponseFormat{
 				Type: openai.ChatCompletionResponseFormatTypeJSONObject,
 			},
@@ -163,11 +179,18 @@ func newChatJSONFn(client *openai.Client, logger *slog.Logger, modelMap map[Mode
 			req.MaxTokens = opts.MaxTokens
 		}
 
-		resp, err := client.CreateChatCompletion(ctx, req)
+		resp, modelName, err := withModelFallback(ctx, modelName, fallbacks, isOpenAIFallbackError, logger,
+			func(model string) (openai.ChatCompletionResponse, error) {
+				return client.CreateChatCompletion(ctx, adaptOpenAIRequest(req, model, opts.ReasoningEffort, reasoningModels))
+			},
+		)
 		if err != nil {
-			return fmt.Errorf("OpenAI API error: %w", err)
+			return fmt.Errorf("OpenAI API error: %w", asContextLengthError(err, modelName))
 		}
 
+		recordSystemFingerprint(ctx, resp.SystemFingerprint)
+		recordOpenAIUsage(ctx, modelName, resp.Usage)
+
 		if len(resp.Choices) == 0 {
 			return errors.New("no response from OpenAI")
 		}
@@ -178,7 +201,7 @@ func newChatJSONFn(client *openai.Client, logger *slog.Logger, modelMap map[Mode
 		}
 
 		if err := json.Unmarshal([]byte(content), result); err != nil {
-			return fmt.Errorf("failed to parse OpenAI JSON response: %w (content: %s)", err, content)
+			return &responseParseError{provider: "OpenAI", content: content, err: err}
 		}
 
 		logger.Debug("JSON chat completion successful",
@@ -192,18 +215,18 @@ func newChatJSONFn(client *openai.Client, logger *slog.Logger, modelMap map[Mode
 	}
 }
 
-func newChatStreamFn(client *openai.Client, logger *slog.Logger, modelMap map[ModelTier]string) ChatStreamFn {
+func newChatStreamFn(client *openai.Client, logger *slog.Logger, modelMap map[ModelTier]string, fallbacks []string, seed *int, reasoningModels []string) ChatStreamFn {
 	return func(ctx context.Context, systemPrompt, userMessage string, opts *ChatOptions, onToken func(token string)) (string, error) {
 		if opts == nil {
 			defaultOpts := defaultChatOptions()
 			opts = &defaultOpts
 		}
 
-		modelName := getModelName(opts.Model, modelMap)
+		modelName, temperature := applyModelOverride(ctx, getModelName(opts.Model, modelMap), opts.Temperature)
 
 		logger.Debug("creating streaming chat completion",
 			slog.String("model", modelName),
-			slog.Float64("temperature", float64(opts.Temperature)),
+			slog.Float64("temperature", float64(temperature)),
 			slog.Int("user_message_len", len(userMessage)),
 		)
 
@@ -214,22 +237,27 @@ func newChatStreamFn(client *openai.Client, logger *slog.Logger, modelMap map[Mo
 					Role:    openai.ChatMessageRoleSystem,
 					Content: systemPrompt,
 				},
-				{
-					Role:    openai.ChatMessageRoleUser,
-					Content: userMessage,
-				},
+				newOpenAIUserMessage(userMessage, opts.Images),
 			},
-			Temperature: opts.Temperature,
+			Temperature: temperature,
+			Seed:        resolveSeed(ctx, opts.Seed, seed),
 			Stream:      true,
+			StreamOptions: &openai.StreamOptions{
+				IncludeUsage: true,
+			},
 		}
 
 		if opts.MaxTokens > 0 {
 			req.MaxTokens = opts.MaxTokens
 		}
 
-		stream, err := client.CreateChatCompletionStream(ctx, req)
+		stream, modelName, err := withModelFallback(ctx, modelName, fallbacks, isOpenAIFallbackError, logger,
+			func(model string) (*openai.ChatCompletionStream, error) {
+				return client.CreateChatCompletionStream(ctx, adaptOpenAIRequest(req, model, opts.ReasoningEffort, reasoningModels))
+			},
+		)
 		if err != nil {
-			return "", fmt.Errorf("OpenAI streaming API error: %w", err)
+			return "", fmt.Errorf("OpenAI streaming API error: %w", asContextLengthError(err, modelName))
 		}
 		defer stream.Close()
 
@@ -244,6 +272,15 @@ func newChatStreamFn(client *openai.Client, logger *slog.Logger, modelMap map[Mo
 				break
 			}
 
+			if response.SystemFingerprint != "" {
+				recordSystemFingerprint(ctx, response.SystemFingerprint)
+			}
+
+			// Usage arrives in a final chunk without choices
+			if response.Usage != nil {
+				recordOpenAIUsage(ctx, modelName, *response.Usage)
+			}
+
 			if len(response.Choices) > 0 {
 				delta := response.Choices[0].Delta.Content
 				if delta != "" {
@@ -263,3 +300,42 @@ func newChatStreamFn(client *openai.Client, logger *slog.Logger, modelMap map[Mo
 		return fullContent, nil
 	}
 }
+
+// newOpenAIUserMessage builds the user message, using multi-part content when images are attached.
+func newOpenAIUserMessage(userMessage string, images []Image) openai.ChatCompletionMessage {
+	if len(images) == 0 {
+		return openai.ChatCompletionMessage{
+			Role:    openai.ChatMessageRoleUser,
+			Content: userMessage,
+		}
+	}
+
+	parts := []openai.ChatMessagePart{
+		{Type: openai.ChatMessagePartTypeText, Text: userMessage},
+	}
+	for _, image := range images {
+		parts = append(parts, openai.ChatMessagePart{
+			Type:     openai.ChatMessagePartTypeImageURL,
+			ImageURL: &openai.ChatMessageImageURL{URL: image.dataURL()},
+		})
+	}
+
+	return openai.ChatCompletionMessage{
+		Role:         openai.ChatMessageRoleUser,
+		MultiContent: parts,
+	}
+}
+
+// recordOpenAIUsage records the token usage of a completion served by model,
+// including prompt tokens served from OpenAI's automatic prompt cache.
+func recordOpenAIUsage(ctx context.Context, model string, usage openai.Usage) {
+	recorded := TokenUsage{
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		TotalTokens:      usage.PromptTokens + usage.CompletionTokens,
+	}
+	if usage.PromptTokensDetails != nil {
+		recorded.CachedTokens = usage.PromptTokensDetails.CachedTokens
+	}
+	recordModelUsage(ctx, model, recorded)
+}
@@ -1,9 +1,17 @@
 package aichat
 
 import (
+	"errors"
+	"fmt"
 	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/go-chi/cors"
+
 	openai "github.com/sashabaranov/go-openai"
 )
 
@@ -12,43 +20,205 @@ type Config struct {
 	// OpenAIClient is the OpenAI-compatible client to use.
 	// For OpenAI: use openai.NewClient(apiKey)
 	// For OpenRouter: use aichat.NewOpenRouterClient(cfg)
+	// Not required when LLMClient is set.
 	OpenAIClient *openai.Client
 
+	// ModelFallbacks lists model names to try in order when a call to the mapped model
+	// fails with a rate limit, server or context-length error (optional). Other errors,
+	// such as invalid JSON in a response, do not trigger a fallback.
+	ModelFallbacks []string
+
+	// ReasoningModels lists OpenAI reasoning model names (defaults to the o1, o3, o4 and
+	// gpt-5 families). Names also match their variants and snapshots, e.g. "o3" matches
+	// "o3-mini". Calls to these models send ChatOptions.ReasoningEffort and max_completion_tokens,
+	// and omit the temperature, which they reject.
+	ReasoningModels []string
+
+	// Seed is the default sampling seed for SDK LLM calls (optional). Set it in
+	// integration tests to request reproducible completions from providers that
+	// support seeding; ChatResult.SystemFingerprint reports when the backend changed.
+	// ChatRequest.Seed overrides it per request.
+	Seed *int
+
+	// LLMClient replaces the OpenAI-backed client for all SDK LLM calls (optional).
+	// For Anthropic, Bedrock or Gemini: use aichat.NewAnthropicClient, NewBedrockClient
+	// or NewGeminiClient. ModelMap and ModelFallbacks do not apply; configure model
+	// names on the client instead.
+	LLMClient LLMClient
+
 	// ModelMap overrides the default model tier to model name mapping.
 	// Use this when using OpenRouter or other providers with different model names.
 	// If nil, defaults to OpenAI model names (gpt-4o-mini, gpt-4o).
 	// See DefaultOpenRouterModelMap() and GPTOpenRouterModelMap() for presets.
 	ModelMap map[ModelTier]string
 
+	// LLMMiddleware wraps every LLM call made by the SDK (optional).
+	// Middleware is applied in order, so the first entry is the outermost wrapper.
+	// See LLMLoggingMiddleware and LLMPromptPrefixMiddleware for built-ins.
+	LLMMiddleware []LLMMiddleware
+
+	// EnablePromptCache caches LLM completions made at temperature 0, keyed by the exact
+	// prompt and model tier, so repeated identical deterministic calls skip the API.
+	EnablePromptCache bool
+
 	// Logger is the structured logger to use. If nil, a default logger is used.
 	Logger *slog.Logger
 
+	// ConversationLocker serializes turns on the same conversation (optional,
+	// defaults to NewMemoryConversationLocker). Set a distributed lock when several
+	// replicas share a store.
+	ConversationLocker LockConversationFn
+
+	// RejectConcurrentTurns makes a message sent while another turn on the same
+	// conversation is in progress fail with ErrConversationBusy (HTTP 409) instead of
+	// waiting for that turn to finish.
+	RejectConcurrentTurns bool
+
+	// MaxConversationTokens caps the LLM tokens a conversation may use across all
+	// its turns (optional). Once reached, further chats on the conversation fail
+	// with ErrTokenBudgetExceeded (HTTP 402). Zero means no limit.
+	MaxConversationTokens int
+
+	// ModelPricing sets prices by model name for the cost estimates of
+	// SDK.ConversationUsage (optional). Entries are added to DefaultModelPricing,
+	// replacing defaults of the same name.
+	ModelPricing map[string]ModelPrice
+
+	// AllowedModels lists the model names callers may request per chat with
+	// ChatRequest.Model (optional). Requests for other models are rejected with
+	// ErrInvalidInput; when empty, model overrides are rejected altogether.
+	AllowedModels []string
+
+	// Persona is prepended to the system prompt of every free-text LLM call (Chat and
+	// ChatStream), including the formatter's and those experts make through
+	// SDK.LLMClient, e.g. "You are Max, the friendly assistant of Acme Tools." (optional).
+	// It keeps experts brand-neutral when they are shared between brands.
+	Persona string
+
+	// Personas are named personas that a request can select with ChatRequest.Persona,
+	// overriding Persona (optional). Requests naming an unknown persona fail with ErrInvalidInput.
+	Personas map[string]string
+
+	// ReturnTrace attaches a trace of every LLM call made for a chat (pipeline step,
+	// model, duration, token usage and error) to ChatResult.Trace. HTTP clients receive
+	// it only when they send "trace": true. Off by default, as it exposes internals.
+	ReturnTrace bool
+
+	// LogPrompts controls debug logging of the prompts and responses of every SDK
+	// LLM call: off (default), hashed, redacted (personal data masked) or full.
+	LogPrompts PromptLogLevel
+
 	// Experts defines the available experts with their metadata and handlers.
 	// Each expert is responsible for resolving any entity data it needs using req.EntityID.
 	Experts map[ExpertType]Expert
 
-	// DefaultExpert is the fallback expert type when routing fails.
+	// EntityResolver loads the entity for a request's EntityID and passes it to the
+	// selected expert as ExpertRequest.Entity (optional). Use it when most experts
+	// just need the entity loaded by ID; experts can still fetch their own data.
+	EntityResolver EntityResolverFn
+
+	// DefaultExpert is the fallback expert type when routing fails. It must be one of Experts.
 	DefaultExpert ExpertType
 
+	// DefaultExpertResolver picks the default expert per request, e.g. per market
+	// (optional). When it returns "" or an unknown expert, DefaultExpert is used.
+	DefaultExpertResolver DefaultExpertResolverFn
+
 	// DefaultReasoning is the default reasoning when falling back to default expert.
 	DefaultReasoning string
 
+	// NoMatchResponse is the answer given, in the user's language, when the router picks
+	// no known expert and DefaultExpert is not set (optional), e.g. "I'm not sure I can
+	// help with that, could you rephrase?". When empty, the router's reasoning is returned.
+	NoMatchResponse string
+
 	// RouterSystemPromptTemplate is the template for the router's system prompt (optional).
 	// Use {{EXPERTS}} placeholder for expert definitions and {{CONTEXT}} for entity context.
 	RouterSystemPromptTemplate string
 
+	// SummarySystemPrompt is the system prompt for conversation summaries
+	// (optional, defaults to DefaultSummarySystemPrompt).
+	SummarySystemPrompt string
+
+	// SummaryModel is the model tier for conversation summaries (defaults to ModelNano).
+	SummaryModel ModelTier
+
+	// GenerateSuggestions adds up to three suggested follow-up questions, in the
+	// user's language, to every answer. It costs one extra ModelNano call per chat,
+	// made concurrently with formatting; if it fails, the answer has no suggestions.
+	GenerateSuggestions bool
+
+	// HealthCheck verifies the LLM provider is reachable for GET /health/ready
+	// (optional, defaults to NewOpenAIHealthCheck when OpenAIClient is set).
+	// Results are cached for 30 seconds.
+	HealthCheck HealthCheckFn
+
 	// Storage is the conversation store (optional, defaults to in-memory).
 	Storage ConversationStore
 
+	// AllowClientConversationIDs lets clients start a conversation under their own ID,
+	// e.g. a ticket number: a chat with an unknown ConversationID creates it instead of
+	// failing with ErrConversationNotFound. IDs may contain letters, digits, '-' and '_'
+	// (at most 128 characters). Only enable it when clients are trusted to pick IDs,
+	// since anyone who knows an ID can continue its conversation.
+	AllowClientConversationIDs bool
+
+	// DisablePersistence processes every chat as if ChatRequest.Ephemeral were set:
+	// nothing is stored and no history is loaded, so each message is answered on its
+	// own. Conversation IDs are still generated for correlation.
+	DisablePersistence bool
+
+	// DisableImages rejects messages with images with ErrImagesNotSupported before any
+	// LLM call. Set it when the LLM client or its models can't process images.
+	DisableImages bool
+
+	// IDGenerator generates the IDs of new conversations and messages (optional,
+	// defaults to the store's IDs and random UUIDs). See the idgen subpackage for
+	// ULIDs and prefixed IDs.
+	IDGenerator IDGenerator
+
 	// FormatterSystemPrompt is a custom system prompt for the formatter (optional).
 	FormatterSystemPrompt string
 
 	// TranslatorSystemPrompt is a custom system prompt for the translator (optional).
 	TranslatorSystemPrompt string
 
+	// LanguageDetector detects the message language without an LLM call (optional,
+	// defaults to NewLanguageDetector). Messages detected as English skip translation.
+	LanguageDetector DetectLanguageFn
+
+	// LanguageDetectionThreshold is the minimum detector confidence required to skip
+	// LLM translation (defaults to 0.6). Lower-confidence messages are translated by the LLM.
+	LanguageDetectionThreshold float64
+
+	// HTTPMiddleware wraps every route of the HTTP handler, after the SDK's request ID,
+	// recovery, logging, timeout and CORS middleware and before Config.Authenticator
+	// (optional). The first entry is the outermost.
+	HTTPMiddleware []func(http.Handler) http.Handler
+
+	// Authenticator authenticates requests to the HTTP handler (optional).
+	// When set, every route except the health checks rejects unauthenticated requests
+	// with 401, and handlers can read the caller with PrincipalFromContext.
+	// Conversations are only accessible to the principal that created them.
+	// See NewAPIKeyAuthenticator and NewJWTAuthenticator.
+	Authenticator AuthenticateFn
+
 	// AllowedOrigins for CORS. Must be explicitly configured unless DevMode is enabled.
 	AllowedOrigins []string
 
+	// CORSAllowCredentials lets browsers send cookies and HTTP auth with cross-origin
+	// requests (optional). It defaults to true, except with a wildcard ("*") origin,
+	// for which browsers never send credentials. Outside DevMode, setting it to true
+	// with a wildcard origin is rejected, since that would trust every site.
+	CORSAllowCredentials *bool
+
+	// CORSAllowedHeaders lists request headers allowed in cross-origin requests
+	// (defaults to Content-Type and Authorization).
+	CORSAllowedHeaders []string
+
+	// CORSAllowedMethods lists methods allowed in cross-origin requests (defaults to GET, POST, PATCH and OPTIONS).
+	CORSAllowedMethods []string
+
 	// DevMode enables permissive settings for development (e.g., allows all CORS origins).
 	// IMPORTANT: Do not enable in production.
 	DevMode bool
@@ -56,11 +226,48 @@ type Config struct {
 	// RequestTimeout is the maximum duration for a request (defaults to 30s).
 	RequestTimeout time.Duration
 
+	// ShutdownTimeout is how long ListenAndServe waits for in-flight requests to finish
+	// after its context is cancelled before cancelling them (defaults to 30s).
+	ShutdownTimeout time.Duration
+
 	// MaxRequestBodySize is the maximum size of a request body in bytes (defaults to 1MB).
 	MaxRequestBodySize int64
 
 	// MaxMessageLength is the maximum length of a message in characters (defaults to 1000).
 	MaxMessageLength int
+
+	// MaxBatchSize is the maximum number of requests in a POST /chat/batch call (defaults to 50).
+	MaxBatchSize int
+
+	// BatchConcurrency is the number of batch items processed concurrently (defaults to 4).
+	BatchConcurrency int
+
+	// BatchTimeout is the maximum duration of a POST /chat/batch request, which
+	// replaces RequestTimeout for batches (defaults to 5m).
+	BatchTimeout time.Duration
+
+	// QuotaLimiter enforces Expert.Quota (optional, defaults to NewMemoryQuotaLimiter).
+	QuotaLimiter QuotaLimiterFn
+
+	// InjectionGuard detects prompt-injection and jailbreak attempts before any LLM call (optional).
+	// Set InjectionGuard.Mode to enable it.
+	InjectionGuard InjectionGuardConfig
+
+	// OutputModerator checks every answer before it is stored or returned (optional).
+	// Flagged answers are replaced with ModerationFallback. When set, streamed content
+	// is withheld until the answer has been moderated. See NewOpenAIModerator.
+	OutputModerator ModerateFn
+
+	// ModerationFallback is the answer returned in place of a flagged answer
+	// (defaults to "I'm sorry, but I can't provide that response.").
+	ModerationFallback string
+
+	// ResponseCache enables semantic caching of answers from experts marked Cacheable (optional).
+	// Set ResponseCache.Embed to enable it.
+	ResponseCache ResponseCacheConfig
+
+	// Webhooks configures asynchronous delivery of events such as chat.completed (optional).
+	Webhooks WebhookConfig
 }
 
 // DefaultRouterSystemPromptTemplate is the default template for the router.
@@ -82,18 +289,62 @@ func (c *Config) applyDefaults() {
 		c.Logger = slog.Default()
 	}
 
+	if c.SummarySystemPrompt == "" {
+		c.SummarySystemPrompt = DefaultSummarySystemPrompt
+	}
+
+	if c.SummaryModel == "" {
+		c.SummaryModel = ModelNano
+	}
+
+	if c.ConversationLocker == nil {
+		c.ConversationLocker = NewMemoryConversationLocker()
+	}
+
+	pricing := DefaultModelPricing()
+	maps.Copy(pricing, c.ModelPricing)
+	c.ModelPricing = pricing
+
+	if c.ReasoningModels == nil {
+		c.ReasoningModels = defaultReasoningModels
+	}
+
+	if c.LogPrompts == "" {
+		c.LogPrompts = PromptLogOff
+	}
+
 	if c.RouterSystemPromptTemplate == "" {
 		c.RouterSystemPromptTemplate = DefaultRouterSystemPromptTemplate
 	}
 
+	if c.LanguageDetector == nil {
+		c.LanguageDetector = NewLanguageDetector()
+	}
+
+	if c.LanguageDetectionThreshold == 0 {
+		c.LanguageDetectionThreshold = 0.6
+	}
+
 	if len(c.AllowedOrigins) == 0 && c.DevMode {
 		c.AllowedOrigins = []string{"*"}
 	}
 
+	if len(c.CORSAllowedHeaders) == 0 {
+		c.CORSAllowedHeaders = []string{"Content-Type", "Authorization"}
+	}
+
+	if len(c.CORSAllowedMethods) == 0 {
+		c.CORSAllowedMethods = []string{"GET", "POST", "PATCH", "OPTIONS"}
+	}
+
 	if c.RequestTimeout == 0 {
 		c.RequestTimeout = 30 * time.Second
 	}
 
+	if c.ShutdownTimeout == 0 {
+		c.ShutdownTimeout = 30 * time.Second
+	}
+
 	if c.MaxRequestBodySize == 0 {
 		c.MaxRequestBodySize = 1 << 20 // 1MB
 	}
@@ -101,4 +352,110 @@ func (c *Config) applyDefaults() {
 	if c.MaxMessageLength == 0 {
 		c.MaxMessageLength = 1000
 	}
+
+	if c.MaxBatchSize == 0 {
+		c.MaxBatchSize = 50
+	}
+
+	if c.BatchConcurrency == 0 {
+		c.BatchConcurrency = 4
+	}
+
+	if c.BatchTimeout == 0 {
+		c.BatchTimeout = 5 * time.Minute
+	}
+
+	if c.QuotaLimiter == nil {
+		c.QuotaLimiter = NewMemoryQuotaLimiter()
+	}
+
+	if c.ResponseCache.Embed != nil {
+		c.ResponseCache.applyDefaults()
+	}
+
+	if c.ModerationFallback == "" {
+		c.ModerationFallback = "I'm sorry, but I can't provide that response."
+	}
+
+	if c.InjectionGuard.enabled() {
+		c.InjectionGuard.applyDefaults()
+	}
+
+	if c.Webhooks.enabled() {
+		c.Webhooks.applyDefaults()
+	}
+}
+
+// validateBatch checks the batch settings, which applyDefaults leaves negative values of.
+func (c *Config) validateBatch() error {
+	if c.MaxBatchSize < 0 {
+		return errors.New("MaxBatchSize must not be negative")
+	}
+	if c.BatchConcurrency < 0 {
+		return errors.New("BatchConcurrency must not be negative")
+	}
+	if c.BatchTimeout < 0 {
+		return errors.New("BatchTimeout must not be negative")
+	}
+	return nil
+}
+
+// validateCORS checks the CORS settings for combinations browsers reject.
+func (c *Config) validateCORS() error {
+	if len(c.AllowedOrigins) == 0 {
+		return errors.New("AllowedOrigins must be configured (or enable DevMode)")
+	}
+
+	allowCredentials := c.CORSAllowCredentials != nil && *c.CORSAllowCredentials
+	if allowCredentials && !c.DevMode && slices.Contains(c.AllowedOrigins, "*") {
+		return errors.New("CORSAllowCredentials cannot be combined with a wildcard AllowedOrigins entry; list the allowed origins instead")
+	}
+
+	return nil
+}
+
+// validateExperts checks that every expert can be dispatched to and that DefaultExpert
+// names one of them, reporting all offenders at once.
+func (c *Config) validateExperts() error {
+	var problems []string
+	for _, expertType := range slices.Sorted(maps.Keys(c.Experts)) {
+		switch {
+		case expertType == "":
+			problems = append(problems, "an expert is registered under an empty type")
+		case expertType == noMatchExpert:
+			problems = append(problems, fmt.Sprintf("expert type %q is reserved", expertType))
+		case c.Experts[expertType].Handler == nil:
+			problems = append(problems, fmt.Sprintf("expert %q has no Handler", expertType))
+		}
+	}
+
+	if c.DefaultExpert != "" {
+		if _, ok := c.Experts[c.DefaultExpert]; !ok {
+			problems = append(problems, fmt.Sprintf("DefaultExpert %q is not a configured expert", c.DefaultExpert))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid expert configuration: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// corsAllowCredentials reports whether cross-origin requests may carry credentials.
+func (c *Config) corsAllowCredentials() bool {
+	if c.CORSAllowCredentials != nil {
+		return *c.CORSAllowCredentials
+	}
+	return !slices.Contains(c.AllowedOrigins, "*")
+}
+
+// corsOptions builds the CORS middleware options from the config.
+func (c *Config) corsOptions() cors.Options {
+	return cors.Options{
+		AllowedOrigins:   c.AllowedOrigins,
+		AllowedMethods:   c.CORSAllowedMethods,
+		AllowedHeaders:   c.CORSAllowedHeaders,
+		AllowCredentials: c.corsAllowCredentials(),
+		MaxAge:           300, // 5 minutes
+	}
 }
@@ -0,0 +1,127 @@
+package aichat
+
+import (
+	"context"
+	"errors"
+	"slices"
+)
+
+// conversationOwner returns the owner recorded on conversations created in ctx:
+// the authenticated principal's tenant and ID, or "" outside authenticated requests.
+func conversationOwner(ctx context.Context) (string, bool) {
+	principal := PrincipalFromContext(ctx)
+	if principal == nil {
+		return "", false
+	}
+	if principal.Tenant == "" {
+		return principal.ID, true
+	}
+	return principal.Tenant + "/" + principal.ID, true
+}
+
+// canAccess reports whether the caller in ctx may access conversation. Calls
+// without an authenticated principal, such as direct SDK calls, may access all
+// conversations; principals only those they created.
+func canAccess(ctx context.Context, conversation *Conversation) bool {
+	owner, ok := conversationOwner(ctx)
+	return !ok || conversation.Owner == owner
+}
+
+// withConversationOwnership wraps a store so that authenticated callers only see
+// the conversations they created, as if others did not exist. New conversations
+// record their creator in Conversation.Owner.
+func withConversationOwnership(store ConversationStore) ConversationStore {
+	get := func(ctx context.Context, id string) (*Conversation, error) {
+		conversation, err := store.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if !canAccess(ctx, conversation) {
+			return nil, ErrConversationNotFound
+		}
+		return conversation, nil
+	}
+
+	wrapped := store
+	wrapped.Get = get
+
+	wrapped.Create = func(ctx context.Context, entityID string) (*Conversation, error) {
+		conversation, err := store.Create(ctx, entityID)
+		if err != nil {
+			return nil, err
+		}
+		if owner, ok := conversationOwner(ctx); ok && owner != "" {
+			conversation.Owner = owner
+			if err := store.Save(ctx, conversation); err != nil {
+				return nil, err
+			}
+		}
+		return conversation, nil
+	}
+
+	wrapped.Save = func(ctx context.Context, conversation *Conversation) error {
+		owner, ok := conversationOwner(ctx)
+		if ok {
+			// Never overwrite another principal's conversation, e.g. under a guessed client ID
+			existing, err := store.Get(ctx, conversation.ID)
+			switch {
+			case err == nil && existing.Owner != owner:
+				return ErrConversationNotFound
+			case err != nil && !errors.Is(err, ErrConversationNotFound):
+				return err
+			}
+			conversation.Owner = owner
+		}
+		return store.Save(ctx, conversation)
+	}
+
+	wrapped.AddMessage = func(ctx context.Context, id string, msg Message) error {
+		if _, err := get(ctx, id); err != nil {
+			return err
+		}
+		return store.AddMessage(ctx, id, msg)
+	}
+
+	if store.List != nil {
+		wrapped.List = func(ctx context.Context, filter ConversationFilter) ([]*Conversation, error) {
+			if owner, ok := conversationOwner(ctx); ok {
+				filter.Owner = owner
+			}
+			conversations, err := store.List(ctx, filter)
+			if err != nil {
+				return nil, err
+			}
+			// Custom stores may not filter by owner
+			return slices.DeleteFunc(conversations, func(conversation *Conversation) bool {
+				return !canAccess(ctx, conversation)
+			}), nil
+		}
+	}
+
+	if store.GetFeedback != nil {
+		wrapped.GetFeedback = func(ctx context.Context, messageID string) (*Feedback, error) {
+			feedback, err := store.GetFeedback(ctx, messageID)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := get(ctx, feedback.ConversationID); err != nil {
+				if errors.Is(err, ErrConversationNotFound) {
+					return nil, ErrFeedbackNotFound
+				}
+				return nil, err
+			}
+			return feedback, nil
+		}
+	}
+
+	if store.ListFeedback != nil {
+		wrapped.ListFeedback = func(ctx context.Context, conversationID string) ([]Feedback, error) {
+			if _, err := get(ctx, conversationID); err != nil {
+				return nil, err
+			}
+			return store.ListFeedback(ctx, conversationID)
+		}
+	}
+
+	return wrapped
+}
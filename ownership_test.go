@@ -0,0 +1,79 @@
+package aichat_test
+
+import (
+	"net/http"
+	"testing"
+
+	aichat "github.com/ourstudio-se/ai-chat-sdk"
+	"github.com/ourstudio-se/ai-chat-sdk/aichattest"
+)
+
+func TestConversationsAreScopedToTheirOwner(t *testing.T) {
+	llm := aichattest.NewLLM(script(turn("The Widget Pro has three speeds."), turn("It weighs 2 kg."))...)
+	sdk := newTestSDK(t, llm, aichat.Config{
+		Authenticator: aichat.NewAPIKeyAuthenticator(map[string]aichat.Principal{
+			"key-alice": {ID: "alice", Tenant: "acme"},
+			"key-bob":   {ID: "bob", Tenant: "globex"},
+		}),
+	})
+
+	rec := serve(t, sdk, http.MethodPost, "/chat", "key-alice", map[string]any{"message": "How many speeds?"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("POST /chat status = %d, body %s", rec.Code, rec.Body)
+	}
+	chat := decode[aichat.HTTPChatResponse](t, rec)
+	id := chat.ConversationID
+
+	otherRoutes := []struct {
+		method, path string
+		body         any
+	}{
+		{http.MethodPost, "/chat", map[string]any{"message": "And the weight?", "conversationId": id}},
+		{http.MethodGet, "/conversations/" + id + "/export", nil},
+		{http.MethodGet, "/conversations/" + id + "/summary", nil},
+		{http.MethodGet, "/conversations/" + id + "/usage", nil},
+		{http.MethodPatch, "/conversations/" + id, map[string]any{"tags": []string{"stolen"}}},
+		{http.MethodPost, "/conversations/" + id + "/regenerate", nil},
+		{http.MethodPost, "/conversations/" + id + "/fork", map[string]any{"fromMessageId": chat.MessageID}},
+		{http.MethodPost, "/conversations/" + id + "/truncate", map[string]any{"afterMessageId": chat.MessageID}},
+		{http.MethodPost, "/feedback", map[string]any{"conversationId": id, "messageId": chat.MessageID, "rating": "negative"}},
+		{http.MethodGet, "/feedback?conversationId=" + id, nil},
+	}
+	for _, route := range otherRoutes {
+		if rec := serve(t, sdk, route.method, route.path, "key-bob", route.body); rec.Code != http.StatusNotFound {
+			t.Errorf("%s %s by another principal: status = %d, want 404 (body %s)", route.method, route.path, rec.Code, rec.Body)
+		}
+	}
+
+	// The owner can still continue and read the conversation
+	rec = serve(t, sdk, http.MethodPost, "/chat", "key-alice", map[string]any{"message": "And the weight?", "conversationId": id})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("POST /chat by the owner: status = %d, body %s", rec.Code, rec.Body)
+	}
+	if rec := serve(t, sdk, http.MethodGet, "/conversations/"+id+"/export", "key-alice", nil); rec.Code != http.StatusOK {
+		t.Errorf("GET export by the owner: status = %d, body %s", rec.Code, rec.Body)
+	}
+
+	llm.AssertDone(t)
+}
+
+func TestClientConversationIDsCannotTakeOverAnotherPrincipalsConversation(t *testing.T) {
+	llm := aichattest.NewLLM(turn("Ticket 42 is about a refund.")...)
+	sdk := newTestSDK(t, llm, aichat.Config{
+		AllowClientConversationIDs: true,
+		Authenticator: aichat.NewAPIKeyAuthenticator(map[string]aichat.Principal{
+			"key-alice": {ID: "alice"},
+			"key-bob":   {ID: "bob"},
+		}),
+	})
+
+	body := map[string]any{"message": "What is this ticket about?", "conversationId": "ticket-42"}
+	if rec := serve(t, sdk, http.MethodPost, "/chat", "key-alice", body); rec.Code != http.StatusOK {
+		t.Fatalf("POST /chat by alice: status = %d, body %s", rec.Code, rec.Body)
+	}
+	if rec := serve(t, sdk, http.MethodPost, "/chat", "key-bob", body); rec.Code != http.StatusNotFound {
+		t.Errorf("POST /chat by bob under alice's ID: status = %d, want 404 (body %s)", rec.Code, rec.Body)
+	}
+
+	llm.AssertDone(t)
+}
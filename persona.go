@@ -0,0 +1,98 @@
+package aichat
+
+import (
+	"context"
+	"fmt"
+)
+
+// personaKey is the context key for the persona of the chat being processed.
+type personaKey struct{}
+
+// withPersona returns a context whose Chat and ChatStream calls are prefixed with persona.
+func withPersona(ctx context.Context, persona string) context.Context {
+	if persona == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, personaKey{}, persona)
+}
+
+// PersonaFromContext returns the persona instructions of the chat being processed,
+// or "". The built-in LLM client stack prepends them to the system prompt of every
+// Chat and ChatStream call; experts calling a provider directly can do the same.
+func PersonaFromContext(ctx context.Context) string {
+	persona, _ := ctx.Value(personaKey{}).(string)
+	return persona
+}
+
+// resolvePersona returns the persona for a request: the named entry of personas
+// if the request selects one, else defaultPersona.
+func resolvePersona(req ChatRequest, personas map[string]string, defaultPersona string) (string, error) {
+	if req.Persona == "" {
+		return defaultPersona, nil
+	}
+	persona, ok := personas[req.Persona]
+	if !ok {
+		return "", fmt.Errorf("%w: unknown persona %q", ErrInvalidInput, req.Persona)
+	}
+	return persona, nil
+}
+
+// withPersonas wraps a chat function to resolve the request's persona.
+func withPersonas(processChat ProcessChatFn, personas map[string]string, defaultPersona string) ProcessChatFn {
+	return func(ctx context.Context, req ChatRequest) (*ChatResult, error) {
+		persona, err := resolvePersona(req, personas, defaultPersona)
+		if err != nil {
+			return nil, err
+		}
+		return processChat(withPersona(ctx, persona), req)
+	}
+}
+
+// withPersonasStreaming wraps a streaming chat function to resolve the request's persona.
+func withPersonasStreaming(processChatStream ProcessChatStreamFn, personas map[string]string, defaultPersona string) ProcessChatStreamFn {
+	return func(ctx context.Context, req ChatRequest, stream StreamCallback) (*ChatResult, error) {
+		persona, err := resolvePersona(req, personas, defaultPersona)
+		if err != nil {
+			return nil, err
+		}
+		return processChatStream(withPersona(ctx, persona), req, stream)
+	}
+}
+
+// newPersonaMiddleware returns a middleware that prepends the request's persona to
+// the system prompt of free-text calls. JSON calls (translation, routing) are
+// internal and left alone.
+func newPersonaMiddleware() LLMMiddleware {
+	apply := func(ctx context.Context, systemPrompt string, opts *ChatOptions) (string, *ChatOptions) {
+		persona := PersonaFromContext(ctx)
+		if persona == "" {
+			return systemPrompt, opts
+		}
+		if systemPrompt == "" {
+			return persona, opts
+		}
+
+		// The persona is stable too, so it extends a cacheable prefix
+		prefix := persona + "\n\n"
+		if opts != nil && opts.CacheableSystemPrefix > 0 {
+			shifted := *opts
+			shifted.CacheableSystemPrefix += len(prefix)
+			opts = &shifted
+		}
+		return prefix + systemPrompt, opts
+	}
+
+	return func(next LLMClient) LLMClient {
+		return LLMClient{
+			Chat: func(ctx context.Context, systemPrompt, userMessage string, opts *ChatOptions) (string, error) {
+				systemPrompt, opts = apply(ctx, systemPrompt, opts)
+				return next.Chat(ctx, systemPrompt, userMessage, opts)
+			},
+			ChatJSON: next.ChatJSON,
+			ChatStream: func(ctx context.Context, systemPrompt, userMessage string, opts *ChatOptions, onToken func(token string)) (string, error) {
+				systemPrompt, opts = apply(ctx, systemPrompt, opts)
+				return next.ChatStream(ctx, systemPrompt, userMessage, opts, onToken)
+			},
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package aichat
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+// defaultPromptCacheSize is the maximum number of completions kept by the prompt cache.
+const defaultPromptCacheSize = 1000
+
+// newPromptCacheMiddleware returns a middleware that caches deterministic completions
+// (temperature 0) keyed by a hash of the method, model tier, prompts and max tokens.
+// Calls with nil options use non-zero default temperatures and are never cached,
+// nor are calls with images or per-request model overrides, which are not part of the key.
+func newPromptCacheMiddleware(maxEntries int, logger *slog.Logger) LLMMiddleware {
+	var mu sync.Mutex
+	entries := make(map[string]string)
+	var order []string
+
+	get := func(key string) (string, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		value, ok := entries[key]
+		return value, ok
+	}
+
+	put := func(key, value string) {
+		mu.Lock()
+		defer mu.Unlock()
+		if _, exists := entries[key]; exists {
+			return
+		}
+		entries[key] = value
+		order = append(order, key)
+		if len(order) > maxEntries {
+			delete(entries, order[0])
+			order = order[1:]
+		}
+	}
+
+	return func(next LLMClient) LLMClient {
+		return LLMClient{
+			Chat: func(ctx context.Context, systemPrompt, userMessage string, opts *ChatOptions) (string, error) {
+				if opts == nil || opts.Temperature != 0 || len(opts.Images) > 0 || hasModelOverride(ctx) {
+					return next.Chat(ctx, systemPrompt, userMessage, opts)
+				}
+
+				key := promptCacheKey("chat", opts.Model, opts.MaxTokens, resolveSeed(ctx, opts.Seed, nil), systemPrompt, userMessage)
+				if cached, ok := get(key); ok {
+					logger.Debug("prompt cache hit", slog.String("method", "chat"))
+					return cached, nil
+				}
+
+				response, err := next.Chat(ctx, systemPrompt, userMessage, opts)
+				if err == nil {
+					put(key, response)
+				}
+				return response, err
+			},
+			ChatJSON: func(ctx context.Context, systemPrompt, userMessage string, opts *ChatJSONOptions, result any) error {
+				if opts == nil || opts.Temperature != 0 || len(opts.Images) > 0 {
+					return next.ChatJSON(ctx, systemPrompt, userMessage, opts, result)
+				}
+
+				key := promptCacheKey("chat_json", opts.Model, opts.MaxTokens, resolveSeed(ctx, opts.Seed, nil), systemPrompt, userMessage)
+				if cached, ok := get(key); ok {
+					logger.Debug("prompt cache hit", slog.String("method", "chat_json"))
+					return json.Unmarshal([]byte(cached), result)
+				}
+
+				if err := next.ChatJSON(ctx, systemPrompt, userMessage, opts, result); err != nil {
+					return err
+				}
+				if data, err := json.Marshal(result); err == nil {
+					put(key, string(data))
+				}
+				return nil
+			},
+			ChatStream: func(ctx context.Context, systemPrompt, userMessage string, opts *ChatOptions, onToken func(token string)) (string, error) {
+				if opts == nil || opts.Temperature != 0 || len(opts.Images) > 0 || hasModelOverride(ctx) {
+					return next.ChatStream(ctx, systemPrompt, userMessage, opts, onToken)
+				}
+
+				// Shares entries with Chat: a deterministic completion is the same either way
+				key := promptCacheKey("chat", opts.Model, opts.MaxTokens, resolveSeed(ctx, opts.Seed, nil), systemPrompt, userMessage)
+				if cached, ok := get(key); ok {
+					logger.Debug("prompt cache hit", slog.String("method", "chat_stream"))
+					if onToken != nil {
+						onToken(cached)
+					}
+					return cached, nil
+				}
+
+				response, err := next.ChatStream(ctx, systemPrompt, userMessage, opts, onToken)
+				if err == nil {
+					put(key, response)
+				}
+				return response, err
+			},
+		}
+	}
+}
+
+func promptCacheKey(method string, model ModelTier, maxTokens int, seed *int, systemPrompt, userMessage string) string {
+	seedKey := "-"
+	if seed != nil {
+		seedKey = fmt.Sprint(*seed)
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%d\x00%s\x00%s\x00%s", method, model, maxTokens, seedKey, systemPrompt, userMessage)
+	return hex.EncodeToString(h.Sum(nil))
+}
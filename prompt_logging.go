@@ -0,0 +1,81 @@
+package aichat
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"regexp"
+)
+
+// PromptLogLevel controls how LLM prompts and responses are logged.
+type PromptLogLevel string
+
+const (
+	// PromptLogOff disables prompt logging.
+	PromptLogOff PromptLogLevel = "off"
+
+	// PromptLogHashed logs a hash and the length of each prompt and response,
+	// so identical prompts can be correlated without logging their content.
+	PromptLogHashed PromptLogLevel = "hashed"
+
+	// PromptLogRedacted logs prompts and responses with emails, phone numbers,
+	// card numbers and similar personal data replaced by placeholders.
+	PromptLogRedacted PromptLogLevel = "redacted"
+
+	// PromptLogFull logs prompts and responses verbatim. Avoid in production.
+	PromptLogFull PromptLogLevel = "full"
+)
+
+// piiPatterns match personal data replaced in PromptLogRedacted logs, in order.
+var piiPatterns = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`), "[EMAIL]"},
+	{regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]{4}){2,7}(?: ?[A-Z0-9]{1,4})?\b`), "[IBAN]"},
+	{regexp.MustCompile(`\b(?:\d[ \-]?){13,19}\b`), "[CARD]"},
+	{regexp.MustCompile(`\b\d{6,8}[\-+]?\d{4}\b`), "[NATIONAL_ID]"},
+	{regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`), "[NATIONAL_ID]"},
+	{regexp.MustCompile(`(?:\+\d{1,3}[ \-]?)?\(?\d{2,4}\)?[ \-]?\d{2,4}[ \-]?\d{2,4}(?:[ \-]?\d{2,4})?\b`), "[PHONE]"},
+	{regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`), "[IP]"},
+}
+
+// redactPII replaces personal data in text with placeholders.
+func redactPII(text string) string {
+	for _, p := range piiPatterns {
+		text = p.pattern.ReplaceAllString(text, p.replacement)
+	}
+	return text
+}
+
+// hashForLog returns a short hash and the length of text.
+func hashForLog(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return fmt.Sprintf("sha256:%s len:%d", hex.EncodeToString(sum[:6]), len(text))
+}
+
+// newPromptLoggingMiddleware returns a middleware logging LLM calls at the given level,
+// or nil when level is PromptLogOff.
+func newPromptLoggingMiddleware(level PromptLogLevel, logger *slog.Logger) LLMMiddleware {
+	switch level {
+	case PromptLogHashed:
+		return llmLoggingMiddleware(logger, hashForLog)
+	case PromptLogRedacted:
+		return llmLoggingMiddleware(logger, redactPII)
+	case PromptLogFull:
+		return llmLoggingMiddleware(logger, func(text string) string { return text })
+	default:
+		return nil
+	}
+}
+
+// validate checks that level is a known prompt log level.
+func (level PromptLogLevel) validate() error {
+	switch level {
+	case PromptLogOff, PromptLogHashed, PromptLogRedacted, PromptLogFull:
+		return nil
+	default:
+		return fmt.Errorf("unknown LogPrompts level %q", level)
+	}
+}
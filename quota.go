@@ -0,0 +1,118 @@
+package aichat
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Quota limits how many requests an expert handles within a time window.
+type Quota struct {
+	// Limit is the maximum number of requests per Window. Zero disables the quota.
+	Limit int
+
+	// Window is the sliding window the limit applies to (defaults to one minute).
+	Window time.Duration
+
+	// PerPrincipal applies the limit to each authenticated principal separately
+	// (see Config.Authenticator). Requests without a principal share one count.
+	PerPrincipal bool
+}
+
+// QuotaLimiterFn reports whether a request under key is allowed by quota,
+// recording it against the quota when it is.
+type QuotaLimiterFn func(ctx context.Context, key string, quota Quota) (bool, error)
+
+// NewMemoryQuotaLimiter creates an in-memory sliding-window quota limiter.
+// Counts are per process; use a shared limiter (e.g. Redis-backed) when running several replicas.
+func NewMemoryQuotaLimiter() QuotaLimiterFn {
+	var mu sync.Mutex
+	requests := make(map[string][]time.Time)
+
+	return func(ctx context.Context, key string, quota Quota) (bool, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		now := time.Now()
+		cutoff := now.Add(-quota.Window)
+
+		// Drop requests that have left the window
+		recent := requests[key]
+		i := 0
+		for i < len(recent) && !recent[i].After(cutoff) {
+			i++
+		}
+		recent = recent[i:]
+
+		if len(recent) >= quota.Limit {
+			requests[key] = recent
+			return false, nil
+		}
+
+		requests[key] = append(recent, now)
+		return true, nil
+	}
+}
+
+// withQuotas returns a copy of experts where every expert with a quota
+// rejects requests with ErrQuotaExceeded once its quota is used up.
+func withQuotas(experts map[ExpertType]Expert, limiter QuotaLimiterFn, logger *slog.Logger) map[ExpertType]Expert {
+	wrapped := make(map[ExpertType]Expert, len(experts))
+	for expertType, expert := range experts {
+		if expert.Quota.Limit > 0 {
+			expert = quotaLimitedExpert(expertType, expert, limiter, logger)
+		}
+		wrapped[expertType] = expert
+	}
+	return wrapped
+}
+
+func quotaLimitedExpert(expertType ExpertType, expert Expert, limiter QuotaLimiterFn, logger *slog.Logger) Expert {
+	key := "expert:" + string(expertType)
+	quota := expert.Quota
+	if quota.Window == 0 {
+		quota.Window = time.Minute
+	}
+
+	allow := func(ctx context.Context) error {
+		key := key
+		if owner, ok := conversationOwner(ctx); ok && quota.PerPrincipal {
+			key += ":principal:" + owner
+		}
+
+		allowed, err := limiter(ctx, key, quota)
+		if err != nil {
+			return fmt.Errorf("failed to check quota: %w", err)
+		}
+		if !allowed {
+			logger.Warn("expert quota exceeded",
+				slog.String("expert_type", string(expertType)),
+				slog.Int("limit", quota.Limit),
+				slog.Duration("window", quota.Window),
+			)
+			return ErrQuotaExceeded
+		}
+		return nil
+	}
+
+	handler := expert.Handler
+	expert.Handler = func(ctx context.Context, req ExpertRequest) (*ExpertResult, error) {
+		if err := allow(ctx); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+
+	if streamHandler := expert.StreamHandler; streamHandler != nil {
+		expert.StreamHandler = func(ctx context.Context, req ExpertRequest, stream StreamCallback) (*ExpertResult, error) {
+			if err := allow(ctx); err != nil {
+				return nil, err
+			}
+			return streamHandler(ctx, req, stream)
+		}
+	}
+
+	return expert
+}
@@ -0,0 +1,39 @@
+package aichat
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestQuotaPerPrincipal(t *testing.T) {
+	expert := quotaLimitedExpert("product", Expert{
+		Handler: func(ctx context.Context, req ExpertRequest) (*ExpertResult, error) {
+			return &ExpertResult{}, nil
+		},
+		Quota: Quota{Limit: 1, Window: time.Hour, PerPrincipal: true},
+	}, NewMemoryQuotaLimiter(), slog.New(slog.DiscardHandler))
+
+	alice := withPrincipal(context.Background(), &Principal{ID: "alice", Tenant: "acme"})
+	bob := withPrincipal(context.Background(), &Principal{ID: "bob", Tenant: "acme"})
+
+	calls := []struct {
+		name    string
+		ctx     context.Context
+		allowed bool
+	}{
+		{"alice", alice, true},
+		{"alice again", alice, false},
+		{"bob", bob, true},
+		{"anonymous", context.Background(), true},
+		{"anonymous again", context.Background(), false},
+	}
+	for _, call := range calls {
+		_, err := expert.Handler(call.ctx, ExpertRequest{Message: "How many speeds?"})
+		if allowed := !errors.Is(err, ErrQuotaExceeded); allowed != call.allowed {
+			t.Errorf("%s: error = %v, want allowed = %v", call.name, err, call.allowed)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package aichat
+
+import (
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// ReasoningEffort controls how much a reasoning model thinks before answering.
+type ReasoningEffort string
+
+// Reasoning efforts supported by OpenAI reasoning models.
+const (
+	ReasoningEffortLow    ReasoningEffort = "low"
+	ReasoningEffortMedium ReasoningEffort = "medium"
+	ReasoningEffortHigh   ReasoningEffort = "high"
+)
+
+// defaultReasoningModels are the OpenAI model families that reject sampling parameters.
+var defaultReasoningModels = []string{"o1", "o3", "o4", "gpt-5"}
+
+// isReasoningModel reports whether model is one of reasoningModels, or a variant
+// or snapshot of one (e.g. "o3-mini-2025-01-31" for "o3").
+func isReasoningModel(model string, reasoningModels []string) bool {
+	for _, name := range reasoningModels {
+		if model == name || strings.HasPrefix(model, name+"-") {
+			return true
+		}
+	}
+	return false
+}
+
+// adaptOpenAIRequest returns req adjusted for model: reasoning models take
+// max_completion_tokens and a reasoning effort instead of max_tokens and a temperature.
+func adaptOpenAIRequest(req openai.ChatCompletionRequest, model string, effort ReasoningEffort, reasoningModels []string) openai.ChatCompletionRequest {
+	req.Model = model
+	if !isReasoningModel(model, reasoningModels) {
+		return req
+	}
+
+	req.Temperature = 0
+	req.MaxCompletionTokens = req.MaxTokens
+	req.MaxTokens = 0
+	req.ReasoningEffort = string(effort)
+	return req
+}
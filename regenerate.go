@@ -0,0 +1,105 @@
+package aichat
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+)
+
+// RegenerateOptions overrides sampling when regenerating a response. Zero values
+// use the configured defaults, as for the fields of the same name on ChatRequest.
+type RegenerateOptions struct {
+	Seed        *int
+	Model       string
+	Temperature *float32
+	Persona     string
+}
+
+// regeneration marks a chat request as regenerating the conversation's last response.
+// Message is set to the user message being answered again.
+type regeneration struct {
+	Message string
+}
+
+// regenerationKey is the context key for the request's *regeneration.
+type regenerationKey struct{}
+
+// withRegenerationRequest returns a context in which the chat pipeline regenerates
+// the last response of req.ConversationID instead of answering req.Message.
+func withRegenerationRequest(ctx context.Context, regen *regeneration) context.Context {
+	return context.WithValue(ctx, regenerationKey{}, regen)
+}
+
+// newRegenerateRequest returns the chat request regenerating the last response of a conversation.
+func newRegenerateRequest(conversationID string, opts RegenerateOptions) ChatRequest {
+	return ChatRequest{
+		ConversationID: conversationID,
+		Seed:           opts.Seed,
+		Model:          opts.Model,
+		Temperature:    opts.Temperature,
+		Persona:        opts.Persona,
+	}
+}
+
+// withRegeneration wraps a chat function to handle regeneration requests: it drops
+// the last user turn and the answers to it, then runs that user message again. It
+// must run under the conversation lock. If the new turn fails, the original
+// messages are restored.
+func withRegeneration(processChat ProcessChatFn, store ConversationStore, logger *slog.Logger) ProcessChatFn {
+	return func(ctx context.Context, req ChatRequest) (*ChatResult, error) {
+		regen, ok := ctx.Value(regenerationKey{}).(*regeneration)
+		if !ok {
+			return processChat(ctx, req)
+		}
+
+		conversation, err := getConversation(ctx, store, req.ConversationID)
+		if err != nil {
+			return nil, err
+		}
+
+		messages := conversation.Messages
+		if len(messages) == 0 || messages[len(messages)-1].Role != RoleAssistant {
+			return nil, ErrNothingToRegenerate
+		}
+		userIndex := lastUserMessage(messages)
+		if userIndex < 0 {
+			return nil, ErrNothingToRegenerate
+		}
+
+		original := *conversation
+		original.Messages = slices.Clone(messages)
+
+		// The pipeline stores the user message again, with a new ID
+		if err := saveTruncated(ctx, store, conversation, userIndex); err != nil {
+			return nil, err
+		}
+
+		userMessage := messages[userIndex]
+		regen.Message = userMessage.Content
+		req.Message = userMessage.Content
+		req.Data = userMessage.Data
+		req.EntityID = conversation.EntityID
+
+		result, err := processChat(ctx, req)
+		if err != nil {
+			if restoreErr := store.Save(context.WithoutCancel(ctx), &original); restoreErr != nil {
+				logger.Error("failed to restore conversation after failed regeneration",
+					"error", restoreErr,
+					"conversation_id", req.ConversationID,
+				)
+			}
+			return nil, err
+		}
+		return result, nil
+	}
+}
+
+// lastUserMessage returns the index of the last user message, or -1.
+func lastUserMessage(messages []Message) int {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == RoleUser {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,271 @@
+package aichat
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"sync"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// EmbedFn converts text into an embedding vector.
+type EmbedFn func(ctx context.Context, text string) ([]float32, error)
+
+// ResponseCacheStore is a struct of functions for semantic response cache persistence.
+// Entries are partitioned by namespace (the expert type, the entity, the request data
+// and the persona), so a cached answer is only ever returned for the expert and input that produced it.
+type ResponseCacheStore struct {
+	// Search returns the most similar cached result in namespace and its cosine similarity,
+	// or a nil result when the namespace is empty.
+	Search func(ctx context.Context, namespace string, embedding []float32) (*ExpertResult, float64, error)
+
+	// Put stores a result under the given embedding.
+	Put func(ctx context.Context, namespace string, embedding []float32, result *ExpertResult) error
+}
+
+// ResponseCacheConfig configures semantic caching of expert responses.
+// Only experts with Cacheable set are cached.
+type ResponseCacheConfig struct {
+	// Embed converts the (translated) question into an embedding (required).
+	// See NewOpenAIEmbedder for an OpenAI-backed implementation.
+	Embed EmbedFn
+
+	// Store holds cached responses (optional, defaults to an in-memory store).
+	Store ResponseCacheStore
+
+	// Threshold is the minimum cosine similarity for a cache hit (defaults to 0.95).
+	Threshold float64
+}
+
+// applyDefaults fills in default values for the response cache config.
+func (c *ResponseCacheConfig) applyDefaults() {
+	if c.Store.Search == nil {
+		c.Store = NewMemoryResponseCache(1000)
+	}
+
+	if c.Threshold == 0 {
+		c.Threshold = 0.95
+	}
+}
+
+// NewOpenAIEmbedder creates an embedding function backed by the OpenAI embeddings API.
+// If model is empty, text-embedding-3-small is used.
+func NewOpenAIEmbedder(client *openai.Client, model openai.EmbeddingModel) EmbedFn {
+	if model == "" {
+		model = openai.SmallEmbedding3
+	}
+
+	return func(ctx context.Context, text string) ([]float32, error) {
+		resp, err := client.CreateEmbeddings(ctx, openai.EmbeddingRequestStrings{
+			Input: []string{text},
+			Model: model,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("OpenAI embeddings API error: %w", err)
+		}
+
+		if len(resp.Data) == 0 {
+			return nil, errors.New("no embedding returned from OpenAI")
+		}
+
+		return resp.Data[0].Embedding, nil
+	}
+}
+
+// NewMemoryResponseCache creates an in-memory semantic response cache.
+// Each namespace keeps at most maxEntries results, evicting the oldest first.
+func NewMemoryResponseCache(maxEntries int) ResponseCacheStore {
+	type entry struct {
+		embedding []float32
+		result    ExpertResult
+	}
+
+	var mu sync.RWMutex
+	namespaces := make(map[string][]entry)
+
+	return ResponseCacheStore{
+		Search: func(ctx context.Context, namespace string, embedding []float32) (*ExpertResult, float64, error) {
+			mu.RLock()
+			defer mu.RUnlock()
+
+			var best *entry
+			bestScore := -1.0
+			for i := range namespaces[namespace] {
+				e := &namespaces[namespace][i]
+				if score := cosineSimilarity(embedding, e.embedding); score > bestScore {
+					best = e
+					bestScore = score
+				}
+			}
+
+			if best == nil {
+				return nil, 0, nil
+			}
+
+			result := best.result
+			return &result, bestScore, nil
+		},
+
+		Put: func(ctx context.Context, namespace string, embedding []float32, result *ExpertResult) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			entries := append(namespaces[namespace], entry{embedding: embedding, result: *result})
+			if maxEntries > 0 && len(entries) > maxEntries {
+				entries = entries[len(entries)-maxEntries:]
+			}
+			namespaces[namespace] = entries
+			return nil
+		},
+	}
+}
+
+// withResponseCache returns a copy of experts where every cacheable expert's
+// handlers consult the semantic response cache before doing any work.
+func withResponseCache(experts map[ExpertType]Expert, cfg ResponseCacheConfig, logger *slog.Logger) map[ExpertType]Expert {
+	wrapped := make(map[ExpertType]Expert, len(experts))
+	for expertType, expert := range experts {
+		if expert.Cacheable {
+			expert = cachedExpert(expertType, expert, cfg, logger)
+		}
+		wrapped[expertType] = expert
+	}
+	return wrapped
+}
+
+// cacheNamespace returns the cache namespace of req. The embedding only covers the
+// message, so requests about a specific entity, with structured data or answered in
+// a persona's voice get their own namespace. It reports false for data that can't be
+// hashed, which is never cached.
+func cacheNamespace(ctx context.Context, expertType ExpertType, req ExpertRequest) (string, bool) {
+	persona := PersonaFromContext(ctx)
+	if req.EntityID == "" && req.Data == nil && persona == "" {
+		return string(expertType), true
+	}
+
+	data, err := json.Marshal(req.Data)
+	if err != nil {
+		return "", false
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s", req.EntityID, data, persona)
+	return string(expertType) + "/" + hex.EncodeToString(h.Sum(nil)), true
+}
+
+func cachedExpert(expertType ExpertType, expert Expert, cfg ResponseCacheConfig, logger *slog.Logger) Expert {
+	lookup := func(ctx context.Context, req ExpertRequest) (*ExpertResult, []float32, string) {
+		// The embedding only covers the text, so questions about images are never cached,
+		// nor are requests experimenting with another model or temperature
+		if len(req.Images) > 0 || hasModelOverride(ctx) {
+			return nil, nil, ""
+		}
+
+		namespace, ok := cacheNamespace(ctx, expertType, req)
+		if !ok {
+			return nil, nil, ""
+		}
+
+		embedding, err := cfg.Embed(ctx, req.Message)
+		if err != nil {
+			logger.Warn("failed to embed message, skipping response cache",
+				slog.String("expert_type", string(expertType)),
+				slog.String("error", err.Error()),
+			)
+			return nil, nil, ""
+		}
+
+		cached, score, err := cfg.Store.Search(ctx, namespace, embedding)
+		if err != nil {
+			logger.Warn("response cache search failed",
+				slog.String("expert_type", string(expertType)),
+				slog.String("error", err.Error()),
+			)
+			return nil, embedding, namespace
+		}
+
+		if cached == nil || score < cfg.Threshold {
+			return nil, embedding, namespace
+		}
+
+		logger.Debug("response cache hit",
+			slog.String("expert_type", string(expertType)),
+			slog.Float64("similarity", score),
+		)
+		cached.Cached = true
+		return cached, embedding, namespace
+	}
+
+	store := func(ctx context.Context, namespace string, embedding []float32, result *ExpertResult) {
+		if embedding == nil || result == nil {
+			return
+		}
+		if err := cfg.Store.Put(ctx, namespace, embedding, result); err != nil {
+			logger.Warn("failed to store response in cache",
+				slog.String("expert_type", string(expertType)),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+
+	handler := expert.Handler
+	expert.Handler = func(ctx context.Context, req ExpertRequest) (*ExpertResult, error) {
+		cached, embedding, namespace := lookup(ctx, req)
+		if cached != nil {
+			return cached, nil
+		}
+
+		result, err := handler(ctx, req)
+		if err == nil {
+			store(ctx, namespace, embedding, result)
+		}
+		return result, err
+	}
+
+	if streamHandler := expert.StreamHandler; streamHandler != nil {
+		expert.StreamHandler = func(ctx context.Context, req ExpertRequest, stream StreamCallback) (*ExpertResult, error) {
+			cached, embedding, namespace := lookup(ctx, req)
+			if cached != nil {
+				stream(StreamEvent{
+					Type:    EventContent,
+					Content: &cached.Answer,
+				})
+				return cached, nil
+			}
+
+			result, err := streamHandler(ctx, req, stream)
+			if err == nil {
+				store(ctx, namespace, embedding, result)
+			}
+			return result, err
+		}
+	}
+
+	return expert
+}
+
+// cosineSimilarity returns the cosine similarity of a and b, or 0 if they are incomparable.
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,54 @@
+package aichat
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+)
+
+func TestCachedExpertPartitionsByEntityDataAndPersona(t *testing.T) {
+	calls := 0
+	expert := cachedExpert("faq", Expert{
+		Handler: func(ctx context.Context, req ExpertRequest) (*ExpertResult, error) {
+			calls++
+			return &ExpertResult{Answer: "answer"}, nil
+		},
+	}, ResponseCacheConfig{
+		Embed: func(ctx context.Context, text string) ([]float32, error) {
+			return []float32{1, 0}, nil
+		},
+		Store:     NewMemoryResponseCache(10),
+		Threshold: 0.95,
+	}, slog.New(slog.DiscardHandler))
+
+	requests := []struct {
+		req     ExpertRequest
+		persona string
+		cached  bool
+	}{
+		{ExpertRequest{Message: "What does it cost?"}, "", false},
+		{ExpertRequest{Message: "What does it cost?"}, "", true},
+		{ExpertRequest{Message: "What does it cost?", EntityID: "xc90"}, "", false},
+		{ExpertRequest{Message: "What does it cost?", EntityID: "xc60"}, "", false},
+		{ExpertRequest{Message: "What does it cost?", EntityID: "xc60"}, "", true},
+		{ExpertRequest{Message: "What does it cost?", Data: map[string]string{"market": "se"}}, "", false},
+		{ExpertRequest{Message: "What does it cost?", Data: map[string]string{"market": "no"}}, "", false},
+		{ExpertRequest{Message: "What does it cost?", Data: map[string]string{"market": "no"}}, "", true},
+		{ExpertRequest{Message: "What does it cost?"}, "Answer like a pirate.", false},
+		{ExpertRequest{Message: "What does it cost?"}, "Answer like a pirate.", true},
+	}
+
+	for i, tt := range requests {
+		ctx := withPersona(context.Background(), tt.persona)
+		result, err := expert.Handler(ctx, tt.req)
+		if err != nil {
+			t.Fatalf("request %d: Handler() error = %v", i, err)
+		}
+		if result.Cached != tt.cached {
+			t.Errorf("request %d (entity %q, data %v, persona %q): Cached = %v, want %v", i, tt.req.EntityID, tt.req.Data, tt.persona, result.Cached, tt.cached)
+		}
+	}
+	if calls != 6 {
+		t.Errorf("handler calls = %d, want 6", calls)
+	}
+}
@@ -23,6 +23,12 @@ func newRouter(
 		systemPrompt := systemPromptTemplate
 		systemPrompt = strings.ReplaceAll(systemPrompt, "{{EXPERTS}}", expertsStr)
 
+		// Everything before the per-request context is the same for every question
+		cacheablePrefix := strings.Index(systemPrompt, "{{CONTEXT}}")
+		if cacheablePrefix < 0 {
+			cacheablePrefix = len(systemPrompt)
+		}
+
 		// Include entity ID in context if available
 		contextStr := "No additional context available."
 		if entityID != "" {
@@ -38,10 +44,12 @@ func newRouter(
 		opts := &ChatJSONOptions{
 			Model:       ModelMini,
 			Temperature: 0.3,
+
+			CacheableSystemPrefix: cacheablePrefix,
 		}
-		if err := chatJSON(ctx, systemPrompt, message, opts, &result); err != nil {
+		if err := chatJSON(withTraceStep(ctx, TraceRoute), systemPrompt, message, opts, &result); err != nil {
 			// Fallback to default expert on routing failure
-			if defaultExpert != "" {
+			if defaultExpert := resolveDefaultExpert(ctx, defaultExpert); defaultExpert != "" {
 				logger.Warn("routing failed, using default expert",
 					slog.String("error", err.Error()),
 					slog.String("default_expert", string(defaultExpert)),
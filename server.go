@@ -0,0 +1,75 @@
+package aichat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// ListenAndServe serves the SDK's HTTP handler on addr until ctx is cancelled.
+//
+// On cancellation the server stops accepting connections and waits up to
+// Config.ShutdownTimeout for in-flight requests to finish and queued webhooks to be
+// delivered. Requests still running at the deadline have their contexts cancelled
+// and their connections closed.
+func (s *SDK) ListenAndServe(ctx context.Context, addr string) error {
+	// Request contexts outlive ctx so in-flight chats can drain after it is cancelled
+	baseCtx, cancelRequests := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelRequests()
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           s.httpHandler,
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext: func(net.Listener) context.Context {
+			return baseCtx
+		},
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+
+	s.logger.Info("server listening", slog.String("addr", addr))
+
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("server failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	s.logger.Info("shutting down server, draining in-flight requests",
+		slog.Duration("timeout", s.config.ShutdownTimeout),
+	)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.config.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		if !errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Errorf("server shutdown failed: %w", err)
+		}
+
+		s.logger.Warn("drain timeout reached, cancelling remaining requests")
+		cancelRequests()
+		if err := server.Close(); err != nil {
+			return fmt.Errorf("failed to close server: %w", err)
+		}
+	}
+
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("server failed: %w", err)
+	}
+
+	if err := s.Shutdown(shutdownCtx); err != nil {
+		s.logger.Warn("drain timeout reached, dropping queued webhooks")
+	}
+
+	s.logger.Info("server stopped")
+	return nil
+}
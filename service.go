@@ -16,6 +16,8 @@ func NewChatService(
 	logger *slog.Logger,
 ) ProcessChatFn {
 	return func(ctx context.Context, req ChatRequest) (*ChatResult, error) {
+		ctx, lastLLMCall := withLLMCallRecord(withSeed(ctx, req.Seed))
+
 		// 1. Translate message to English for consistent processing
 		translation, err := translate(ctx, req.Message)
 		if err != nil {
@@ -34,6 +36,7 @@ func NewChatService(
 		if err != nil {
 			return nil, err
 		}
+		ctx = withConversation(ctx, conversation)
 
 		// 3. Store user message (original language)
 		if err := storeUserMessage(ctx, store, conversation.ID, req.Message, req.Data); err != nil {
@@ -46,6 +49,9 @@ func NewChatService(
 			Message:  translation.TranslatedMessage,
 			EntityID: conversation.EntityID,
 			Data:     req.Data,
+			Images:   req.Images,
+
+			SuspectedInjection: req.SuspectedInjection,
 		}
 
 		expertResult, err := dispatchQuestion(ctx, expertReq)
@@ -74,18 +80,45 @@ func NewChatService(
 		expertResult.Answer = formattedResponse.FormattedAnswer
 
 		// 6. Store assistant message
-		if err := storeAssistantMessage(ctx, store, conversation.ID, expertResult); err != nil {
+		llmCall := lastLLMCall()
+		messageID, err := storeAssistantMessage(ctx, store, conversation.ID, expertResult, llmCall)
+		if err != nil {
 			logger.Warn("failed to store assistant message", "error", err)
 			// Don't fail - response is already generated
 		}
 
+		logModeration(logger, formattedResponse, conversation.ID, messageID)
+
 		return &ChatResult{
-			ConversationID: conversation.ID,
-			ExpertResult:   expertResult,
+			ConversationID:     conversation.ID,
+			MessageID:          messageID,
+			ExpertResult:       expertResult,
+			Cached:             expertResult.Cached,
+			DetectedLanguage:   translation.DetectedLanguage,
+			LanguageConfidence: translation.Confidence,
+			Model:              llmCall.ExpertModel,
+			SystemFingerprint:  llmCall.SystemFingerprint,
+			Moderated:          formattedResponse.Moderation != nil,
+			Usage:              llmCall.Usage,
+			Trace:              llmCall.Trace,
+			SuggestedFollowups: formattedResponse.SuggestedFollowups,
 		}, nil
 	}
 }
 
+// logModeration records answers replaced by output moderation for later review.
+func logModeration(logger *slog.Logger, resp *FormatResponse, conversationID, messageID string) {
+	if resp.Moderation == nil {
+		return
+	}
+
+	logger.Warn("answer flagged by output moderation",
+		"conversation_id", conversationID,
+		"message_id", messageID,
+		"categories", resp.Moderation.Categories,
+	)
+}
+
 func getOrCreateConversation(
 	ctx context.Context,
 	req ChatRequest,
@@ -101,16 +134,30 @@ func getOrCreateConversation(
 	}
 
 	// New conversation
-	conv, err := store.Create(ctx, req.EntityID)
+	conv, err := createConversation(ctx, store, req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create conversation: %w", err)
 	}
 
+	if len(req.Metadata) > 0 || len(req.Tags) > 0 {
+		conv.Metadata = req.Metadata
+		conv.Tags = req.Tags
+		if err := store.Save(ctx, conv); err != nil {
+			return nil, fmt.Errorf("failed to save conversation metadata: %w", err)
+		}
+	}
+
 	return conv, nil
 }
 
 func storeUserMessage(ctx context.Context, store ConversationStore, conversationID, message string, data any) error {
+	id, err := newMessageID(ctx)
+	if err != nil {
+		return err
+	}
+
 	msg := Message{
+		ID:        id,
 		Role:      RoleUser,
 		Content:   message,
 		Timestamp: time.Now(),
@@ -119,15 +166,28 @@ func storeUserMessage(ctx context.Context, store ConversationStore, conversation
 	return store.AddMessage(ctx, conversationID, msg)
 }
 
-func storeAssistantMessage(ctx context.Context, store ConversationStore, conversationID string, result *ExpertResult) error {
+// storeAssistantMessage stores the expert's answer with the tokens spent producing it
+// and returns the ID of the stored message.
+func storeAssistantMessage(ctx context.Context, store ConversationStore, conversationID string, result *ExpertResult, llmCall llmCallRecord) (string, error) {
+	id, err := newMessageID(ctx)
+	if err != nil {
+		return "", err
+	}
+
 	msg := Message{
+		ID:        id,
 		Role:      RoleAssistant,
 		Content:   result.Answer,
 		Timestamp: time.Now(),
 		Expert:    &result.ExpertName,
 		Data:      result.Details,
+		Tokens:    llmCall.Usage.TotalTokens,
+		Usage:     llmCall.modelUsage(),
 	}
-	return store.AddMessage(ctx, conversationID, msg)
+	if err := store.AddMessage(ctx, conversationID, msg); err != nil {
+		return "", err
+	}
+	return msg.ID, nil
 }
 
 // NewChatServiceStreaming creates a streaming chat processing function.
@@ -139,6 +199,8 @@ func NewChatServiceStreaming(
 	logger *slog.Logger,
 ) ProcessChatStreamFn {
 	return func(ctx context.Context, req ChatRequest, stream StreamCallback) (*ChatResult, error) {
+		ctx, lastLLMCall := withLLMCallRecord(withSeed(ctx, req.Seed))
+
 		// 1. Send translating event
 		stream(StreamEvent{Type: EventTranslating})
 
@@ -160,6 +222,7 @@ func NewChatServiceStreaming(
 		if err != nil {
 			return nil, err
 		}
+		ctx = withConversation(ctx, conversation)
 
 		// 3. Store user message (original language)
 		if err := storeUserMessage(ctx, store, conversation.ID, req.Message, req.Data); err != nil {
@@ -171,6 +234,9 @@ func NewChatServiceStreaming(
 			Message:  translation.TranslatedMessage,
 			EntityID: conversation.EntityID,
 			Data:     req.Data,
+			Images:   req.Images,
+
+			SuspectedInjection: req.SuspectedInjection,
 		}
 
 		expertResult, err := dispatchQuestion(ctx, expertReq, stream)
@@ -198,13 +264,27 @@ func NewChatServiceStreaming(
 		expertResult.Answer = formattedResponse.FormattedAnswer
 
 		// 6. Store assistant message
-		if err := storeAssistantMessage(ctx, store, conversation.ID, expertResult); err != nil {
+		llmCall := lastLLMCall()
+		messageID, err := storeAssistantMessage(ctx, store, conversation.ID, expertResult, llmCall)
+		if err != nil {
 			logger.Warn("failed to store assistant message", "error", err)
 		}
 
+		logModeration(logger, formattedResponse, conversation.ID, messageID)
+
 		return &ChatResult{
-			ConversationID: conversation.ID,
-			ExpertResult:   expertResult,
+			ConversationID:     conversation.ID,
+			MessageID:          messageID,
+			ExpertResult:       expertResult,
+			Cached:             expertResult.Cached,
+			DetectedLanguage:   translation.DetectedLanguage,
+			LanguageConfidence: translation.Confidence,
+			Model:              llmCall.ExpertModel,
+			SystemFingerprint:  llmCall.SystemFingerprint,
+			Moderated:          formattedResponse.Moderation != nil,
+			Usage:              llmCall.Usage,
+			Trace:              llmCall.Trace,
+			SuggestedFollowups: formattedResponse.SuggestedFollowups,
 		}, nil
 	}
 }
@@ -3,37 +3,87 @@ package aichat
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// MemoryStoreOption configures an in-memory store.
+type MemoryStoreOption func(*memoryStoreOptions)
+
+type memoryStoreOptions struct {
+	ttl time.Duration
+}
+
+// WithMemoryTTL expires conversations that have not been updated for ttl.
+// Expired conversations are no longer returned, and are dropped when new
+// conversations are created or PurgeExpired is called.
+func WithMemoryTTL(ttl time.Duration) MemoryStoreOption {
+	return func(o *memoryStoreOptions) {
+		o.ttl = ttl
+	}
+}
+
 // NewMemoryStore creates a new in-memory conversation store.
 // This is useful for development and testing, but conversations are lost on restart.
-func NewMemoryStore(logger *slog.Logger) ConversationStore {
+func NewMemoryStore(logger *slog.Logger, opts ...MemoryStoreOption) ConversationStore {
+	var options memoryStoreOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	var mu sync.RWMutex
 	conversations := make(map[string]*Conversation)
+	feedback := make(map[string]Feedback)
 
-	logger.Info("initialized in-memory store")
+	// updated holds the time of each conversation's last write, like a file's
+	// modification time, since Save may store a stale UpdatedAt
+	updated := make(map[string]time.Time)
+	expired := func(id string) bool {
+		return options.ttl > 0 && time.Since(updated[id]) >= options.ttl
+	}
+	purgeUnlocked := func() int {
+		purged := 0
+		for id := range conversations {
+			if expired(id) {
+				delete(conversations, id)
+				delete(updated, id)
+				purged++
+			}
+		}
+		return purged
+	}
+
+	logger.Info("initialized in-memory store", slog.Duration("ttl", options.ttl))
 
 	return ConversationStore{
 		Create: func(ctx context.Context, entityID string) (*Conversation, error) {
 			mu.Lock()
 			defer mu.Unlock()
 
+			purgeUnlocked()
+
+			now := time.Now()
 			conversation := &Conversation{
 				ID:        uuid.New().String(),
-				CreatedAt: time.Now(),
+				CreatedAt: now,
+				UpdatedAt: now,
 				EntityID:  entityID,
 				Messages:  []Message{},
 			}
 
 			conversations[conversation.ID] = conversation
+			updated[conversation.ID] = now
 
 			logger.Debug("created conversation",
 				slog.String("conversation_id", conversation.ID),
@@ -48,28 +98,19 @@ func NewMemoryStore(logger *slog.Logger) ConversationStore {
 			defer mu.RUnlock()
 
 			conversation, exists := conversations[id]
-			if !exists {
+			if !exists || expired(id) {
 				return nil, ErrConversationNotFound
 			}
 
 			// Return a deep copy to prevent concurrent modification
-			result := *conversation
-			result.Messages = make([]Message, len(conversation.Messages))
-			for i := range conversation.Messages {
-				msg := conversation.Messages[i]
-				if msg.Expert != nil {
-					expertCopy := *msg.Expert
-					msg.Expert = &expertCopy
-				}
-				result.Messages[i] = msg
-			}
+			result := cloneConversation(conversation)
 
 			logger.Debug("retrieved conversation",
 				slog.String("conversation_id", id),
 				slog.Int("message_count", len(result.Messages)),
 			)
 
-			return &result, nil
+			return result, nil
 		},
 
 		AddMessage: func(ctx context.Context, id string, msg Message) error {
@@ -77,11 +118,12 @@ func NewMemoryStore(logger *slog.Logger) ConversationStore {
 			defer mu.Unlock()
 
 			conversation, exists := conversations[id]
-			if !exists {
+			if !exists || expired(id) {
 				return ErrConversationNotFound
 			}
 
 			AddMessage(conversation, msg)
+			updated[id] = time.Now()
 
 			logger.Debug("added message to conversation",
 				slog.String("conversation_id", id),
@@ -97,26 +139,186 @@ func NewMemoryStore(logger *slog.Logger) ConversationStore {
 			defer mu.Unlock()
 
 			conversations[conversation.ID] = conversation
+			updated[conversation.ID] = time.Now()
 			return nil
 		},
+
+		List: func(ctx context.Context, filter ConversationFilter) ([]*Conversation, error) {
+			mu.RLock()
+			defer mu.RUnlock()
+
+			var matches []*Conversation
+			for id, conversation := range conversations {
+				if !expired(id) && matchesConversationFilter(conversation, filter) {
+					matches = append(matches, conversation)
+				}
+			}
+
+			slices.SortFunc(matches, func(a, b *Conversation) int {
+				return lastUpdated(b).Compare(lastUpdated(a))
+			})
+
+			if filter.Offset >= len(matches) {
+				return []*Conversation{}, nil
+			}
+			matches = matches[max(filter.Offset, 0):]
+			matches = matches[:min(len(matches), filter.limit())]
+
+			result := make([]*Conversation, len(matches))
+			for i, conversation := range matches {
+				result[i] = cloneConversation(conversation)
+			}
+
+			return result, nil
+		},
+
+		SaveFeedback: func(ctx context.Context, fb Feedback) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			feedback[fb.MessageID] = fb
+			return nil
+		},
+
+		GetFeedback: func(ctx context.Context, messageID string) (*Feedback, error) {
+			mu.RLock()
+			defer mu.RUnlock()
+
+			fb, exists := feedback[messageID]
+			if !exists {
+				return nil, ErrFeedbackNotFound
+			}
+			return &fb, nil
+		},
+
+		ListFeedback: func(ctx context.Context, conversationID string) ([]Feedback, error) {
+			mu.RLock()
+			defer mu.RUnlock()
+
+			result := []Feedback{}
+			for _, fb := range feedback {
+				if fb.ConversationID == conversationID {
+					result = append(result, fb)
+				}
+			}
+			sortFeedback(result)
+
+			return result, nil
+		},
+
+		PurgeExpired: func(ctx context.Context) (int, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			purged := purgeUnlocked()
+			if purged > 0 {
+				logger.Info("purged expired conversations", slog.Int("count", purged))
+			}
+			return purged, nil
+		},
+	}
+}
+
+// FileStoreOption configures a file store.
+type FileStoreOption func(*fileStoreOptions)
+
+type fileStoreOptions struct {
+	ttl           time.Duration
+	sweepInterval time.Duration
+	fileLocking   bool
+}
+
+// WithTTL expires conversations that have not been updated for ttl. Expired
+// conversations are no longer returned, and their files are deleted by a
+// background sweeper until the store is closed.
+func WithTTL(ttl time.Duration) FileStoreOption {
+	return func(o *fileStoreOptions) {
+		o.ttl = ttl
+	}
+}
+
+// WithSweepInterval sets how often the background sweeper deletes expired
+// conversations (defaults to 10 minutes). It only applies together with WithTTL.
+func WithSweepInterval(interval time.Duration) FileStoreOption {
+	return func(o *fileStoreOptions) {
+		o.sweepInterval = interval
+	}
+}
+
+// errFileLockingUnsupported is returned by NewFileStore for WithFileLocking on
+// platforms without flock.
+var errFileLockingUnsupported = errors.New("file locking is not supported on this platform")
+
+// WithFileLocking serializes writes across processes sharing the data directory
+// with an advisory lock file (flock). Use it when several replicas mount the same
+// volume. It is only supported on Unix systems; elsewhere NewFileStore fails.
+func WithFileLocking() FileStoreOption {
+	return func(o *fileStoreOptions) {
+		o.fileLocking = true
 	}
 }
 
 // NewFileStore creates a new file-based conversation store.
-func NewFileStore(dataDir string, logger *slog.Logger) (ConversationStore, error) {
-	if err := os.MkdirAll(dataDir, 0755); err != nil {
+// Files are written atomically, so a crash mid-write never leaves a partial conversation.
+func NewFileStore(dataDir string, logger *slog.Logger, opts ...FileStoreOption) (ConversationStore, error) {
+	options := fileStoreOptions{sweepInterval: 10 * time.Minute}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if options.fileLocking && !fileLockingSupported {
+		return ConversationStore{}, errFileLockingUnsupported
+	}
+
+	feedbackDir := filepath.Join(dataDir, "feedback")
+	if err := os.MkdirAll(feedbackDir, 0755); err != nil {
 		return ConversationStore{}, fmt.Errorf("failed to create conversations directory: %w", err)
 	}
 
-	logger.Info("initialized file store", slog.String("directory", dataDir))
+	logger.Info("initialized file store",
+		slog.String("directory", dataDir),
+		slog.Duration("ttl", options.ttl),
+	)
 
 	var mu sync.RWMutex
 
+	// lockExclusive serializes writers across processes when file locking is enabled.
+	// Readers need no lock since files are replaced atomically.
+	var lock *os.File
+	if options.fileLocking {
+		var err error
+		lock, err = os.OpenFile(filepath.Join(dataDir, ".lock"), os.O_CREATE|os.O_RDWR, 0644)
+		if err != nil {
+			return ConversationStore{}, fmt.Errorf("failed to open lock file: %w", err)
+		}
+	}
+	lockExclusive := func() (func(), error) {
+		if lock == nil {
+			return func() {}, nil
+		}
+		if err := lockFile(lock); err != nil {
+			return nil, fmt.Errorf("failed to lock conversations directory: %w", err)
+		}
+		return func() {
+			if err := unlockFile(lock); err != nil {
+				logger.Warn("failed to unlock conversations directory", slog.String("error", err.Error()))
+			}
+		}, nil
+	}
+
 	getFilePath := func(id string) string {
 		return filepath.Join(dataDir, fmt.Sprintf("%s.json", id))
 	}
 
+	// Every update rewrites the file, so its modification time is the last update time
+	expired := func(modTime time.Time) bool {
+		return options.ttl > 0 && time.Since(modTime) >= options.ttl
+	}
+
 	saveUnlocked := func(conversation *Conversation) error {
+		if err := validateStoreID(conversation.ID); err != nil {
+			return err
+		}
 		path := getFilePath(conversation.ID)
 
 		data, err := json.MarshalIndent(conversation, "", "  ")
@@ -124,7 +326,7 @@ func NewFileStore(dataDir string, logger *slog.Logger) (ConversationStore, error
 			return fmt.Errorf("failed to marshal conversation: %w", err)
 		}
 
-		if err := os.WriteFile(path, data, 0644); err != nil {
+		if err := writeFileAtomic(path, data); err != nil {
 			return fmt.Errorf("failed to write conversation file: %w", err)
 		}
 
@@ -132,22 +334,135 @@ func NewFileStore(dataDir string, logger *slog.Logger) (ConversationStore, error
 	}
 
 	getUnlocked := func(id string) (*Conversation, error) {
+		if validateStoreID(id) != nil {
+			return nil, ErrConversationNotFound
+		}
 		path := getFilePath(id)
 
+		// Expired files stay on disk until the sweeper runs
+		if options.ttl > 0 {
+			if info, err := os.Stat(path); err == nil && expired(info.ModTime()) {
+				return nil, ErrConversationNotFound
+			}
+		}
+
+		read := func() (*Conversation, error) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil, ErrConversationNotFound
+				}
+				return nil, fmt.Errorf("failed to read conversation file: %w", err)
+			}
+
+			var conversation Conversation
+			if err := json.Unmarshal(data, &conversation); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal conversation: %w", err)
+			}
+
+			return &conversation, nil
+		}
+
+		// A file written in place by another process may be caught mid-write; retry once
+		conversation, err := read()
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) || errors.Is(err, io.ErrUnexpectedEOF) {
+			time.Sleep(50 * time.Millisecond)
+			conversation, err = read()
+		}
+		return conversation, err
+	}
+
+	readFeedbackUnlocked := func(path string) (*Feedback, error) {
 		data, err := os.ReadFile(path)
 		if err != nil {
 			if os.IsNotExist(err) {
-				return nil, ErrConversationNotFound
+				return nil, ErrFeedbackNotFound
+			}
+			return nil, fmt.Errorf("failed to read feedback file: %w", err)
+		}
+
+		var fb Feedback
+		if err := json.Unmarshal(data, &fb); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal feedback: %w", err)
+		}
+
+		return &fb, nil
+	}
+
+	purgeExpired := func(ctx context.Context) (int, error) {
+		if options.ttl <= 0 {
+			return 0, nil
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		unlock, err := lockExclusive()
+		if err != nil {
+			return 0, err
+		}
+		defer unlock()
+
+		entries, err := os.ReadDir(dataDir)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read conversations directory: %w", err)
+		}
+
+		purged := 0
+		for _, entry := range entries {
+			if err := ctx.Err(); err != nil {
+				return purged, err
+			}
+			if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+				continue
+			}
+
+			id := strings.TrimSuffix(entry.Name(), ".json")
+			if validateStoreID(id) != nil {
+				continue
+			}
+
+			info, err := entry.Info()
+			if err != nil || !expired(info.ModTime()) {
+				continue
+			}
+
+			if err := os.Remove(getFilePath(id)); err != nil && !os.IsNotExist(err) {
+				logger.Warn("failed to delete expired conversation",
+					slog.String("conversation_id", id),
+					slog.String("error", err.Error()),
+				)
+				continue
 			}
-			return nil, fmt.Errorf("failed to read conversation file: %w", err)
+			purged++
 		}
 
-		var conversation Conversation
-		if err := json.Unmarshal(data, &conversation); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal conversation: %w", err)
+		if purged > 0 {
+			logger.Info("purged expired conversations", slog.Int("count", purged))
 		}
 
-		return &conversation, nil
+		return purged, nil
+	}
+
+	stopSweeper := make(chan struct{})
+	var closeOnce sync.Once
+	if options.ttl > 0 {
+		go func() {
+			ticker := time.NewTicker(options.sweepInterval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-stopSweeper:
+					return
+				case <-ticker.C:
+					if _, err := purgeExpired(context.Background()); err != nil {
+						logger.Warn("failed to purge expired conversations", slog.String("error", err.Error()))
+					}
+				}
+			}
+		}()
 	}
 
 	return ConversationStore{
@@ -155,9 +470,11 @@ func NewFileStore(dataDir string, logger *slog.Logger) (ConversationStore, error
 			mu.Lock()
 			defer mu.Unlock()
 
+			now := time.Now()
 			conversation := &Conversation{
 				ID:        uuid.New().String(),
-				CreatedAt: time.Now(),
+				CreatedAt: now,
+				UpdatedAt: now,
 				EntityID:  entityID,
 				Messages:  []Message{},
 			}
@@ -195,6 +512,12 @@ func NewFileStore(dataDir string, logger *slog.Logger) (ConversationStore, error
 			mu.Lock()
 			defer mu.Unlock()
 
+			unlock, err := lockExclusive()
+			if err != nil {
+				return err
+			}
+			defer unlock()
+
 			conversation, err := getUnlocked(id)
 			if err != nil {
 				return err
@@ -219,7 +542,257 @@ func NewFileStore(dataDir string, logger *slog.Logger) (ConversationStore, error
 			mu.Lock()
 			defer mu.Unlock()
 
+			unlock, err := lockExclusive()
+			if err != nil {
+				return err
+			}
+			defer unlock()
+
 			return saveUnlocked(conversation)
 		},
+
+		List: func(ctx context.Context, filter ConversationFilter) ([]*Conversation, error) {
+			mu.RLock()
+			defer mu.RUnlock()
+
+			entries, err := os.ReadDir(dataDir)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read conversations directory: %w", err)
+			}
+
+			// Every update rewrites the file, so the modification time orders
+			// conversations by last update without parsing them
+			type candidate struct {
+				id      string
+				modTime time.Time
+			}
+			candidates := make([]candidate, 0, len(entries))
+			for _, entry := range entries {
+				if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+					continue
+				}
+				info, err := entry.Info()
+				if err != nil || expired(info.ModTime()) {
+					continue
+				}
+				candidates = append(candidates, candidate{
+					id:      strings.TrimSuffix(entry.Name(), ".json"),
+					modTime: info.ModTime(),
+				})
+			}
+
+			slices.SortFunc(candidates, func(a, b candidate) int {
+				return b.modTime.Compare(a.modTime)
+			})
+
+			// Parse files lazily, stopping once the page is full
+			limit := filter.limit()
+			skipped := 0
+			result := []*Conversation{}
+			for _, c := range candidates {
+				if len(result) >= limit {
+					break
+				}
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+
+				conversation, err := getUnlocked(c.id)
+				if err != nil {
+					logger.Warn("skipping unreadable conversation file",
+						slog.String("conversation_id", c.id),
+						slog.String("error", err.Error()),
+					)
+					continue
+				}
+
+				if !matchesConversationFilter(conversation, filter) {
+					continue
+				}
+				if skipped < filter.Offset {
+					skipped++
+					continue
+				}
+				result = append(result, conversation)
+			}
+
+			return result, nil
+		},
+
+		SaveFeedback: func(ctx context.Context, fb Feedback) error {
+			if err := validateStoreID(fb.MessageID); err != nil {
+				return err
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			data, err := json.MarshalIndent(fb, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to marshal feedback: %w", err)
+			}
+
+			path := filepath.Join(feedbackDir, fb.MessageID+".json")
+			if err := writeFileAtomic(path, data); err != nil {
+				return fmt.Errorf("failed to write feedback file: %w", err)
+			}
+
+			return nil
+		},
+
+		GetFeedback: func(ctx context.Context, messageID string) (*Feedback, error) {
+			if err := validateStoreID(messageID); err != nil {
+				return nil, ErrFeedbackNotFound
+			}
+
+			mu.RLock()
+			defer mu.RUnlock()
+
+			return readFeedbackUnlocked(filepath.Join(feedbackDir, messageID+".json"))
+		},
+
+		ListFeedback: func(ctx context.Context, conversationID string) ([]Feedback, error) {
+			mu.RLock()
+			defer mu.RUnlock()
+
+			entries, err := os.ReadDir(feedbackDir)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read feedback directory: %w", err)
+			}
+
+			result := []Feedback{}
+			for _, entry := range entries {
+				if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+					continue
+				}
+
+				fb, err := readFeedbackUnlocked(filepath.Join(feedbackDir, entry.Name()))
+				if err != nil {
+					logger.Warn("skipping unreadable feedback file",
+						slog.String("file", entry.Name()),
+						slog.String("error", err.Error()),
+					)
+					continue
+				}
+
+				if fb.ConversationID == conversationID {
+					result = append(result, *fb)
+				}
+			}
+			sortFeedback(result)
+
+			return result, nil
+		},
+
+		PurgeExpired: purgeExpired,
+
+		Close: func() error {
+			var err error
+			closeOnce.Do(func() {
+				close(stopSweeper)
+				if lock != nil {
+					err = lock.Close()
+				}
+			})
+			return err
+		},
 	}, nil
 }
+
+// writeFileAtomic writes data to a temporary file in the same directory and
+// renames it over path, so readers never observe a partially written file.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// validateStoreID rejects IDs that are unsafe to use as file names.
+func validateStoreID(id string) error {
+	if id == "" {
+		return fmt.Errorf("%w: empty ID", ErrInvalidInput)
+	}
+
+	for _, r := range id {
+		isAlnum := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+		if !isAlnum && r != '-' && r != '_' {
+			return fmt.Errorf("%w: ID contains invalid characters", ErrInvalidInput)
+		}
+	}
+
+	return nil
+}
+
+// sortFeedback orders feedback oldest first.
+func sortFeedback(feedback []Feedback) {
+	slices.SortFunc(feedback, func(a, b Feedback) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+}
+
+// cloneConversation returns a deep copy of the conversation's mutable fields.
+func cloneConversation(conversation *Conversation) *Conversation {
+	result := *conversation
+	result.Metadata = maps.Clone(conversation.Metadata)
+	result.Tags = slices.Clone(conversation.Tags)
+	result.Usage = conversation.Usage.clone()
+	result.Messages = make([]Message, len(conversation.Messages))
+	for i := range conversation.Messages {
+		msg := conversation.Messages[i]
+		if msg.Expert != nil {
+			expertCopy := *msg.Expert
+			msg.Expert = &expertCopy
+		}
+		result.Messages[i] = msg
+	}
+	return &result
+}
+
+// matchesConversationFilter reports whether the conversation satisfies the filter's criteria.
+// Limit and Offset are applied by the caller.
+func matchesConversationFilter(conversation *Conversation, filter ConversationFilter) bool {
+	if filter.EntityID != "" && conversation.EntityID != filter.EntityID {
+		return false
+	}
+
+	if filter.Owner != "" && conversation.Owner != filter.Owner {
+		return false
+	}
+
+	if !filter.CreatedAfter.IsZero() && !conversation.CreatedAt.After(filter.CreatedAfter) {
+		return false
+	}
+
+	for _, tag := range filter.Tags {
+		if !slices.Contains(conversation.Tags, tag) {
+			return false
+		}
+	}
+
+	for key, value := range filter.Metadata {
+		if conversation.Metadata[key] != value {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,158 @@
+package aichat_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	aichat "github.com/ourstudio-se/ai-chat-sdk"
+)
+
+func TestFileStoreKeepsConcurrentMessages(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	store, err := aichat.NewFileStore(dir, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatalf("NewFileStore() error = %v", err)
+	}
+	conversation, err := store.Create(ctx, "")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	const writers = 50
+	var wg sync.WaitGroup
+	for i := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			msg := aichat.Message{Role: aichat.RoleUser, Content: fmt.Sprintf("message %d", i)}
+			if err := store.AddMessage(ctx, conversation.ID, msg); err != nil {
+				t.Errorf("AddMessage() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	stored, err := store.Get(ctx, conversation.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	seen := make(map[string]bool)
+	for _, msg := range stored.Messages {
+		seen[msg.Content] = true
+	}
+	if len(stored.Messages) != writers || len(seen) != writers {
+		t.Errorf("stored %d messages (%d distinct), want %d", len(stored.Messages), len(seen), writers)
+	}
+
+	// Writes go through temporary files that are renamed into place
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && strings.HasSuffix(path, ".tmp") {
+			t.Errorf("temporary file %s left behind", path)
+		}
+		return err
+	})
+}
+
+func TestFileStoreExpiresConversationsByModificationTime(t *testing.T) {
+	dir := t.TempDir()
+
+	// The sweeper doesn't run during the test, so only reads enforce the TTL
+	store, err := aichat.NewFileStore(dir, slog.New(slog.DiscardHandler),
+		aichat.WithTTL(time.Hour),
+		aichat.WithSweepInterval(time.Hour),
+	)
+	if err != nil {
+		t.Fatalf("NewFileStore() error = %v", err)
+	}
+	defer store.Close()
+
+	old := mustCreate(t, store)
+	fresh := mustCreate(t, store)
+
+	oldPath := filepath.Join(dir, old.ID+".json")
+	lastUpdate := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(oldPath, lastUpdate, lastUpdate); err != nil {
+		t.Fatalf("Chtimes() error = %v", err)
+	}
+
+	assertExpiry(t, store, old.ID, fresh.ID)
+
+	if _, err := os.Stat(oldPath); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expired conversation file still exists after PurgeExpired: %v", err)
+	}
+}
+
+func TestMemoryStoreExpiresConversations(t *testing.T) {
+	const ttl = 100 * time.Millisecond
+	ctx := context.Background()
+	store := aichat.NewMemoryStore(slog.New(slog.DiscardHandler), aichat.WithMemoryTTL(ttl))
+
+	old := mustCreate(t, store)
+	if _, err := store.Get(ctx, old.ID); err != nil {
+		t.Fatalf("Get() before the TTL error = %v", err)
+	}
+	time.Sleep(ttl + 50*time.Millisecond)
+
+	// Save refreshes the conversation like a file write
+	fresh := &aichat.Conversation{ID: "fresh", Messages: []aichat.Message{}}
+	if err := store.Save(ctx, fresh); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	assertExpiry(t, store, old.ID, fresh.ID)
+}
+
+func mustCreate(t *testing.T, store aichat.ConversationStore) *aichat.Conversation {
+	t.Helper()
+
+	conversation, err := store.Create(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	return conversation
+}
+
+// assertExpiry checks that the conversation expiredID is hidden from reads and
+// writes until PurgeExpired deletes it, while freshID is kept.
+func assertExpiry(t *testing.T, store aichat.ConversationStore, expiredID, freshID string) {
+	t.Helper()
+	ctx := context.Background()
+
+	if _, err := store.Get(ctx, expiredID); !errors.Is(err, aichat.ErrConversationNotFound) {
+		t.Errorf("Get(expired) error = %v, want ErrConversationNotFound", err)
+	}
+	msg := aichat.Message{Role: aichat.RoleUser, Content: "Still there?", Timestamp: time.Now()}
+	if err := store.AddMessage(ctx, expiredID, msg); !errors.Is(err, aichat.ErrConversationNotFound) {
+		t.Errorf("AddMessage(expired) error = %v, want ErrConversationNotFound", err)
+	}
+
+	conversations, err := store.List(ctx, aichat.ConversationFilter{})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(conversations) != 1 || conversations[0].ID != freshID {
+		t.Errorf("List() returned %d conversations, want only %s", len(conversations), freshID)
+	}
+
+	purged, err := store.PurgeExpired(ctx)
+	if err != nil || purged != 1 {
+		t.Errorf("PurgeExpired() = %d, %v; want 1, nil", purged, err)
+	}
+	if purged, _ := store.PurgeExpired(ctx); purged != 0 {
+		t.Errorf("second PurgeExpired() = %d, want 0", purged)
+	}
+	if _, err := store.Get(ctx, freshID); err != nil {
+		t.Errorf("Get(fresh) error = %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+package aichat
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// maxSuggestedFollowups is the number of follow-up questions suggested per answer.
+const maxSuggestedFollowups = 3
+
+// suggestionsHistoryLength is the number of earlier messages shown to the LLM for context.
+const suggestionsHistoryLength = 6
+
+const suggestionsSystemPrompt = `You suggest follow-up questions for a customer chat.
+
+Given the conversation so far, the customer's latest question and the assistant's answer,
+propose %d short questions the customer is likely to ask next.
+
+Rules:
+- Write them from the customer's point of view, in the language with ISO 639-1 code "%s"
+- Keep each under 60 characters
+- Only suggest questions the same assistant could plausibly answer
+- Do not repeat questions already asked
+
+Respond with JSON: {"suggestions": ["...", "..."]}`
+
+// withFollowupSuggestions wraps a formatter so that follow-up questions are suggested
+// alongside every answer. The suggestions call runs concurrently with formatting,
+// using the English answer, and its failure never fails the chat.
+// Suggestions are dropped when the answer was replaced by output moderation.
+func withFollowupSuggestions(formatResponse FormatResponseFn, chatJSON ChatJSONFn, store ConversationStore, logger *slog.Logger) FormatResponseFn {
+	return func(ctx context.Context, req FormatRequest) (*FormatResponse, error) {
+		suggestions := make(chan []string, 1)
+		go func() {
+			suggestions <- suggestFollowups(ctx, chatJSON, store, req, logger)
+		}()
+
+		resp, err := formatResponse(ctx, req)
+		followups := <-suggestions
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.Moderation == nil {
+			resp.SuggestedFollowups = followups
+		}
+		return resp, nil
+	}
+}
+
+// suggestFollowups asks the LLM for follow-up questions, returning nil on failure.
+func suggestFollowups(ctx context.Context, chatJSON ChatJSONFn, store ConversationStore, req FormatRequest, logger *slog.Logger) []string {
+	if req.Answer == "" {
+		return nil
+	}
+
+	var history string
+	if conversationID := ConversationIDFromContext(ctx); conversationID != "" {
+		conversation, err := store.Get(ctx, conversationID)
+		if err != nil {
+			logger.Warn("failed to load conversation for follow-up suggestions", slog.String("error", err.Error()))
+		} else {
+			// The latest message is the question being answered, which is passed separately
+			messages := conversation.Messages
+			if len(messages) > 0 {
+				messages = messages[:len(messages)-1]
+			}
+			history = buildTranscript(messages[max(0, len(messages)-suggestionsHistoryLength):])
+		}
+	}
+	if history == "" {
+		history = "(none)\n"
+	}
+
+	language := req.DetectedLanguage
+	if language == "" {
+		language = "en"
+	}
+
+	userPrompt := fmt.Sprintf("Conversation so far:\n%s\nLatest question: %q\n\nAnswer: %q",
+		history,
+		req.OriginalQuestion,
+		req.Answer,
+	)
+
+	var result struct {
+		Suggestions []string `json:"suggestions"`
+	}
+	opts := &ChatJSONOptions{
+		Model:       ModelNano,
+		Temperature: 0.7,
+	}
+	systemPrompt := fmt.Sprintf(suggestionsSystemPrompt, maxSuggestedFollowups, language)
+	if err := chatJSON(withTraceStep(ctx, TraceSuggest), systemPrompt, userPrompt, opts, &result); err != nil {
+		logger.Warn("failed to generate follow-up suggestions",
+			slog.String("expert_type", string(req.ExpertType)),
+			slog.String("error", err.Error()),
+		)
+		return nil
+	}
+
+	var followups []string
+	for _, suggestion := range result.Suggestions {
+		if suggestion = strings.TrimSpace(suggestion); suggestion != "" {
+			followups = append(followups, suggestion)
+		}
+		if len(followups) == maxSuggestedFollowups {
+			break
+		}
+	}
+	return followups
+}
@@ -0,0 +1,115 @@
+package aichat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// DefaultSummarySystemPrompt is the default system prompt for conversation summaries.
+const DefaultSummarySystemPrompt = `You summarize customer conversations for support agents taking over the chat.
+
+Write one concise paragraph in English covering:
+- What the customer wants and any product or order they referred to
+- What the assistant has already answered or suggested
+- Anything still unresolved
+
+Do not invent details that are not in the conversation.`
+
+// ConversationSummary is a cached summary of a conversation.
+type ConversationSummary struct {
+	Text string `json:"text"`
+
+	// MessageCount is the number of messages summarized. The summary is stale
+	// once the conversation has more messages.
+	MessageCount int `json:"messageCount"`
+
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// SummarizeConversationFn returns a short summary of a stored conversation.
+type SummarizeConversationFn func(ctx context.Context, id string) (string, error)
+
+// newConversationSummarizer creates a function that summarizes conversations with
+// the LLM and caches the summary on the conversation until new messages arrive.
+// lock serializes saving the summary with chat turns, so no message is overwritten.
+func newConversationSummarizer(
+	store ConversationStore,
+	chat ChatFn,
+	lock LockConversationFn,
+	systemPrompt string,
+	model ModelTier,
+	logger *slog.Logger,
+) SummarizeConversationFn {
+	return func(ctx context.Context, id string) (string, error) {
+		conversation, err := store.Get(ctx, id)
+		if err != nil {
+			if errors.Is(err, ErrConversationNotFound) {
+				return "", err
+			}
+			return "", fmt.Errorf("failed to get conversation: %w", err)
+		}
+
+		if len(conversation.Messages) == 0 {
+			return "", nil
+		}
+
+		if summary := conversation.Summary; summary != nil && summary.MessageCount == len(conversation.Messages) {
+			return summary.Text, nil
+		}
+
+		text, err := chat(ctx, systemPrompt, buildTranscript(conversation.Messages), &ChatOptions{
+			Model:       model,
+			Temperature: 0.3,
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to summarize conversation: %w", err)
+		}
+
+		summary := &ConversationSummary{
+			Text:         text,
+			MessageCount: len(conversation.Messages),
+			CreatedAt:    time.Now(),
+		}
+		if err := saveSummary(ctx, store, lock, id, summary); err != nil {
+			logger.Warn("failed to cache conversation summary",
+				slog.String("conversation_id", id),
+				slog.String("error", err.Error()),
+			)
+		}
+
+		return text, nil
+	}
+}
+
+// saveSummary stores summary on the conversation unless messages were added since it was generated.
+func saveSummary(ctx context.Context, store ConversationStore, lock LockConversationFn, id string, summary *ConversationSummary) error {
+	unlock, err := lock(ctx, id, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	conversation, err := store.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if len(conversation.Messages) != summary.MessageCount {
+		return nil
+	}
+
+	conversation.Summary = summary
+	return store.Save(ctx, conversation)
+}
+
+// buildTranscript formats messages as a plain-text transcript for the LLM.
+func buildTranscript(messages []Message) string {
+	var sb strings.Builder
+	for _, msg := range messages {
+		fmt.Fprintf(&sb, "%s: %s\n", msg.Role, msg.Content)
+	}
+	return sb.String()
+}
@@ -0,0 +1,67 @@
+package aichat
+
+import (
+	"context"
+	"fmt"
+)
+
+// withConversationTokenBudget wraps a chat function to reject requests on
+// conversations that have used up maxTokens, and to report the remaining budget.
+func withConversationTokenBudget(processChat ProcessChatFn, store ConversationStore, maxTokens int) ProcessChatFn {
+	return func(ctx context.Context, req ChatRequest) (*ChatResult, error) {
+		used, err := conversationTokensUsed(ctx, store, req.ConversationID, maxTokens)
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := processChat(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		result.TokensRemaining = tokensRemaining(maxTokens, used, result)
+		return result, nil
+	}
+}
+
+// withConversationTokenBudgetStreaming wraps a streaming chat function to reject requests
+// on conversations that have used up maxTokens, and to report the remaining budget.
+func withConversationTokenBudgetStreaming(processChatStream ProcessChatStreamFn, store ConversationStore, maxTokens int) ProcessChatStreamFn {
+	return func(ctx context.Context, req ChatRequest, stream StreamCallback) (*ChatResult, error) {
+		used, err := conversationTokensUsed(ctx, store, req.ConversationID, maxTokens)
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := processChatStream(ctx, req, stream)
+		if err != nil {
+			return nil, err
+		}
+		result.TokensRemaining = tokensRemaining(maxTokens, used, result)
+		return result, nil
+	}
+}
+
+// conversationTokensUsed returns the tokens used so far by an existing conversation,
+// or ErrTokenBudgetExceeded once it has reached maxTokens. Lookup failures are left
+// for the chat function to report.
+func conversationTokensUsed(ctx context.Context, store ConversationStore, conversationID string, maxTokens int) (int, error) {
+	if conversationID == "" {
+		return 0, nil
+	}
+
+	conversation, err := store.Get(ctx, conversationID)
+	if err != nil {
+		return 0, nil
+	}
+
+	if conversation.TokensUsed >= maxTokens {
+		return 0, fmt.Errorf("%w: conversation %s has used %d of %d tokens",
+			ErrTokenBudgetExceeded, conversationID, conversation.TokensUsed, maxTokens)
+	}
+	return conversation.TokensUsed, nil
+}
+
+func tokensRemaining(maxTokens, used int, result *ChatResult) *int {
+	remaining := max(maxTokens-used-result.Usage.TotalTokens, 0)
+	return &remaining
+}
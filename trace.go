@@ -0,0 +1,98 @@
+package aichat
+
+import (
+	"context"
+	"time"
+)
+
+// TraceStep identifies the pipeline step that made an LLM call.
+type TraceStep string
+
+// Pipeline steps recorded in traces. Calls made by experts through SDK.LLMClient are traced as TraceExpert.
+const (
+	TraceTranslate TraceStep = "translate"
+	TraceRoute     TraceStep = "route"
+	TraceExpert    TraceStep = "expert"
+	TraceFormat    TraceStep = "format"
+	TraceSuggest   TraceStep = "suggest"
+)
+
+// TraceEntry describes one LLM call made while processing a chat request.
+type TraceEntry struct {
+	Step       TraceStep  `json:"step"`
+	Method     string     `json:"method"` // chat, chat_json or chat_stream
+	Model      string     `json:"model,omitempty"`
+	DurationMs int64      `json:"durationMs"`
+	Usage      TokenUsage `json:"usage"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// traceStepKey is the context key for the pipeline step making LLM calls.
+type traceStepKey struct{}
+
+// withTraceStep returns a context whose LLM calls are traced as step.
+func withTraceStep(ctx context.Context, step TraceStep) context.Context {
+	return context.WithValue(ctx, traceStepKey{}, step)
+}
+
+// newTraceMiddleware returns a middleware that adds every LLM call made for a
+// chat request to the request's trace.
+func newTraceMiddleware() LLMMiddleware {
+	return func(next LLMClient) LLMClient {
+		return LLMClient{
+			Chat: func(ctx context.Context, systemPrompt, userMessage string, opts *ChatOptions) (string, error) {
+				done := startTraceEntry(ctx, "chat")
+				response, err := next.Chat(ctx, systemPrompt, userMessage, opts)
+				done(err)
+				return response, err
+			},
+			ChatJSON: func(ctx context.Context, systemPrompt, userMessage string, opts *ChatJSONOptions, result any) error {
+				done := startTraceEntry(ctx, "chat_json")
+				err := next.ChatJSON(ctx, systemPrompt, userMessage, opts, result)
+				done(err)
+				return err
+			},
+			ChatStream: func(ctx context.Context, systemPrompt, userMessage string, opts *ChatOptions, onToken func(token string)) (string, error) {
+				done := startTraceEntry(ctx, "chat_stream")
+				response, err := next.ChatStream(ctx, systemPrompt, userMessage, opts, onToken)
+				done(err)
+				return response, err
+			},
+		}
+	}
+}
+
+// startTraceEntry starts timing an LLM call and returns a function that adds
+// the call to the trace once it has completed. Token usage is the usage
+// recorded while the call ran, so concurrent calls may share their counts.
+func startTraceEntry(ctx context.Context, method string) func(err error) {
+	recorder, ok := ctx.Value(llmCallRecordKey{}).(*llmCallRecorder)
+	if !ok {
+		return func(err error) {}
+	}
+
+	step, _ := ctx.Value(traceStepKey{}).(TraceStep)
+	start := time.Now()
+	usageBefore := recorder.snapshot().Usage
+
+	return func(err error) {
+		updateLLMCallRecord(ctx, func(record *llmCallRecord) {
+			entry := TraceEntry{
+				Step:       step,
+				Method:     method,
+				Model:      record.Model,
+				DurationMs: time.Since(start).Milliseconds(),
+				Usage: TokenUsage{
+					PromptTokens:     record.Usage.PromptTokens - usageBefore.PromptTokens,
+					CompletionTokens: record.Usage.CompletionTokens - usageBefore.CompletionTokens,
+					TotalTokens:      record.Usage.TotalTokens - usageBefore.TotalTokens,
+					CachedTokens:     record.Usage.CachedTokens - usageBefore.CachedTokens,
+				},
+			}
+			if err != nil {
+				entry.Error = err.Error()
+			}
+			record.Trace = append(record.Trace, entry)
+		})
+	}
+}
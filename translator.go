@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
-	"strings"
 )
 
 // DefaultTranslatorSystemPrompt is the default system prompt for translation.
@@ -31,24 +30,38 @@ IMPORTANT RULES:
 - Be precise with technical terminology`
 
 // newTranslator creates a translation function.
-func newTranslator(chatJSON ChatJSONFn, logger *slog.Logger, customSystemPrompt string) TranslateFn {
+// Messages that detectLanguage identifies as English with at least minConfidence skip the LLM.
+func newTranslator(
+	chatJSON ChatJSONFn,
+	detectLanguage DetectLanguageFn,
+	minConfidence float64,
+	logger *slog.Logger,
+	customSystemPrompt string,
+) TranslateFn {
 	systemPrompt := customSystemPrompt
 	if systemPrompt == "" {
 		systemPrompt = DefaultTranslatorSystemPrompt
 	}
 
 	return func(ctx context.Context, message string) (*TranslationResult, error) {
-		if isLikelyEnglish(message) {
-			logger.Debug("message appears to be English, skipping translation")
+		detection := detectLanguage(message)
+
+		logger.Debug("language detected",
+			slog.String("language", detection.Language),
+			slog.Float64("confidence", detection.Confidence),
+		)
+
+		if detection.Language == "en" && detection.Confidence >= minConfidence {
+			logger.Debug("message detected as English, skipping translation")
 			return &TranslationResult{
 				TranslatedMessage: message,
 				DetectedLanguage:  "en",
-				Confidence:        0.95,
+				Confidence:        detection.Confidence,
 			}, nil
 		}
 
 		var response TranslationResult
-		if err := chatJSON(ctx, systemPrompt, message, nil, &response); err != nil {
+		if err := chatJSON(withTraceStep(ctx, TraceTranslate), systemPrompt, message, nil, &response); err != nil {
 			return nil, fmt.Errorf("translation API call failed: %w", err)
 		}
 
@@ -73,50 +86,3 @@ func newTranslator(chatJSON ChatJSONFn, logger *slog.Logger, customSystemPrompt
 		return &response, nil
 	}
 }
-
-func isLikelyEnglish(text string) bool {
-	lowerText := strings.ToLower(text)
-
-	swedishWords := []string{
-		"hur", "många", "får", "plats", "kan", "jag", "min", "mitt",
-		"bilen", "bagaget", "släp", "dragkrok", "färg", "vad", "vilken",
-		"är", "har", "med", "och", "för", "från", "till", "på", "i",
-	}
-
-	germanWords := []string{
-		"wie", "viele", "kann", "ich", "mein", "auto", "kofferraum",
-		"anhänger", "farbe", "welche", "ist", "hat", "und", "für",
-	}
-
-	nordicWords := []string{
-		"hvor", "mange", "bil", "bagasje", "tilhenger", "farge",
-	}
-
-	nonEnglishCount := 0
-
-	for _, word := range swedishWords {
-		if strings.Contains(lowerText, " "+word+" ") ||
-			strings.HasPrefix(lowerText, word+" ") ||
-			strings.HasSuffix(lowerText, " "+word) {
-			nonEnglishCount++
-		}
-	}
-
-	for _, word := range germanWords {
-		if strings.Contains(lowerText, " "+word+" ") ||
-			strings.HasPrefix(lowerText, word+" ") ||
-			strings.HasSuffix(lowerText, " "+word) {
-			nonEnglishCount++
-		}
-	}
-
-	for _, word := range nordicWords {
-		if strings.Contains(lowerText, " "+word+" ") ||
-			strings.HasPrefix(lowerText, word+" ") ||
-			strings.HasSuffix(lowerText, " "+word) {
-			nonEnglishCount++
-		}
-	}
-
-	return nonEnglishCount < 2
-}
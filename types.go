@@ -2,6 +2,7 @@ package aichat
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
@@ -23,15 +24,35 @@ const (
 // ChatOptions contains optional parameters for chat completions.
 type ChatOptions struct {
 	Model       ModelTier
-	Temperature float32
+	Temperature float32 // Ignored by reasoning models
 	MaxTokens   int
+	Images      []Image // Attached to the user message; requires a vision-capable model
+	Seed        *int    // Requests reproducible sampling where the provider supports it
+
+	// ReasoningEffort is sent to reasoning models (see Config.ReasoningModels) and ignored by others.
+	ReasoningEffort ReasoningEffort
+
+	// CacheableSystemPrefix is the length in bytes of the leading part of the system
+	// prompt that is identical across calls. Providers with explicit prompt caching
+	// (Anthropic, Bedrock) mark it cacheable; OpenAI caches prompt prefixes automatically.
+	CacheableSystemPrefix int
 }
 
 // ChatJSONOptions contains optional parameters for JSON chat completions.
 type ChatJSONOptions struct {
 	Model       ModelTier
-	Temperature float32
+	Temperature float32 // Ignored by reasoning models
 	MaxTokens   int
+	Images      []Image // Attached to the user message; requires a vision-capable model
+	Seed        *int    // Requests reproducible sampling where the provider supports it
+
+	// ReasoningEffort is sent to reasoning models (see Config.ReasoningModels) and ignored by others.
+	ReasoningEffort ReasoningEffort
+
+	// CacheableSystemPrefix is the length in bytes of the leading part of the system
+	// prompt that is identical across calls. Providers with explicit prompt caching
+	// (Anthropic, Bedrock) mark it cacheable; OpenAI caches prompt prefixes automatically.
+	CacheableSystemPrefix int
 }
 
 // ChatFn performs a chat completion and returns the response string.
@@ -43,6 +64,16 @@ type ChatJSONFn func(ctx context.Context, systemPrompt, userMessage string, opts
 // ChatStreamFn performs a streaming chat completion and calls the callback for each token.
 type ChatStreamFn func(ctx context.Context, systemPrompt, userMessage string, opts *ChatOptions, onToken func(token string)) (string, error)
 
+// LLMClient is a struct of functions for LLM access used by the SDK pipeline.
+type LLMClient struct {
+	Chat       ChatFn
+	ChatJSON   ChatJSONFn
+	ChatStream ChatStreamFn
+}
+
+// LLMMiddleware wraps an LLMClient to intercept every LLM call.
+type LLMMiddleware func(next LLMClient) LLMClient
+
 // TranslationResult contains the result of a translation.
 type TranslationResult struct {
 	TranslatedMessage string  `json:"translatedMessage"`
@@ -69,7 +100,13 @@ type ExpertRequest struct {
 	Message          string
 	EntityID         string
 	RoutingReasoning string
-	Data             any // Structured data passed from the request
+	Data             any     // Structured data passed from the request
+	Entity           any     // Entity loaded by Config.EntityResolver, if configured
+	Images           []Image // Images attached to the user's message
+
+	// SuspectedInjection is set when the injection guard (in flag mode) scored the
+	// message as a likely prompt-injection attempt. Experts should answer cautiously.
+	SuspectedInjection bool
 }
 
 // ExpertResult is returned by expert handlers.
@@ -79,6 +116,7 @@ type ExpertResult struct {
 	Answer     string     `json:"answer"`
 	Reasoning  string     `json:"reasoning,omitempty"`
 	Details    any        `json:"details,omitempty"`
+	Cached     bool       `json:"cached,omitempty"` // Served from the response cache
 }
 
 // GetDetails extracts the Details field from an ExpertResult as the specified type T.
@@ -110,6 +148,28 @@ func GetDetails[T any](result *ExpertResult) (T, error) {
 	return details, nil
 }
 
+// DecodeDetails decodes expert details that have been through JSON, such as the
+// "data" field of an HTTP chat response, a stored message's Data, or ChatResult.Details,
+// into T. Use GetDetails for the original value returned by an expert handler.
+//
+// Example:
+//
+//	var resp struct {
+//	    Data json.RawMessage `json:"data"`
+//	}
+//	// ... decode the POST /chat response into resp
+//	details, err := aichat.DecodeDetails[ProductDetails](resp.Data)
+func DecodeDetails[T any](raw json.RawMessage) (T, error) {
+	var details T
+	if len(raw) == 0 || string(raw) == "null" {
+		return details, errors.New("details is nil")
+	}
+	if err := json.Unmarshal(raw, &details); err != nil {
+		return details, fmt.Errorf("failed to decode details: %w", err)
+	}
+	return details, nil
+}
+
 // HandleQuestionFn handles an expert question.
 type HandleQuestionFn func(ctx context.Context, req ExpertRequest) (*ExpertResult, error)
 
@@ -131,6 +191,18 @@ type Expert struct {
 	// StreamHandler processes questions with streaming support.
 	// If nil, Handler will be used and content sent in one chunk.
 	StreamHandler HandleQuestionStreamFn
+
+	// Cacheable marks the expert's answers as deterministic, allowing them to be
+	// served from the semantic response cache (see Config.ResponseCache).
+	Cacheable bool
+
+	// Quota caps how often this expert is invoked, e.g. for experts that call
+	// expensive downstream APIs (optional). Cached answers do not count.
+	Quota Quota
+
+	// ValidateData checks ExpertRequest.Data before the expert runs (optional).
+	// Requests failing it are rejected with ErrInvalidInput (HTTP 400). See DecodeData.
+	ValidateData ValidateDataFn
 }
 
 // FormatRequest represents a formatting request.
@@ -146,6 +218,10 @@ type FormatRequest struct {
 type FormatResponse struct {
 	FormattedAnswer string
 	Language        string
+	Moderation      *ModerationResult // Set when the answer was flagged and replaced by output moderation
+
+	// SuggestedFollowups are questions the user might ask next (see Config.GenerateSuggestions).
+	SuggestedFollowups []string
 }
 
 // FormatResponseFn formats an expert answer for the user.
@@ -153,16 +229,101 @@ type FormatResponseFn func(ctx context.Context, req FormatRequest) (*FormatRespo
 
 // ChatRequest represents an incoming chat message.
 type ChatRequest struct {
-	ConversationID string `json:"conversationId,omitempty"`
-	Message        string `json:"message"`
-	EntityID       string `json:"entityId,omitempty"`
-	Data           any    `json:"data,omitempty"` // Structured data for experts
+	ConversationID string            `json:"conversationId,omitempty"`
+	Message        string            `json:"message"`
+	EntityID       string            `json:"entityId,omitempty"`
+	Data           any               `json:"data,omitempty"`     // Structured data for experts
+	Images         []Image           `json:"images,omitempty"`   // Passed to experts
+	Metadata       map[string]string `json:"metadata,omitempty"` // Set on new conversations
+	Tags           []string          `json:"tags,omitempty"`     // Set on new conversations
+
+	// Seed requests reproducible LLM sampling for this request, overriding Config.Seed.
+	Seed *int `json:"seed,omitempty"`
+
+	// Model overrides the model of the request's free-text LLM calls (Chat and ChatStream);
+	// ChatJSON calls keep their configured models. It must be listed in Config.AllowedModels.
+	Model string `json:"model,omitempty"`
+
+	// Temperature overrides the sampling temperature of the request's free-text LLM calls (0-2).
+	// Anthropic caps it at 1.
+	Temperature *float32 `json:"temperature,omitempty"`
+
+	// Persona selects an entry of Config.Personas for this request, overriding Config.Persona.
+	Persona string `json:"persona,omitempty"`
+
+	// Ephemeral processes the request without storing anything or loading the
+	// conversation's history. ChatResult.ConversationID is still set, for correlation.
+	Ephemeral bool `json:"ephemeral,omitempty"`
+
+	// SuspectedInjection is set by the injection guard in flag mode.
+	SuspectedInjection bool `json:"-"`
 }
 
 // ChatResult is the processed chat result.
 type ChatResult struct {
 	ConversationID string        `json:"conversationId"`
+	MessageID      string        `json:"messageId,omitempty"` // ID of the stored assistant message
 	ExpertResult   *ExpertResult `json:"expertResult"`
+	Cached         bool          `json:"cached,omitempty"`    // Expert answer served from the response cache
+	Model          string        `json:"model,omitempty"`     // Model that served the expert's LLM call, after any fallback
+	Blocked        bool          `json:"blocked,omitempty"`   // Message was blocked by the injection guard
+	Moderated      bool          `json:"moderated,omitempty"` // Answer was flagged and replaced by output moderation
+
+	// SystemFingerprint identifies the provider backend that served the last LLM call.
+	// When it changes between runs, seeded outputs may differ.
+	SystemFingerprint string `json:"systemFingerprint,omitempty"`
+
+	// DetectedLanguage is the ISO 639-1 code of the user's message and
+	// LanguageConfidence the confidence of that detection.
+	DetectedLanguage   string  `json:"detectedLanguage,omitempty"`
+	LanguageConfidence float64 `json:"languageConfidence,omitempty"`
+
+	// Usage counts the tokens of every LLM call made for this request.
+	Usage TokenUsage `json:"usage"`
+
+	// TokensRemaining is the conversation's remaining token budget after this
+	// request, or nil when Config.MaxConversationTokens is not set.
+	TokensRemaining *int `json:"tokensRemaining,omitempty"`
+
+	// Trace lists the LLM calls made for this request when Config.ReturnTrace is set.
+	Trace []TraceEntry `json:"trace,omitempty"`
+
+	// SuggestedFollowups are questions the user might ask next, in their language,
+	// when Config.GenerateSuggestions is set.
+	SuggestedFollowups []string `json:"suggestedFollowups,omitempty"`
+}
+
+// TokenUsage counts the tokens consumed by LLM calls.
+type TokenUsage struct {
+	PromptTokens     int `json:"promptTokens"`
+	CompletionTokens int `json:"completionTokens"`
+	TotalTokens      int `json:"totalTokens"`
+
+	// CachedTokens is the part of PromptTokens served from the provider's prompt cache.
+	CachedTokens int `json:"cachedTokens"`
+}
+
+// add returns the sum of u and other.
+func (u TokenUsage) add(other TokenUsage) TokenUsage {
+	return TokenUsage{
+		PromptTokens:     u.PromptTokens + other.PromptTokens,
+		CompletionTokens: u.CompletionTokens + other.CompletionTokens,
+		TotalTokens:      u.TotalTokens + other.TotalTokens,
+		CachedTokens:     u.CachedTokens + other.CachedTokens,
+	}
+}
+
+// Details returns the expert's details as JSON, or nil when there are none.
+// Decode them with DecodeDetails.
+func (r *ChatResult) Details() json.RawMessage {
+	if r.ExpertResult == nil || r.ExpertResult.Details == nil {
+		return nil
+	}
+	raw, err := json.Marshal(r.ExpertResult.Details)
+	if err != nil {
+		return nil
+	}
+	return raw
 }
 
 // ProcessChatFn processes a complete chat request.
@@ -180,6 +341,58 @@ type DispatchQuestionFn func(ctx context.Context, req ExpertRequest) (*ExpertRes
 // DispatchQuestionStreamFn routes and processes a question with streaming support.
 type DispatchQuestionStreamFn func(ctx context.Context, req ExpertRequest, stream StreamCallback) (*ExpertResult, error)
 
+// ExportConversationFn exports a stored conversation in the given format.
+type ExportConversationFn func(ctx context.Context, id string, format ExportFormat) ([]byte, error)
+
+// HTTPConversationSummaryResponse represents the response body of GET /conversations/{id}/summary.
+type HTTPConversationSummaryResponse struct {
+	ConversationID string `json:"conversationId"`
+	Summary        string `json:"summary"`
+}
+
+// HTTPRegenerateRequest represents the optional request body of POST /conversations/{id}/regenerate.
+type HTTPRegenerateRequest struct {
+	Seed        *int     `json:"seed,omitempty"`
+	Model       string   `json:"model,omitempty"`       // Must be in Config.AllowedModels
+	Temperature *float32 `json:"temperature,omitempty"` // 0-2
+	Persona     string   `json:"persona,omitempty"`     // Name of an entry in Config.Personas
+	Trace       bool     `json:"trace,omitempty"`       // Include the trace when Config.ReturnTrace is set
+}
+
+// HTTPForkConversationRequest represents the request body of POST /conversations/{id}/fork.
+type HTTPForkConversationRequest struct {
+	FromMessageID string `json:"fromMessageId"`
+}
+
+// HTTPForkConversationResponse represents the response body of POST /conversations/{id}/fork.
+type HTTPForkConversationResponse struct {
+	ConversationID string `json:"conversationId"`
+}
+
+// HTTPTruncateConversationRequest represents the request body of POST /conversations/{id}/truncate.
+type HTTPTruncateConversationRequest struct {
+	AfterMessageID string `json:"afterMessageId"`
+}
+
+// HTTPTruncateConversationResponse represents the response body of POST /conversations/{id}/truncate.
+type HTTPTruncateConversationResponse struct {
+	ConversationID string `json:"conversationId"`
+	MessageCount   int    `json:"messageCount"`
+}
+
+// UpdateConversationFn updates a conversation's metadata and tags.
+type UpdateConversationFn func(ctx context.Context, id string, update ConversationUpdate) (*Conversation, error)
+
+// ForkConversationFn copies a conversation up to and including a message into a new
+// conversation and returns the new conversation's ID.
+type ForkConversationFn func(ctx context.Context, id, fromMessageID string) (string, error)
+
+// TruncateConversationFn removes the messages after a message from a conversation.
+type TruncateConversationFn func(ctx context.Context, id, afterMessageID string) (*Conversation, error)
+
+// SubmitFeedbackFn validates and stores feedback on an assistant message.
+type SubmitFeedbackFn func(ctx context.Context, feedback Feedback) (*Feedback, error)
+
 // MessageRole represents the role of a message sender.
 type MessageRole string
 
@@ -190,24 +403,124 @@ const (
 
 // Message represents a single message in a conversation.
 type Message struct {
-	Role      MessageRole `json:"role"`
-	Content   string      `json:"content"`
-	Timestamp time.Time   `json:"timestamp"`
-	Expert    *string     `json:"expert,omitempty"`
-	Data      any         `json:"data,omitempty"`
+	ID        string       `json:"id,omitempty"`
+	Role      MessageRole  `json:"role"`
+	Content   string       `json:"content"`
+	Timestamp time.Time    `json:"timestamp"`
+	Expert    *string      `json:"expert,omitempty"`
+	Data      any          `json:"data,omitempty"`
+	Tokens    int          `json:"tokens,omitempty"` // LLM tokens spent producing an assistant message
+	Usage     []ModelUsage `json:"usage,omitempty"`  // Tokens broken down by model, for assistant messages
+}
+
+// ModelUsage is the token usage of the LLM calls served by one model.
+type ModelUsage struct {
+	Model string `json:"model,omitempty"`
+	TokenUsage
 }
 
 // Conversation represents a conversation between a user and the assistant.
 type Conversation struct {
-	ID        string    `json:"id"`
-	CreatedAt time.Time `json:"createdAt"`
-	EntityID  string    `json:"entityId,omitempty"`
-	Messages  []Message `json:"messages"`
+	ID        string            `json:"id"`
+	CreatedAt time.Time         `json:"createdAt"`
+	UpdatedAt time.Time         `json:"updatedAt"`
+	EntityID  string            `json:"entityId,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	Tags      []string          `json:"tags,omitempty"`
+	Messages  []Message         `json:"messages"`
+
+	// Owner identifies the principal that created the conversation when
+	// Config.Authenticator is set. Other principals cannot access it.
+	Owner string `json:"owner,omitempty"`
+
+	// TokensUsed is the total of the messages' Tokens, counted against Config.MaxConversationTokens.
+	TokensUsed int `json:"tokensUsed,omitempty"`
+
+	// Usage is the cumulative LLM usage of the conversation's turns, recorded by
+	// AddMessage alongside TokensUsed. Unlike the messages' Usage, it keeps
+	// the usage of turns that were later truncated or regenerated.
+	Usage *ConversationUsage `json:"usage,omitempty"`
+
+	// Summary caches the latest summary from SDK.SummarizeConversation.
+	Summary *ConversationSummary `json:"summary,omitempty"`
+}
+
+// ConversationUpdate describes changes to a conversation's metadata and tags.
+type ConversationUpdate struct {
+	// Metadata is merged into the existing metadata; an empty value removes the key.
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// Tags replaces the existing tags when non-nil.
+	Tags []string `json:"tags,omitempty"`
+}
+
+// ConversationFilter selects conversations when listing.
+// A conversation matches when it satisfies every non-zero field.
+// Results are ordered by last update, most recent first.
+type ConversationFilter struct {
+	// EntityID matches conversations about a specific entity.
+	EntityID string
+
+	// CreatedAfter matches conversations created after this time.
+	CreatedAfter time.Time
+
+	// Tags matches conversations that have all of these tags.
+	Tags []string
+
+	// Metadata matches conversations that have all of these key/value pairs.
+	Metadata map[string]string
+
+	// Owner matches conversations created by a principal (see Conversation.Owner).
+	Owner string
+
+	// Limit is the maximum number of conversations to return
+	// (defaults to 100, capped at 1000).
+	Limit int
+
+	// Offset is the number of matching conversations to skip.
+	Offset int
 }
 
-// AddMessage appends a message to the conversation.
+const (
+	defaultListLimit = 100
+	maxListLimit     = 1000
+)
+
+// limit returns the effective page size for the filter.
+func (f ConversationFilter) limit() int {
+	if f.Limit <= 0 {
+		return defaultListLimit
+	}
+	return min(f.Limit, maxListLimit)
+}
+
+// AddMessage appends a message to the conversation and adds its tokens to TokensUsed
+// and, for assistant messages, its usage to Usage.
 func AddMessage(c *Conversation, msg Message) {
+	if msg.Role == RoleAssistant {
+		c.Usage = usageLedger(c)
+		c.Usage.add(msg)
+	}
+	appendMessage(c, msg)
+}
+
+// appendMessage appends a message to the conversation and adds its tokens to TokensUsed.
+func appendMessage(c *Conversation, msg Message) {
 	c.Messages = append(c.Messages, msg)
+	c.TokensUsed += msg.Tokens
+	c.UpdatedAt = msg.Timestamp
+	if c.UpdatedAt.IsZero() {
+		c.UpdatedAt = time.Now()
+	}
+}
+
+// lastUpdated returns when the conversation was last updated, falling back to
+// its creation time for conversations stored before UpdatedAt was tracked.
+func lastUpdated(c *Conversation) time.Time {
+	if c.UpdatedAt.IsZero() {
+		return c.CreatedAt
+	}
+	return c.UpdatedAt
 }
 
 // ConversationStore is a struct of functions for conversation persistence.
@@ -216,6 +529,41 @@ type ConversationStore struct {
 	Get        func(ctx context.Context, id string) (*Conversation, error)
 	AddMessage func(ctx context.Context, id string, msg Message) error
 	Save       func(ctx context.Context, conversation *Conversation) error
+
+	// List returns conversations matching the filter, most recently updated first (optional for custom stores).
+	List func(ctx context.Context, filter ConversationFilter) ([]*Conversation, error)
+
+	// SaveFeedback stores feedback, replacing any earlier feedback on the same message (optional).
+	SaveFeedback func(ctx context.Context, feedback Feedback) error
+
+	// GetFeedback returns the feedback on a message, or ErrFeedbackNotFound (optional).
+	GetFeedback func(ctx context.Context, messageID string) (*Feedback, error)
+
+	// ListFeedback returns all feedback in a conversation, oldest first (optional).
+	ListFeedback func(ctx context.Context, conversationID string) ([]Feedback, error)
+
+	// PurgeExpired deletes expired conversations and returns how many were deleted (optional).
+	PurgeExpired func(ctx context.Context) (int, error)
+
+	// Close releases background resources held by the store (optional).
+	Close func() error
+}
+
+// FeedbackRating is a user's rating of an assistant message.
+type FeedbackRating string
+
+const (
+	RatingPositive FeedbackRating = "positive"
+	RatingNegative FeedbackRating = "negative"
+)
+
+// Feedback is a user's rating of an assistant message.
+type Feedback struct {
+	MessageID      string         `json:"messageId"`
+	ConversationID string         `json:"conversationId"`
+	Rating         FeedbackRating `json:"rating"`
+	Comment        string         `json:"comment,omitempty"`
+	CreatedAt      time.Time      `json:"createdAt"`
 }
 
 // StreamEventType represents the type of server-sent event.
@@ -240,23 +588,75 @@ type StreamEvent struct {
 	Content        *string         `json:"content,omitempty"`
 	MessageID      *string         `json:"messageId,omitempty"`
 	Data           any             `json:"data,omitempty"` // Structured data from expert
+	Code           ErrorCode       `json:"code,omitempty"` // Set on error events
+
+	// TokensRemaining is the conversation's remaining token budget, set on done
+	// events when Config.MaxConversationTokens is configured.
+	TokensRemaining *int `json:"tokensRemaining,omitempty"`
+
+	// Trace lists the request's LLM calls, set on done events when requested and Config.ReturnTrace is on.
+	Trace []TraceEntry `json:"trace,omitempty"`
+
+	// SuggestedFollowups are questions the user might ask next, set on done events
+	// when Config.GenerateSuggestions is on.
+	SuggestedFollowups []string `json:"suggestedFollowups,omitempty"`
 }
 
 // HTTPChatRequest represents the HTTP request body for chat endpoints.
 type HTTPChatRequest struct {
-	Message        string  `json:"message"`
-	ConversationID *string `json:"conversationId,omitempty"`
-	EntityID       *string `json:"entityId,omitempty"`
-	Data           any     `json:"data,omitempty"` // Structured data for experts
+	Message        string            `json:"message"`
+	ConversationID *string           `json:"conversationId,omitempty"`
+	EntityID       *string           `json:"entityId,omitempty"`
+	Data           any               `json:"data,omitempty"`        // Structured data for experts
+	Images         []Image           `json:"images,omitempty"`      // Passed to experts
+	Metadata       map[string]string `json:"metadata,omitempty"`    // Set on new conversations
+	Tags           []string          `json:"tags,omitempty"`        // Set on new conversations
+	Seed           *int              `json:"seed,omitempty"`        // For reproducible outputs in tests
+	Model          string            `json:"model,omitempty"`       // Must be in Config.AllowedModels
+	Temperature    *float32          `json:"temperature,omitempty"` // 0-2
+	Persona        string            `json:"persona,omitempty"`     // Name of an entry in Config.Personas
+	Ephemeral      bool              `json:"ephemeral,omitempty"`   // Do not store or load history
+	Trace          bool              `json:"trace,omitempty"`       // Include the trace when Config.ReturnTrace is set
 }
 
 // HTTPChatResponse represents the HTTP response body for chat endpoints.
 type HTTPChatResponse struct {
 	ConversationID string     `json:"conversationId"`
+	MessageID      string     `json:"messageId,omitempty"`
 	Expert         ExpertType `json:"expert"`
 	ExpertName     string     `json:"expertName"`
 	Message        string     `json:"message"`
 	Reasoning      string     `json:"reasoning"`
 	Response       string     `json:"response"`
 	Data           any        `json:"data,omitempty"` // Structured data from expert
+	Cached         bool       `json:"cached,omitempty"`
+	Blocked        bool       `json:"blocked,omitempty"`
+
+	Trace []TraceEntry `json:"trace,omitempty"` // Set when requested and Config.ReturnTrace is on
+
+	SuggestedFollowups []string `json:"suggestedFollowups,omitempty"` // Set when Config.GenerateSuggestions is on
+}
+
+// HTTPConversationResponse represents a conversation's attributes without its messages.
+type HTTPConversationResponse struct {
+	ID        string            `json:"id"`
+	CreatedAt time.Time         `json:"createdAt"`
+	EntityID  string            `json:"entityId,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	Tags      []string          `json:"tags,omitempty"`
+}
+
+// ErrorResponse is the HTTP response body for errors.
+type ErrorResponse struct {
+	Error string    `json:"error"`
+	Code  ErrorCode `json:"code"`
+}
+
+// HTTPChatBatchResult is a single item of the POST /chat/batch response.
+// Exactly one of Response and Error is set.
+type HTTPChatBatchResult struct {
+	Status   int               `json:"status"`
+	Response *HTTPChatResponse `json:"response,omitempty"`
+	Error    string            `json:"error,omitempty"`
+	Code     ErrorCode         `json:"code,omitempty"`
 }
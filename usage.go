@@ -0,0 +1,224 @@
+package aichat
+
+import (
+	"context"
+	"maps"
+	"slices"
+)
+
+// ModelPrice is a model's price in USD per million tokens.
+type ModelPrice struct {
+	Input  float64
+	Output float64
+
+	// CachedInput is the price of prompt tokens served from the provider's prompt
+	// cache. Zero means cached tokens cost the same as Input.
+	CachedInput float64
+}
+
+// cost returns the estimated cost in USD of usage.
+func (p ModelPrice) cost(usage TokenUsage) float64 {
+	cachedPrice := p.CachedInput
+	if cachedPrice == 0 {
+		cachedPrice = p.Input
+	}
+	uncached := usage.PromptTokens - usage.CachedTokens
+	return (float64(uncached)*p.Input +
+		float64(usage.CachedTokens)*cachedPrice +
+		float64(usage.CompletionTokens)*p.Output) / 1_000_000
+}
+
+// DefaultModelPricing returns list prices for common OpenAI, Anthropic and Gemini models,
+// keyed like ModelContextWindow. Prices change; set Config.ModelPricing to keep
+// estimates accurate.
+func DefaultModelPricing() map[string]ModelPrice {
+	return map[string]ModelPrice{
+		"gpt-4o":                {Input: 2.50, CachedInput: 1.25, Output: 10.00},
+		"gpt-4o-mini":           {Input: 0.15, CachedInput: 0.075, Output: 0.60},
+		"gpt-4.1":               {Input: 2.00, CachedInput: 0.50, Output: 8.00},
+		"gpt-4.1-mini":          {Input: 0.40, CachedInput: 0.10, Output: 1.60},
+		"gpt-4.1-nano":          {Input: 0.10, CachedInput: 0.025, Output: 0.40},
+		"gpt-5":                 {Input: 1.25, CachedInput: 0.125, Output: 10.00},
+		"gpt-5-mini":            {Input: 0.25, CachedInput: 0.025, Output: 2.00},
+		"gpt-5-nano":            {Input: 0.05, CachedInput: 0.005, Output: 0.40},
+		"o3":                    {Input: 2.00, CachedInput: 0.50, Output: 8.00},
+		"o4-mini":               {Input: 1.10, CachedInput: 0.275, Output: 4.40},
+		"claude-3-5-haiku":      {Input: 0.80, CachedInput: 0.08, Output: 4.00},
+		"claude-3-7-sonnet":     {Input: 3.00, CachedInput: 0.30, Output: 15.00},
+		"claude-haiku-4":        {Input: 1.00, CachedInput: 0.10, Output: 5.00},
+		"claude-sonnet-4":       {Input: 3.00, CachedInput: 0.30, Output: 15.00},
+		"claude-opus-4":         {Input: 15.00, CachedInput: 1.50, Output: 75.00},
+		"gemini-2.0-flash":      {Input: 0.10, CachedInput: 0.025, Output: 0.40},
+		"gemini-2.5-flash":      {Input: 0.30, CachedInput: 0.03, Output: 2.50},
+		"gemini-2.5-flash-lite": {Input: 0.10, CachedInput: 0.01, Output: 0.40},
+		"gemini-2.5-pro":        {Input: 1.25, CachedInput: 0.125, Output: 10.00},
+	}
+}
+
+// UsageReport summarizes the LLM usage of a conversation.
+type UsageReport struct {
+	ConversationID string `json:"conversationId"`
+
+	// Turns is the number of answered turns, including truncated and regenerated ones.
+	Turns int `json:"turns"`
+
+	// Usage is the total token usage across all turns.
+	Usage TokenUsage `json:"usage"`
+
+	// EstimatedCost is the estimated cost in USD of the models with known prices.
+	EstimatedCost float64 `json:"estimatedCost"`
+
+	// ByModel and ByExpert break the usage down, sorted by model and expert name.
+	ByModel  []ModelUsageReport  `json:"byModel"`
+	ByExpert []ExpertUsageReport `json:"byExpert"`
+}
+
+// ModelUsageReport is the usage of one model in a UsageReport. Model is empty for
+// tokens that could not be attributed, such as those of messages stored before
+// per-model usage was recorded.
+type ModelUsageReport struct {
+	Model         string     `json:"model"`
+	Usage         TokenUsage `json:"usage"`
+	EstimatedCost float64    `json:"estimatedCost"`
+
+	// Priced reports whether the model's price is known. If not, EstimatedCost is 0.
+	Priced bool `json:"priced"`
+}
+
+// ExpertUsageReport is the usage of the turns answered by one expert in a UsageReport.
+type ExpertUsageReport struct {
+	Expert        string     `json:"expert"`
+	Turns         int        `json:"turns"`
+	Usage         TokenUsage `json:"usage"`
+	EstimatedCost float64    `json:"estimatedCost"`
+}
+
+// ConversationUsageFn reports the LLM usage of a conversation.
+type ConversationUsageFn func(ctx context.Context, id string) (*UsageReport, error)
+
+// newConversationUsageReporter creates a function that reports the usage of
+// stored conversations, pricing it with pricing.
+func newConversationUsageReporter(store ConversationStore, pricing map[string]ModelPrice) ConversationUsageFn {
+	return func(ctx context.Context, id string) (*UsageReport, error) {
+		conversation, err := getConversation(ctx, store, id)
+		if err != nil {
+			return nil, err
+		}
+		return buildUsageReport(conversation, pricing), nil
+	}
+}
+
+// ConversationUsage is the cumulative LLM usage of a conversation's turns.
+type ConversationUsage struct {
+	// ByExpert maps expert names to the usage of the turns they answered.
+	ByExpert map[string]ExpertUsage `json:"byExpert,omitempty"`
+}
+
+// ExpertUsage is the cumulative usage of the turns answered by one expert.
+type ExpertUsage struct {
+	Turns int `json:"turns"`
+
+	// Models maps each model to the tokens it used. The empty model holds tokens
+	// that could not be attributed (see ModelUsageReport).
+	Models map[string]TokenUsage `json:"models,omitempty"`
+}
+
+// add adds the usage of an assistant message.
+func (u *ConversationUsage) add(msg Message) {
+	usage := msg.Usage
+	if usage == nil && msg.Tokens > 0 {
+		usage = []ModelUsage{{TokenUsage: TokenUsage{TotalTokens: msg.Tokens}}}
+	}
+
+	var expertName string
+	if msg.Expert != nil {
+		expertName = *msg.Expert
+	}
+
+	if u.ByExpert == nil {
+		u.ByExpert = make(map[string]ExpertUsage)
+	}
+	expert := u.ByExpert[expertName]
+	expert.Turns++
+	for _, mu := range usage {
+		if expert.Models == nil {
+			expert.Models = make(map[string]TokenUsage)
+		}
+		expert.Models[mu.Model] = expert.Models[mu.Model].add(mu.TokenUsage)
+	}
+	u.ByExpert[expertName] = expert
+}
+
+// clone returns a deep copy of the usage.
+func (u *ConversationUsage) clone() *ConversationUsage {
+	if u == nil {
+		return nil
+	}
+	result := &ConversationUsage{ByExpert: make(map[string]ExpertUsage, len(u.ByExpert))}
+	for name, expert := range u.ByExpert {
+		expert.Models = maps.Clone(expert.Models)
+		result.ByExpert[name] = expert
+	}
+	return result
+}
+
+// usageLedger returns the usage ledger of a conversation. For conversations stored
+// before the ledger was kept, it returns a new ledger holding the usage of the
+// current assistant messages.
+func usageLedger(conversation *Conversation) *ConversationUsage {
+	if conversation.Usage != nil {
+		return conversation.Usage
+	}
+	ledger := &ConversationUsage{}
+	for _, msg := range conversation.Messages {
+		if msg.Role == RoleAssistant {
+			ledger.add(msg)
+		}
+	}
+	return ledger
+}
+
+// buildUsageReport prices a conversation's usage ledger.
+func buildUsageReport(conversation *Conversation, pricing map[string]ModelPrice) *UsageReport {
+	report := &UsageReport{
+		ConversationID: conversation.ID,
+		ByModel:        []ModelUsageReport{},
+		ByExpert:       []ExpertUsageReport{},
+	}
+	ledger := usageLedger(conversation).ByExpert
+
+	byModel := make(map[string]*ModelUsageReport)
+	for _, expertName := range slices.Sorted(maps.Keys(ledger)) {
+		usage := ledger[expertName]
+		expert := ExpertUsageReport{Expert: expertName, Turns: usage.Turns}
+		report.Turns += usage.Turns
+
+		for _, modelName := range slices.Sorted(maps.Keys(usage.Models)) {
+			tokens := usage.Models[modelName]
+			price, priced := lookupModel(pricing, modelName)
+			var cost float64
+			if priced {
+				cost = price.cost(tokens)
+			}
+
+			model, ok := byModel[modelName]
+			if !ok {
+				model = &ModelUsageReport{Model: modelName, Priced: priced}
+				byModel[modelName] = model
+			}
+			model.Usage = model.Usage.add(tokens)
+			model.EstimatedCost += cost
+			expert.Usage = expert.Usage.add(tokens)
+			expert.EstimatedCost += cost
+			report.Usage = report.Usage.add(tokens)
+			report.EstimatedCost += cost
+		}
+
+		report.ByExpert = append(report.ByExpert, expert)
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(byModel)) {
+		report.ByModel = append(report.ByModel, *byModel[name])
+	}
+	return report
+}
@@ -0,0 +1,72 @@
+package aichat_test
+
+import (
+	"context"
+	"testing"
+
+	aichat "github.com/ourstudio-se/ai-chat-sdk"
+	"github.com/ourstudio-se/ai-chat-sdk/aichattest"
+)
+
+// tokenTurn returns the scripted responses of a turn whose routing and expert
+// calls report the given tokens.
+func tokenTurn(answer string, routing, expert aichat.TokenUsage) []aichattest.Response {
+	responses := turn(answer)
+	responses[0] = responses[0].WithTokens(routing.PromptTokens, routing.CompletionTokens)
+	responses[1] = responses[1].WithTokens(expert.PromptTokens, expert.CompletionTokens)
+	return responses
+}
+
+func TestConversationUsageSumsTurns(t *testing.T) {
+	llm := aichattest.NewLLM(script(
+		tokenTurn("The Widget Pro has three speeds.",
+			aichat.TokenUsage{PromptTokens: 100, CompletionTokens: 10},
+			aichat.TokenUsage{PromptTokens: 200, CompletionTokens: 20}),
+		tokenTurn("It weighs 2 kg.",
+			aichat.TokenUsage{PromptTokens: 50, CompletionTokens: 5},
+			aichat.TokenUsage{PromptTokens: 300, CompletionTokens: 30}),
+	)...)
+	sdk := newTestSDK(t, llm, aichat.Config{})
+	ctx := context.Background()
+
+	first := mustChat(t, sdk, aichat.ChatRequest{Message: "How many speeds?"})
+	mustChat(t, sdk, aichat.ChatRequest{Message: "And the weight?", ConversationID: first.ConversationID})
+	llm.AssertDone(t)
+
+	want := aichat.TokenUsage{PromptTokens: 650, CompletionTokens: 65, TotalTokens: 715}
+	assertUsage := func(id string, wantTurns int, want aichat.TokenUsage) {
+		t.Helper()
+
+		report, err := sdk.ConversationUsage(ctx, id)
+		if err != nil {
+			t.Fatalf("ConversationUsage() error = %v", err)
+		}
+		if report.Turns != wantTurns || report.Usage != want {
+			t.Errorf("ConversationUsage() = %d turns, %+v; want %d turns, %+v", report.Turns, report.Usage, wantTurns, want)
+		}
+
+		var byExpert aichat.TokenUsage
+		for _, expert := range report.ByExpert {
+			byExpert.PromptTokens += expert.Usage.PromptTokens
+			byExpert.CompletionTokens += expert.Usage.CompletionTokens
+			byExpert.TotalTokens += expert.Usage.TotalTokens
+		}
+		if byExpert != want {
+			t.Errorf("ConversationUsage().ByExpert sums to %+v, want %+v", byExpert, want)
+		}
+	}
+	assertUsage(first.ConversationID, 2, want)
+
+	// Truncating doesn't refund the removed turn
+	if _, err := sdk.TruncateConversation(ctx, first.ConversationID, first.MessageID); err != nil {
+		t.Fatalf("TruncateConversation() error = %v", err)
+	}
+	assertUsage(first.ConversationID, 2, want)
+
+	// A fork doesn't bill the copied turns again
+	fork, err := sdk.ForkConversation(ctx, first.ConversationID, first.MessageID)
+	if err != nil {
+		t.Fatalf("ForkConversation() error = %v", err)
+	}
+	assertUsage(fork, 0, aichat.TokenUsage{})
+}
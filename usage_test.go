@@ -0,0 +1,92 @@
+package aichat
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestBuildUsageReport(t *testing.T) {
+	product, support := "product", "support"
+	pricing := map[string]ModelPrice{
+		"gpt-4o":      {Input: 2, Output: 10},
+		"gpt-4o-mini": {Input: 1, CachedInput: 0.5, Output: 4},
+	}
+	conversation := &Conversation{
+		ID: "conv-1",
+		Messages: []Message{
+			{Role: RoleUser, Content: "Which widget?", Tokens: 999},
+			{Role: RoleAssistant, Expert: &product, Usage: []ModelUsage{
+				{Model: "gpt-4o-mini", TokenUsage: TokenUsage{PromptTokens: 1_000_000, CompletionTokens: 500_000, CachedTokens: 400_000, TotalTokens: 1_500_000}},
+				{Model: "gpt-4o-2024-08-06", TokenUsage: TokenUsage{PromptTokens: 500_000, CompletionTokens: 100_000, TotalTokens: 600_000}},
+			}},
+			{Role: RoleUser, Content: "And support?"},
+			{Role: RoleAssistant, Expert: &support, Usage: []ModelUsage{
+				{Model: "self-hosted", TokenUsage: TokenUsage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}},
+			}},
+			// Stored before per-model usage was recorded
+			{Role: RoleAssistant, Expert: &product, Tokens: 42},
+			{Role: RoleAssistant},
+		},
+	}
+
+	report := buildUsageReport(conversation, pricing)
+
+	// 600k uncached at $1, 400k cached at $0.5 and 500k completion at $4
+	miniCost := 0.6 + 0.2 + 2.0
+	// 500k prompt at $2 and 100k completion at $10
+	fullCost := 1.0 + 1.0
+	want := &UsageReport{
+		ConversationID: "conv-1",
+		Turns:          4,
+		Usage:          TokenUsage{PromptTokens: 1_500_010, CompletionTokens: 600_005, CachedTokens: 400_000, TotalTokens: 2_100_057},
+		EstimatedCost:  miniCost + fullCost,
+		ByModel: []ModelUsageReport{
+			{Model: "", Usage: TokenUsage{TotalTokens: 42}},
+			{Model: "gpt-4o-2024-08-06", Usage: TokenUsage{PromptTokens: 500_000, CompletionTokens: 100_000, TotalTokens: 600_000}, EstimatedCost: fullCost, Priced: true},
+			{Model: "gpt-4o-mini", Usage: TokenUsage{PromptTokens: 1_000_000, CompletionTokens: 500_000, CachedTokens: 400_000, TotalTokens: 1_500_000}, EstimatedCost: miniCost, Priced: true},
+			{Model: "self-hosted", Usage: TokenUsage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}},
+		},
+		ByExpert: []ExpertUsageReport{
+			{Expert: "", Turns: 1},
+			{Expert: "product", Turns: 2, Usage: TokenUsage{PromptTokens: 1_500_000, CompletionTokens: 600_000, CachedTokens: 400_000, TotalTokens: 2_100_042}, EstimatedCost: miniCost + fullCost},
+			{Expert: "support", Turns: 1, Usage: TokenUsage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}},
+		},
+	}
+
+	// Round the costs so float addition order doesn't matter
+	round := func(r *UsageReport) {
+		r.EstimatedCost = math.Round(r.EstimatedCost*1e9) / 1e9
+		for i := range r.ByModel {
+			r.ByModel[i].EstimatedCost = math.Round(r.ByModel[i].EstimatedCost*1e9) / 1e9
+		}
+		for i := range r.ByExpert {
+			r.ByExpert[i].EstimatedCost = math.Round(r.ByExpert[i].EstimatedCost*1e9) / 1e9
+		}
+	}
+	round(report)
+	round(want)
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("report = %+v\nwant %+v", report, want)
+	}
+}
+
+func TestBuildUsageReportEmptyConversation(t *testing.T) {
+	report := buildUsageReport(&Conversation{ID: "conv-1"}, DefaultModelPricing())
+
+	want := &UsageReport{ConversationID: "conv-1", ByModel: []ModelUsageReport{}, ByExpert: []ExpertUsageReport{}}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("report = %+v, want %+v", report, want)
+	}
+}
+
+func TestConversationUsageOfMissingConversation(t *testing.T) {
+	usage := newConversationUsageReporter(NewMemoryStore(slog.New(slog.DiscardHandler)), DefaultModelPricing())
+
+	if _, err := usage(context.Background(), "missing"); !errors.Is(err, ErrConversationNotFound) {
+		t.Errorf("err = %v, want ErrConversationNotFound", err)
+	}
+}
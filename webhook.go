@@ -0,0 +1,295 @@
+package aichat
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// WebhookEventType identifies the kind of webhook event.
+type WebhookEventType string
+
+const (
+	// WebhookChatCompleted is sent after a chat request completes successfully.
+	WebhookChatCompleted WebhookEventType = "chat.completed"
+
+	// WebhookFeedbackReceived is sent after feedback on an answer is stored.
+	WebhookFeedbackReceived WebhookEventType = "feedback.received"
+)
+
+// WebhookConfig configures asynchronous delivery of SDK events to HTTP endpoints.
+type WebhookConfig struct {
+	// ChatCompletedURL receives a chat.completed event after every successful chat (optional).
+	ChatCompletedURL string
+
+	// FeedbackReceivedURL receives a feedback.received event whenever feedback is stored (optional).
+	FeedbackReceivedURL string
+
+	// Secret is the shared secret used to sign payloads (required when a URL is set).
+	// The hex-encoded HMAC-SHA256 of the body is sent in the X-Signature header.
+	Secret string
+
+	// MaxRetries is the number of retries after a failed delivery (defaults to 3).
+	MaxRetries int
+
+	// QueueSize bounds the number of pending deliveries (defaults to 100).
+	// Events are dropped and logged when the queue is full.
+	QueueSize int
+
+	// Timeout is the timeout of a single delivery attempt (defaults to 10s).
+	Timeout time.Duration
+
+	// HTTPClient is the client used for delivery (optional, defaults to http.DefaultClient).
+	HTTPClient *http.Client
+}
+
+// WebhookEvent is the JSON payload delivered to webhook endpoints.
+type WebhookEvent struct {
+	Type           WebhookEventType `json:"type"`
+	Timestamp      time.Time        `json:"timestamp"`
+	ConversationID string           `json:"conversationId"`
+	MessageID      string           `json:"messageId,omitempty"`
+	Expert         ExpertType       `json:"expert,omitempty"`
+	ExpertName     string           `json:"expertName,omitempty"`
+
+	// Usage is the token usage of the chat request (chat.completed only).
+	Usage *TokenUsage `json:"usage,omitempty"`
+
+	// Feedback is the stored feedback (feedback.received only).
+	Feedback *Feedback `json:"feedback,omitempty"`
+}
+
+// NotifyWebhookFn enqueues a webhook event for asynchronous delivery.
+type NotifyWebhookFn func(event WebhookEvent)
+
+// enabled reports whether any webhook URL is configured.
+func (c WebhookConfig) enabled() bool {
+	return c.ChatCompletedURL != "" || c.FeedbackReceivedURL != ""
+}
+
+// validate checks that payloads can be signed.
+func (c WebhookConfig) validate() error {
+	if c.Secret == "" {
+		return errors.New("Webhooks.Secret is required")
+	}
+	return nil
+}
+
+// applyDefaults fills in default values for the webhook config.
+func (c *WebhookConfig) applyDefaults() {
+	if c.MaxRetries == 0 {
+		c.MaxRetries = 3
+	}
+
+	if c.QueueSize == 0 {
+		c.QueueSize = 100
+	}
+
+	if c.Timeout == 0 {
+		c.Timeout = 10 * time.Second
+	}
+
+	if c.HTTPClient == nil {
+		c.HTTPClient = http.DefaultClient
+	}
+}
+
+// newWebhookNotifier starts a delivery worker and returns a function that enqueues
+// events and a function that shuts the worker down. Enqueueing never blocks: when the
+// queue is full, or after shutdown, the event is dropped and logged. Shutdown delivers
+// the queued events until ctx is done, then aborts the remaining deliveries.
+func newWebhookNotifier(cfg WebhookConfig, logger *slog.Logger) (NotifyWebhookFn, func(ctx context.Context) error) {
+	queue := make(chan WebhookEvent, cfg.QueueSize)
+	done := make(chan struct{})
+	deliveryCtx, abort := context.WithCancel(context.Background())
+
+	var mu sync.RWMutex
+	closed := false
+
+	go func() {
+		defer close(done)
+		for event := range queue {
+			url := webhookURL(cfg, event.Type)
+			if url == "" {
+				continue
+			}
+			if err := deliverWebhook(deliveryCtx, cfg, url, event); err != nil {
+				logger.Error("webhook delivery failed",
+					slog.String("event", string(event.Type)),
+					slog.String("conversation_id", event.ConversationID),
+					slog.String("error", err.Error()),
+				)
+			}
+		}
+	}()
+
+	notify := func(event WebhookEvent) {
+		mu.RLock()
+		defer mu.RUnlock()
+
+		if closed {
+			logger.Warn("webhooks shut down, dropping event",
+				slog.String("event", string(event.Type)),
+				slog.String("conversation_id", event.ConversationID),
+			)
+			return
+		}
+
+		select {
+		case queue <- event:
+		default:
+			logger.Warn("webhook queue full, dropping event",
+				slog.String("event", string(event.Type)),
+				slog.String("conversation_id", event.ConversationID),
+			)
+		}
+	}
+
+	shutdown := func(ctx context.Context) error {
+		mu.Lock()
+		if !closed {
+			closed = true
+			close(queue)
+		}
+		mu.Unlock()
+
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			abort()
+			<-done
+			return ctx.Err()
+		}
+	}
+
+	return notify, shutdown
+}
+
+func webhookURL(cfg WebhookConfig, eventType WebhookEventType) string {
+	switch eventType {
+	case WebhookChatCompleted:
+		return cfg.ChatCompletedURL
+	case WebhookFeedbackReceived:
+		return cfg.FeedbackReceivedURL
+	default:
+		return ""
+	}
+}
+
+// deliverWebhook posts the event, retrying with exponential backoff on failure
+// until the retries are exhausted or ctx is done.
+func deliverWebhook(ctx context.Context, cfg WebhookConfig, url string, event WebhookEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook event: %w", err)
+	}
+
+	backoff := 500 * time.Millisecond
+	for attempt := 0; ; attempt++ {
+		err = postWebhook(ctx, cfg, url, event.Type, body)
+		if err == nil || attempt >= cfg.MaxRetries {
+			return err
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return err
+		}
+		backoff *= 2
+	}
+}
+
+func postWebhook(ctx context.Context, cfg WebhookConfig, url string, eventType WebhookEventType, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", string(eventType))
+	req.Header.Set("X-Signature", signWebhookPayload(cfg.Secret, body))
+
+	resp, err := cfg.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook endpoint returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// signWebhookPayload returns the hex-encoded HMAC-SHA256 of body using secret.
+func signWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// withChatCompletedWebhook wraps a chat function to emit chat.completed events on success.
+func withChatCompletedWebhook(processChat ProcessChatFn, notify NotifyWebhookFn) ProcessChatFn {
+	return func(ctx context.Context, req ChatRequest) (*ChatResult, error) {
+		result, err := processChat(ctx, req)
+		if err == nil {
+			notify(chatCompletedEvent(result))
+		}
+		return result, err
+	}
+}
+
+// withChatCompletedWebhookStreaming wraps a streaming chat function to emit chat.completed events on success.
+func withChatCompletedWebhookStreaming(processChatStream ProcessChatStreamFn, notify NotifyWebhookFn) ProcessChatStreamFn {
+	return func(ctx context.Context, req ChatRequest, stream StreamCallback) (*ChatResult, error) {
+		result, err := processChatStream(ctx, req, stream)
+		if err == nil {
+			notify(chatCompletedEvent(result))
+		}
+		return result, err
+	}
+}
+
+func chatCompletedEvent(result *ChatResult) WebhookEvent {
+	usage := result.Usage
+	return WebhookEvent{
+		Type:           WebhookChatCompleted,
+		Timestamp:      time.Now(),
+		ConversationID: result.ConversationID,
+		MessageID:      result.MessageID,
+		Expert:         result.ExpertResult.ExpertType,
+		ExpertName:     result.ExpertResult.ExpertName,
+		Usage:          &usage,
+	}
+}
+
+// withFeedbackReceivedWebhook wraps a feedback function to emit feedback.received events on success.
+func withFeedbackReceivedWebhook(submitFeedback SubmitFeedbackFn, notify NotifyWebhookFn) SubmitFeedbackFn {
+	return func(ctx context.Context, feedback Feedback) (*Feedback, error) {
+		stored, err := submitFeedback(ctx, feedback)
+		if err == nil {
+			notify(WebhookEvent{
+				Type:           WebhookFeedbackReceived,
+				Timestamp:      time.Now(),
+				ConversationID: stored.ConversationID,
+				MessageID:      stored.MessageID,
+				Feedback:       stored,
+			})
+		}
+		return stored, err
+	}
+}
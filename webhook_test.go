@@ -0,0 +1,101 @@
+package aichat_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	aichat "github.com/ourstudio-se/ai-chat-sdk"
+	"github.com/ourstudio-se/ai-chat-sdk/aichattest"
+)
+
+func TestWebhooksDeliverChatAndFeedbackEventsBeforeShutdown(t *testing.T) {
+	const secret = "webhook-secret"
+
+	var mu sync.Mutex
+	var events []aichat.WebhookEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		if got, want := r.Header.Get("X-Signature"), hex.EncodeToString(mac.Sum(nil)); got != want {
+			t.Errorf("X-Signature = %q, want %q", got, want)
+		}
+
+		var event aichat.WebhookEvent
+		if err := json.Unmarshal(body, &event); err != nil {
+			t.Errorf("failed to decode event: %v", err)
+		}
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	llm := aichattest.NewLLM(script(tokenTurn("The Widget Pro has three speeds.",
+		aichat.TokenUsage{PromptTokens: 100, CompletionTokens: 10},
+		aichat.TokenUsage{PromptTokens: 200, CompletionTokens: 20}))...)
+	sdk := newTestSDK(t, llm, aichat.Config{
+		Webhooks: aichat.WebhookConfig{
+			ChatCompletedURL:    server.URL + "/chat",
+			FeedbackReceivedURL: server.URL + "/feedback",
+			Secret:              secret,
+		},
+	})
+
+	result := mustChat(t, sdk, aichat.ChatRequest{Message: "How many speeds?"})
+	if _, err := sdk.SubmitFeedback(context.Background(), aichat.Feedback{
+		ConversationID: result.ConversationID,
+		MessageID:      result.MessageID,
+		Rating:         aichat.RatingNegative,
+	}); err != nil {
+		t.Fatalf("SubmitFeedback() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := sdk.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) != 2 {
+		t.Fatalf("received %d events, want 2", len(events))
+	}
+
+	chat, feedback := events[0], events[1]
+	if chat.Type != aichat.WebhookChatCompleted || chat.Usage == nil || chat.Usage.TotalTokens != 330 {
+		t.Errorf("chat event = %+v (usage %+v), want chat.completed with 330 tokens", chat, chat.Usage)
+	}
+	if feedback.Type != aichat.WebhookFeedbackReceived || feedback.Feedback == nil || feedback.Feedback.Rating != aichat.RatingNegative {
+		t.Errorf("feedback event = %+v, want feedback.received with a negative rating", feedback)
+	}
+	if feedback.MessageID != result.MessageID {
+		t.Errorf("feedback event MessageID = %q, want %q", feedback.MessageID, result.MessageID)
+	}
+}
+
+func TestWebhooksRequireSecret(t *testing.T) {
+	_, err := aichat.New(aichat.Config{
+		LLMClient: aichattest.NewLLM().Client(),
+		DevMode:   true,
+		Experts: map[aichat.ExpertType]aichat.Expert{
+			testExpert: {Name: "Product Expert", Description: "Questions about products", Handler: func(ctx context.Context, req aichat.ExpertRequest) (*aichat.ExpertResult, error) {
+				return &aichat.ExpertResult{}, nil
+			}},
+		},
+		Webhooks: aichat.WebhookConfig{ChatCompletedURL: "https://example.com/hooks/chat"},
+	})
+	if err == nil {
+		t.Error("New() with a webhook URL but no secret succeeded, want an error")
+	}
+}